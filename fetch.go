@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// fetchOptions sets the HTTP request for downloading the WSDL document,
+// for servers that don't serve it to plain GET requests, such as
+// gateways that only serve it to SOAP calls like GetMetadata.
+type fetchOptions struct {
+	Method string     // HTTP method, GET by default
+	Body   string     // File with the request body, if any
+	Header headerFlag // Request headers, as "Name: value"
+}
+
+// isDefault reports whether the request is a plain GET.
+func (f *fetchOptions) isDefault() bool {
+	return (f.Method == "" || f.Method == http.MethodGet) && f.Body == "" && len(f.Header) == 0
+}
+
+// open downloads the document at url with the request set by f.
+func (f *fetchOptions) open(cli *http.Client, url string) (io.ReadCloser, error) {
+	method := f.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if f.Body != "" {
+		b, err := os.Open(f.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer b.Close()
+		body = b
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range f.Header {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid header %q, want 'Name: value'", h)
+		}
+		req.Header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// headerFlag is a flag.Value of HTTP headers, set once per header.
+type headerFlag []string
+
+func (h *headerFlag) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlag) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// listing describes the contents of a WSDL document.
+type listing struct {
+	Name            string         `json:"name,omitempty"`
+	TargetNamespace string         `json:"targetNamespace,omitempty"`
+	Services        []listService  `json:"services"`
+	Bindings        []listBinding  `json:"bindings"`
+	PortTypes       []listPortType `json:"portTypes,omitempty"` // not bound
+	Types           map[string]int `json:"types"`
+}
+
+type listService struct {
+	Name  string     `json:"name,omitempty"`
+	Ports []listPort `json:"ports"`
+}
+
+type listPort struct {
+	Name     string `json:"name"`
+	Binding  string `json:"binding"`
+	Location string `json:"location,omitempty"`
+}
+
+type listBinding struct {
+	Name       string   `json:"name"`
+	PortType   string   `json:"portType"`
+	Style      string   `json:"style"`
+	Operations []listOp `json:"operations"`
+}
+
+type listPortType struct {
+	Name       string   `json:"name"`
+	Operations []listOp `json:"operations"`
+}
+
+type listOp struct {
+	Name   string `json:"name"`
+	Style  string `json:"style,omitempty"` // if not the binding's
+	Action string `json:"action,omitempty"`
+	Input  string `json:"input,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// list implements the list command, which prints the services, ports,
+// bindings, operations and type counts of a WSDL document.
+func list(w io.Writer, args []string) error {
+	var src string
+	var insecure, asJSON bool
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.StringVar(&src, "i", src, "input file, url, or '-' for stdin")
+	fs.BoolVar(&asJSON, "json", asJSON, "print as JSON")
+	fs.BoolVar(&insecure, "yolo", insecure, "accept invalid https certificates")
+	fs.Parse(args)
+
+	d, err := load(src, httpClient(insecure, "", ""), nil, nil)
+	if err != nil {
+		return err
+	}
+	l := newListing(d)
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(l)
+	}
+	return l.write(w)
+}
+
+func newListing(d *wsdl.Definitions) *listing {
+	l := &listing{
+		Name:            d.Name,
+		TargetNamespace: d.TargetNamespace,
+		Services:        []listService{},
+		Bindings:        []listBinding{},
+		Types: map[string]int{
+			"simpleTypes":  0,
+			"complexTypes": 0,
+			"elements":     0,
+			"messages":     len(d.Messages),
+		},
+	}
+	// counted in the schemas that declare them, as the merged schema of
+	// the definitions is only set when they're unmarshaled
+	for _, s := range d.Types.Schemas {
+		l.Types["simpleTypes"] += len(s.SimpleTypes)
+		l.Types["complexTypes"] += len(s.ComplexTypes)
+		l.Types["elements"] += len(s.Elements)
+	}
+	for _, s := range d.Services {
+		ls := listService{Name: s.Name, Ports: []listPort{}}
+		for _, p := range s.Ports {
+			ls.Ports = append(ls.Ports, listPort{
+				Name:     p.Name,
+				Binding:  p.Binding,
+				Location: p.Address.Location,
+			})
+		}
+		l.Services = append(l.Services, ls)
+	}
+	portTypes := make(map[string]*wsdl.PortType)
+	for _, pt := range d.PortTypes {
+		portTypes[pt.Name] = pt
+	}
+	bound := make(map[string]bool)
+	for _, b := range d.Bindings {
+		name := b.Type[strings.LastIndex(b.Type, ":")+1:]
+		bound[name] = true
+		l.Bindings = append(l.Bindings, newListBinding(b, portTypes[name]))
+	}
+	for _, pt := range d.PortTypes {
+		if !bound[pt.Name] {
+			l.PortTypes = append(l.PortTypes, listPortType{
+				Name:       pt.Name,
+				Operations: listOps(pt, nil, nil),
+			})
+		}
+	}
+	return l
+}
+
+// newListBinding returns the listing of binding b, with the operations
+// of its port type pt, which is nil if not defined.
+func newListBinding(b *wsdl.Binding, pt *wsdl.PortType) listBinding {
+	lb := listBinding{Name: b.Name, PortType: b.Type}
+	if b.BindingType != nil {
+		lb.Style = b.BindingType.Style
+	}
+	if lb.Style == "" {
+		lb.Style = "document"
+	}
+	actions := make(map[string]string)
+	styles := make(map[string]string)
+	for _, bo := range b.Operations {
+		actions[bo.Name] = bo.Operation11.Action
+		if bo.Operation.Action != "" {
+			actions[bo.Name] = bo.Operation.Action
+		}
+		styles[bo.Name] = bo.Operation.Style
+		if bo.Operation11.Style != "" {
+			styles[bo.Name] = bo.Operation11.Style
+		}
+		if styles[bo.Name] == lb.Style {
+			delete(styles, bo.Name)
+		}
+	}
+	lb.Operations = listOps(pt, actions, styles)
+	return lb
+}
+
+// listOps returns the operations of port type pt, which may be nil, with
+// the actions and styles of the binding by operation name.
+func listOps(pt *wsdl.PortType, actions, styles map[string]string) []listOp {
+	ops := []listOp{}
+	if pt == nil {
+		return ops
+	}
+	for _, op := range pt.Operations {
+		lop := listOp{Name: op.Name, Style: styles[op.Name], Action: actions[op.Name]}
+		if op.Input != nil {
+			lop.Input = op.Input.Message
+		}
+		if op.Output != nil {
+			lop.Output = op.Output.Message
+		}
+		ops = append(ops, lop)
+	}
+	return ops
+}
+
+func (l *listing) write(w io.Writer) error {
+	var err error
+	printf := func(format string, a ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, a...)
+		}
+	}
+	for _, s := range l.Services {
+		printf("Service %s\n", s.Name)
+		for _, p := range s.Ports {
+			printf("  Port %s (binding %s) %s\n", p.Name, p.Binding, p.Location)
+		}
+	}
+	for _, b := range l.Bindings {
+		printf("Binding %s (port type %s, style %s)\n", b.Name, b.PortType, b.Style)
+		writeOps(printf, b.Operations)
+	}
+	for _, pt := range l.PortTypes {
+		printf("Port type %s (not bound)\n", pt.Name)
+		writeOps(printf, pt.Operations)
+	}
+	printf("Types: %d simple, %d complex, %d elements, %d messages\n",
+		l.Types["simpleTypes"], l.Types["complexTypes"],
+		l.Types["elements"], l.Types["messages"])
+	return err
+}
+
+func writeOps(printf func(string, ...interface{}), ops []listOp) {
+	for _, op := range ops {
+		printf("  Operation %s", op.Name)
+		if op.Style != "" {
+			printf(" (style %s)", op.Style)
+		}
+		if op.Action != "" {
+			printf(" (action %q)", op.Action)
+		}
+		printf("\n")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+func TestListTypes(t *testing.T) {
+	const header = `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema"
+  xmlns:tns="urn:t" targetNamespace="urn:t">`
+	for _, tc := range []struct {
+		Name string
+		Src  string
+		Want map[string]int
+	}{
+		{
+			Name: "no types",
+			Src:  `<message name="Ping"/>`,
+			Want: map[string]int{"simpleTypes": 0, "complexTypes": 0, "elements": 0, "messages": 1},
+		},
+		{
+			Name: "one schema",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:t">
+      <xs:simpleType name="Code"><xs:restriction base="xs:string"/></xs:simpleType>
+      <xs:complexType name="Item"><xs:sequence><xs:element name="ID" type="xs:int"/></xs:sequence></xs:complexType>
+      <xs:element name="Get" type="tns:Item"/>
+    </xs:schema>
+  </types>
+  <message name="GetRequest"><part name="parameters" element="tns:Get"/></message>`,
+			Want: map[string]int{"simpleTypes": 1, "complexTypes": 1, "elements": 1, "messages": 1},
+		},
+		{
+			Name: "schemas of the same namespace",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:t">
+      <xs:complexType name="Item"><xs:sequence><xs:element name="ID" type="xs:int"/></xs:sequence></xs:complexType>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:t">
+      <xs:complexType name="Order"><xs:sequence><xs:element name="Item" type="tns:Item"/></xs:sequence></xs:complexType>
+      <xs:element name="Get" type="tns:Order"/>
+    </xs:schema>
+  </types>`,
+			Want: map[string]int{"simpleTypes": 0, "complexTypes": 2, "elements": 1, "messages": 0},
+		},
+		{
+			Name: "schemas of several namespaces",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:a">
+      <xs:simpleType name="Code"><xs:restriction base="xs:string"/></xs:simpleType>
+      <xs:element name="Get" type="xs:string"/>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:b">
+      <xs:simpleType name="Code"><xs:restriction base="xs:int"/></xs:simpleType>
+      <xs:element name="Get" type="xs:int"/>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:c"/>
+  </types>
+  <message name="GetRequest"/>
+  <message name="GetResponse"/>`,
+			Want: map[string]int{"simpleTypes": 2, "complexTypes": 0, "elements": 2, "messages": 2},
+		},
+	} {
+		d, err := wsdl.Unmarshal(strings.NewReader(header + tc.Src + `</definitions>`))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+		if have := newListing(d).Types; !reflect.DeepEqual(have, tc.Want) {
+			t.Errorf("%s: want %v, have %v", tc.Name, tc.Want, have)
+		}
+	}
+}
+
+func TestListWrite(t *testing.T) {
+	d, err := wsdl.Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a"><xs:element name="A" type="xs:string"/></xs:schema>
+    <xs:schema targetNamespace="urn:b"><xs:element name="B" type="xs:string"/></xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err = newListing(d).write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "Types: 0 simple, 0 complex, 2 elements, 0 messages\n"
+	if !strings.HasSuffix(b.String(), want) {
+		t.Errorf("want suffix %q, have:\n%s", want, &b)
+	}
+}
+
+func TestListBindings(t *testing.T) {
+	d, err := wsdl.Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:t" targetNamespace="urn:t">
+  <portType name="Orders"><operation name="Get"/></portType>
+  <portType name="Stock"><operation name="Count"/></portType>
+  <portType name="Admin"><operation name="Reset"/></portType>
+  <binding name="OrdersSoap" type="tns:Orders">
+    <soap:binding style="rpc"/>
+    <operation name="Get"><soap:operation soapAction="urn:Get"/></operation>
+  </binding>
+  <binding name="StockSoap" type="tns:Stock">
+    <operation name="Count"><soap:operation style="rpc"/></operation>
+  </binding>
+  <service name="OrderService"><port name="P" binding="tns:OrdersSoap"><soap:address location="http://host/orders"/></port></service>
+  <service name="StockService"><port name="P" binding="tns:StockSoap"><soap:address location="http://host/stock"/></port></service>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err = newListing(d).write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `Service OrderService
+  Port P (binding tns:OrdersSoap) http://host/orders
+Service StockService
+  Port P (binding tns:StockSoap) http://host/stock
+Binding OrdersSoap (port type tns:Orders, style rpc)
+  Operation Get (action "urn:Get")
+Binding StockSoap (port type tns:Stock, style document)
+  Operation Count (style rpc)
+Port type Admin (not bound)
+  Operation Reset
+Types: 0 simple, 0 complex, 0 elements, 0 messages
+`
+	if b.String() != want {
+		t.Errorf("want:\n%s\nhave:\n%s", want, &b)
+	}
+}
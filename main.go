@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/fiorix/wsdl2go/wsdl"
 	"github.com/fiorix/wsdl2go/wsdlgo"
@@ -17,23 +23,85 @@ import (
 var version = "tip"
 
 type options struct {
-	Src            string
-	Dst            string
-	Package        string
-	Namespace      string
-	Insecure       bool
-	ClientCertFile string
-	ClientKeyFile  string
-	Version        bool
+	Src             string
+	Dst             string
+	OpenAPI         string
+	Tests           string
+	Samples         string
+	Package         string
+	Namespace       string
+	DocLang         string
+	Compat          string
+	Naming          string
+	Initialisms     string
+	OpLabels        bool
+	Minimal         bool
+	Getters         bool
+	EqualCopy       bool
+	Constructors    bool
+	FieldTags       string
+	OmitEmpty       bool
+	OmitEmptyFlip   string
+	ValueScalars    bool
+	ChoiceStructs   bool
+	ResponseWrapper bool
+	RawResponse     bool
+	Unwrap          bool
+	Strict          bool
+	Lenient         bool
+	Verbose         bool
+	Catalog         string
+	CacheDir        string
+	CacheTTL        time.Duration
+	Fetch           fetchOptions
+	Insecure        bool
+	ClientCertFile  string
+	ClientKeyFile   string
+	Version         bool
 }
 
 func main() {
-	opts := options{}
+	if len(os.Args) > 1 && os.Args[1] == "list" {
+		if err := list(os.Stdout, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	opts := options{OmitEmpty: true}
 
 	flag.StringVar(&opts.Src, "i", opts.Src, "input file, url, or '-' for stdin")
 	flag.StringVar(&opts.Dst, "o", opts.Dst, "output file, or '-' for stdout")
+	flag.StringVar(&opts.OpenAPI, "openapi", opts.OpenAPI, "also write an OpenAPI 3 document (yaml) to file, or '-' for stdout")
+	flag.StringVar(&opts.Tests, "tests", opts.Tests, "also write XML round-trip tests of generated types to file, or '-' for stdout")
+	flag.StringVar(&opts.Samples, "samples", opts.Samples, "also write sample request/response envelopes to directory, or '-' for stdout")
 	flag.StringVar(&opts.Namespace, "n", opts.Namespace, "override namespace")
 	flag.StringVar(&opts.Package, "p", opts.Package, "package name")
+	flag.StringVar(&opts.Compat, "compat", opts.Compat, "keep the generated type names of a previous version, 'v1', for existing code")
+	flag.StringVar(&opts.Naming, "naming", opts.Naming, "case of initialisms in generated identifiers, 'go' as in CustomerID (default), or 'title' as in CustomerId, as older versions")
+	flag.StringVar(&opts.Initialisms, "initialisms", opts.Initialisms, "comma-separated initialisms written in upper case by -naming go, replacing the golint set")
+	flag.StringVar(&opts.DocLang, "doclang", opts.DocLang, "language of documentation comments, such as 'en', for WSDLs documented in several languages")
+	flag.BoolVar(&opts.OpLabels, "oplabels", opts.OpLabels, "call the Observe hook of soap.Client with service.port.operation names, for metrics")
+	flag.BoolVar(&opts.Getters, "getters", opts.Getters, "generate GetX methods of pointer fields X that return the zero value when they're nil")
+	flag.BoolVar(&opts.EqualCopy, "equal-copy", opts.EqualCopy, "generate Equal and DeepCopy methods of structs, which compare and copy their values")
+	flag.BoolVar(&opts.Constructors, "constructors", opts.Constructors, "generate NewX functions of structs X taking their required fields, and setting the defaults of optional ones")
+	flag.StringVar(&opts.FieldTags, "field-tags", opts.FieldTags, "file of struct tags to add to fields by name, type or facet, such as validate:\"required\" on required ones")
+	flag.BoolVar(&opts.OmitEmpty, "omitempty", opts.OmitEmpty, "make optional fields pointers tagged with omitempty, or else values that are always sent")
+	flag.StringVar(&opts.OmitEmptyFlip, "omitempty-structs", opts.OmitEmptyFlip, "comma-separated structs whose optional fields do the opposite of -omitempty")
+	flag.BoolVar(&opts.ValueScalars, "value-scalars", opts.ValueScalars, "make optional fields of simple types values rather than pointers, except nillable ones")
+	flag.BoolVar(&opts.ChoiceStructs, "choice-structs", opts.ChoiceStructs, "generate choices of elements as structs that only encode the element that is set")
+	flag.BoolVar(&opts.ResponseWrapper, "response-wrapper", opts.ResponseWrapper, "make operations return their response struct rather than a value per part of the output")
+	flag.BoolVar(&opts.RawResponse, "raw-response", opts.RawResponse, "make operations also return the raw response envelope and HTTP response, as *soap.Response")
+	flag.BoolVar(&opts.Unwrap, "unwrap", opts.Unwrap, "make document/literal operations return the single element of their response, e.g. *Customer, rather than the response")
+	flag.BoolVar(&opts.Strict, "strict", opts.Strict, "fail listing the types referenced but not found, rather than generating code that doesn't build")
+	flag.BoolVar(&opts.Lenient, "lenient", opts.Lenient, "skip imports that can't be fetched, with a warning, and generate placeholders keeping the raw XML of the types not found")
+	flag.BoolVar(&opts.Verbose, "v", opts.Verbose, "log the documents imported, and the operations and constructs that aren't generated, besides warnings")
+	flag.BoolVar(&opts.Minimal, "minimal", opts.Minimal, "generate code without reflection, for constrained targets such as TinyGo")
+	flag.StringVar(&opts.Catalog, "catalog", opts.Catalog, "file mapping namespaces or locations of imported documents to local files, to read instead of downloading")
+	flag.StringVar(&opts.CacheDir, "cache-dir", opts.CacheDir, "keep downloaded documents in directory, to reuse them in later runs")
+	flag.DurationVar(&opts.CacheTTL, "cache-ttl", opts.CacheTTL, "how long to reuse documents of -cache-dir, or forever if 0")
+	flag.StringVar(&opts.Fetch.Method, "method", "GET", "HTTP method of the request for the input url, e.g. POST for gateways that serve WSDLs to SOAP calls")
+	flag.StringVar(&opts.Fetch.Body, "body", opts.Fetch.Body, "file with the body of the request for the input url")
+	flag.Var(&opts.Fetch.Header, "header", "HTTP header of the request for the input url, as 'Name: value' (repeatable)")
 	flag.BoolVar(&opts.Insecure, "yolo", opts.Insecure, "accept invalid https certificates")
 	flag.StringVar(&opts.ClientCertFile, "cert", opts.ClientCertFile, "use client TLS cert file")
 	flag.StringVar(&opts.ClientKeyFile, "key", opts.ClientKeyFile, "use client TLS key file")
@@ -43,58 +111,168 @@ func main() {
 		fmt.Printf("wsdl2go %s\n", version)
 		return
 	}
-	var w io.Writer
-	switch opts.Dst {
-	case "", "-":
-		w = os.Stdout
-	default:
-		f, err := os.OpenFile(opts.Dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-		w = f
-	}
-
 	cli := httpClient(opts.Insecure, opts.ClientCertFile, opts.ClientKeyFile)
 
-	err := codegen(w, opts, cli)
+	files, err := codegen(opts, cli)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err = writeOutputs(os.Stdout, files); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func codegen(w io.Writer, opts options, cli *http.Client) error {
-	var err error
-	var f io.ReadCloser
-	if opts.Src == "" || opts.Src == "-" {
-		f = os.Stdin
-	} else if f, err = open(opts.Src, cli); err != nil {
-		return err
+func codegen(opts options, cli *http.Client) ([]*outputFile, error) {
+	var cache *wsdlgo.Cache
+	if opts.CacheDir != "" {
+		cache = &wsdlgo.Cache{Dir: opts.CacheDir, TTL: opts.CacheTTL}
 	}
-	d, err := wsdl.Unmarshal(f)
+	d, err := load(opts.Src, cli, cache, &opts.Fetch)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts.Verbose {
+		for _, p := range d.Validate() {
+			log.Print(p)
+		}
 	}
-	f.Close()
 
-	enc := wsdlgo.NewEncoder(w)
-	enc.SetClient(cli)
+	code := &outputFile{Name: "client.go", Dst: opts.Dst}
+	if opts.Package != "" {
+		code.Name = opts.Package + ".go"
+	}
+	tests := &outputFile{Name: strings.TrimSuffix(code.Name, ".go") + "_test.go", Dst: opts.Tests}
+	enums := &outputFile{Name: strings.TrimSuffix(code.Name, ".go") + "_enums.go", Dst: opts.Dst}
+	if opts.Dst != "" && opts.Dst != "-" {
+		enums.Dst = strings.TrimSuffix(opts.Dst, ".go") + "_enums.go"
+	}
+	encOpts := []wsdlgo.Option{
+		wsdlgo.WithClient(cli),
+		wsdlgo.WithDocLang(opts.DocLang),
+		wsdlgo.WithOperationLabels(opts.OpLabels),
+		wsdlgo.WithMinimal(opts.Minimal),
+		wsdlgo.WithGetters(opts.Getters),
+		wsdlgo.WithEqualCopy(opts.EqualCopy),
+		wsdlgo.WithConstructors(opts.Constructors),
+		wsdlgo.WithOmitEmpty(opts.OmitEmpty),
+		wsdlgo.WithValueScalars(opts.ValueScalars),
+		wsdlgo.WithChoiceStructs(opts.ChoiceStructs),
+		wsdlgo.WithResponseWrapper(opts.ResponseWrapper),
+		wsdlgo.WithRawResponse(opts.RawResponse),
+		wsdlgo.WithUnwrap(opts.Unwrap),
+		wsdlgo.WithStrict(opts.Strict),
+		wsdlgo.WithLenient(opts.Lenient),
+		wsdlgo.WithCompat(opts.Compat),
+		wsdlgo.WithNaming(wsdlgo.Naming(opts.Naming)),
+		wsdlgo.WithEnumWriter(&enums.Data),
+	}
+	if opts.Tests != "" {
+		encOpts = append(encOpts, wsdlgo.WithTestWriter(&tests.Data))
+	}
+	if cache != nil {
+		encOpts = append(encOpts, wsdlgo.WithCache(cache))
+	}
+	if opts.Catalog != "" {
+		catalog, err := wsdlgo.LoadCatalog(opts.Catalog)
+		if err != nil {
+			return nil, err
+		}
+		encOpts = append(encOpts, wsdlgo.WithCatalog(catalog))
+	}
+	if opts.OmitEmptyFlip != "" {
+		for _, name := range strings.Split(opts.OmitEmptyFlip, ",") {
+			encOpts = append(encOpts, wsdlgo.WithStructOmitEmpty(strings.TrimSpace(name), !opts.OmitEmpty))
+		}
+	}
+	if opts.Verbose {
+		encOpts = append(encOpts, wsdlgo.WithReporter(func(e wsdlgo.Event) { log.Print(e) }))
+	}
+	if opts.Initialisms != "" {
+		var words []string
+		for _, w := range strings.Split(opts.Initialisms, ",") {
+			words = append(words, strings.TrimSpace(w))
+		}
+		encOpts = append(encOpts, wsdlgo.WithInitialisms(words))
+	}
+	if opts.FieldTags != "" {
+		tags, err := wsdlgo.LoadFieldTags(opts.FieldTags)
+		if err != nil {
+			return nil, err
+		}
+		encOpts = append(encOpts, wsdlgo.WithFieldTags(tags))
+	}
+	if opts.Src != "-" {
+		encOpts = append(encOpts, wsdlgo.WithBaseURL(opts.Src))
+	}
 	if opts.Package != "" {
-		enc.SetPackageName(wsdlgo.PackageName(opts.Package))
+		encOpts = append(encOpts, wsdlgo.WithPackageName(wsdlgo.PackageName(opts.Package)))
 	}
 	if opts.Namespace != "" {
-		enc.SetLocalNamespace(opts.Namespace)
+		encOpts = append(encOpts, wsdlgo.WithLocalNamespace(opts.Namespace))
+	}
+	enc := wsdlgo.NewEncoder(&code.Data, encOpts...)
+
+	if err = enc.Encode(d); err != nil {
+		return nil, err
+	}
+	files := []*outputFile{code}
+	if enums.Data.Len() > 0 {
+		files = append(files, enums)
+	}
+	if opts.Tests != "" && tests.Data.Len() > 0 {
+		files = append(files, tests)
 	}
+	if opts.OpenAPI != "" {
+		spec := &outputFile{Name: "openapi.yaml", Dst: opts.OpenAPI}
+		if err = wsdlgo.EncodeOpenAPI(&spec.Data, d); err != nil {
+			return nil, err
+		}
+		files = append(files, spec)
+	}
+	if opts.Samples != "" {
+		for _, sample := range wsdlgo.EncodeSamples(d) {
+			f := &outputFile{Name: path.Join("samples", sample.Name), Dst: opts.Samples}
+			if opts.Samples != "-" {
+				f.Dst = filepath.Join(opts.Samples, sample.Name)
+			}
+			f.Data.Write(sample.Data)
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
 
-	return enc.Encode(d)
+// load reads the WSDL document from the src file, url, or stdin. Urls
+// are downloaded with the request set by fetch, or through cache with
+// the default request, unless nil.
+func load(src string, cli *http.Client, cache *wsdlgo.Cache, fetch *fetchOptions) (*wsdl.Definitions, error) {
+	var err error
+	var f io.ReadCloser
+	loc := src
+	if src == "" || src == "-" {
+		f, loc = os.Stdin, "<stdin>"
+	} else if f, err = open(src, cli, cache, fetch); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return wsdl.UnmarshalFrom(f, loc)
 }
 
-func open(name string, cli *http.Client) (io.ReadCloser, error) {
+func open(name string, cli *http.Client, cache *wsdlgo.Cache, fetch *fetchOptions) (io.ReadCloser, error) {
 	u, err := url.Parse(name)
 	if err != nil || u.Scheme == "" {
 		return os.Open(name)
 	}
+	if fetch != nil && !fetch.isDefault() {
+		return fetch.open(cli, name)
+	}
+	if cache != nil {
+		data, err := cache.Get(cli, name)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
 	resp, err := cli.Get(name)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// outputFile is one of the files generated by wsdl2go.
+type outputFile struct {
+	Name string // Name of the file in archives
+	Dst  string // Destination file, or "-" for stdout
+	Data bytes.Buffer
+}
+
+// writeOutputs writes files to their destinations. When more than one
+// file is destined to stdout, they're written as a txtar archive so
+// pipelines can still capture all of them. Each file in the archive
+// starts with a "-- name --" line, followed by its contents.
+func writeOutputs(stdout io.Writer, files []*outputFile) error {
+	var archive []*outputFile
+	for _, f := range files {
+		switch f.Dst {
+		case "", "-":
+			archive = append(archive, f)
+		default:
+			if err := os.MkdirAll(filepath.Dir(f.Dst), 0755); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(f.Dst, f.Data.Bytes(), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	if len(archive) == 1 {
+		_, err := archive[0].Data.WriteTo(stdout)
+		return err
+	}
+	for _, f := range archive {
+		if _, err := fmt.Fprintf(stdout, "-- %s --\n", f.Name); err != nil {
+			return err
+		}
+		data := f.Data.Bytes()
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		if _, err := stdout.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
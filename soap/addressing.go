@@ -0,0 +1,157 @@
+package soap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// AddressingNamespace is a link to the WS-Addressing 1.0 namespace.
+const AddressingNamespace = "http://www.w3.org/2005/08/addressing"
+
+// SOAPActionMode selects how the SOAPAction HTTP header is sent in
+// requests that carry a WS-Addressing action.
+type SOAPActionMode int
+
+const (
+	// SOAPActionMatch sends the SOAPAction header with the same value
+	// as wsa:Action.
+	SOAPActionMatch SOAPActionMode = iota
+
+	// SOAPActionEmpty sends an empty SOAPAction header.
+	SOAPActionEmpty
+
+	// SOAPActionOmit sends no SOAPAction header.
+	SOAPActionOmit
+)
+
+// RoundTripWithAddressing implements the RoundTripper interface for
+// SOAP 1.1 services that use WS-Addressing. The action is sent in the
+// wsa:Action header along with wsa:MessageID and wsa:To, and in the
+// SOAPAction HTTP header as set by c.AddressingSOAPAction.
+func (c *Client) RoundTripWithAddressing(action string, in, out Message) error {
+	id, err := messageID()
+	if err != nil {
+		return err
+	}
+	header := &addressingHeader{
+		Action:    action,
+		MessageID: id,
+		To:        c.URL,
+		Header:    c.Header,
+	}
+	headerFunc := func(r *http.Request) {
+		c.setContentHeaders(r)
+		switch c.AddressingSOAPAction {
+		case SOAPActionMatch:
+			r.Header.Set("SOAPAction", action)
+		case SOAPActionEmpty:
+			r.Header["SOAPAction"] = []string{""}
+		}
+	}
+	return doRoundTripAttachments(c, headerFunc, header, in, out, nil, nil)
+}
+
+// messageID returns a random UUID URN to identify a message.
+func messageID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// addressingHeader is the SOAP header of WS-Addressing requests. The
+// contents of the client's Header follow the addressing properties.
+type addressingHeader struct {
+	Action    string
+	MessageID string
+	To        string
+	Header    Header
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (h *addressingHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var inner []xml.Token
+	if h.Header != nil {
+		var b bytes.Buffer
+		if err := xml.NewEncoder(&b).EncodeElement(h.Header, start); err != nil {
+			return err
+		}
+		d := xml.NewDecoder(&b)
+		for {
+			t, err := d.RawToken()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			inner = append(inner, prefixedToken(xml.CopyToken(t)))
+		}
+		// Keep the attributes of the client's header element, such as
+		// namespace declarations, and drop the element itself.
+		if len(inner) >= 2 {
+			if s, ok := inner[0].(xml.StartElement); ok {
+				start.Attr = append(start.Attr, s.Attr...)
+			}
+			inner = inner[1 : len(inner)-1]
+		}
+	}
+	start.Attr = append(start.Attr, xml.Attr{
+		Name:  xml.Name{Local: "xmlns:wsa"},
+		Value: AddressingNamespace,
+	})
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	properties := []struct{ name, value string }{
+		{"wsa:Action", h.Action},
+		{"wsa:MessageID", h.MessageID},
+		{"wsa:To", h.To},
+	}
+	for _, p := range properties {
+		if p.value == "" {
+			continue
+		}
+		el := xml.StartElement{Name: xml.Name{Local: p.name}}
+		if err := e.EncodeElement(p.value, el); err != nil {
+			return err
+		}
+	}
+	for _, t := range inner {
+		if err := e.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// prefixedToken returns t with the prefixes of element and attribute
+// names moved into their local names, as in the struct tags used with
+// this package, so it's encoded again with the same prefixes.
+func prefixedToken(t xml.Token) xml.Token {
+	prefixed := func(n xml.Name) xml.Name {
+		if n.Space == "" {
+			return n
+		}
+		return xml.Name{Local: n.Space + ":" + n.Local}
+	}
+	switch v := t.(type) {
+	case xml.StartElement:
+		v.Name = prefixed(v.Name)
+		for i := range v.Attr {
+			v.Attr[i].Name = prefixed(v.Attr[i].Name)
+		}
+		return v
+	case xml.EndElement:
+		v.Name = prefixed(v.Name)
+		return v
+	}
+	return t
+}
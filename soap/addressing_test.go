@@ -0,0 +1,62 @@
+package soap
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRoundTripWithAddressing(t *testing.T) {
+	type msgT struct{ A string }
+	var headers http.Header
+	var body []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("<Envelope><Body><A>world</A></Body></Envelope>"))
+	}))
+	defer s.Close()
+
+	cases := []struct {
+		Mode   SOAPActionMode
+		Action []string
+	}{
+		{SOAPActionMatch, []string{"urn:test/Echo"}},
+		{SOAPActionEmpty, []string{""}},
+		{SOAPActionOmit, nil},
+	}
+	for _, tc := range cases {
+		c := &Client{
+			URL:                  s.URL,
+			Header:               &AuthHeader{Namespace: "urn:auth", Username: "user", Password: "pass"},
+			AddressingSOAPAction: tc.Mode,
+		}
+		var out msgT
+		if err := c.RoundTripWithAddressing("urn:test/Echo", &msgT{A: "hello"}, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.A != "world" {
+			t.Errorf("mode %d: unexpected response: %q", tc.Mode, out.A)
+		}
+		if have := headers["Soapaction"]; len(have) != len(tc.Action) || (len(have) > 0 && have[0] != tc.Action[0]) {
+			t.Errorf("mode %d: want SOAPAction %q, have %q", tc.Mode, tc.Action, have)
+		}
+		for _, want := range []string{
+			`<SOAP-ENV:Header xmlns:ns="urn:auth" xmlns:wsa="` + AddressingNamespace + `">`,
+			`<wsa:Action>urn:test/Echo</wsa:Action>`,
+			`<wsa:MessageID>urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}</wsa:MessageID>`,
+			`<wsa:To>` + regexp.QuoteMeta(s.URL) + `</wsa:To>`,
+			`<ns:username>user</ns:username><ns:password>pass</ns:password></SOAP-ENV:Header>`,
+		} {
+			if !regexp.MustCompile(want).Match(body) {
+				t.Errorf("mode %d: missing %s in request:\n%s", tc.Mode, want, body)
+			}
+		}
+		if err := xml.Unmarshal(body, new(interface{})); err != nil {
+			t.Errorf("mode %d: malformed request: %v", tc.Mode, err)
+		}
+	}
+}
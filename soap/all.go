@@ -0,0 +1,75 @@
+package soap
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// A Call is a prepared SOAP call, run by All. It's usually a closure
+// that calls a method of generated code and keeps its result:
+//
+//	var quote *GetQuoteResponse
+//	getQuote := func(ctx context.Context) (err error) {
+//		quote, err = svc.GetQuote(&GetQuote{Symbol: "ABC"})
+//		return err
+//	}
+type Call func(ctx context.Context) error
+
+// Errors holds the errors of calls run by All, in the order of the
+// calls that failed.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// All runs the calls concurrently and waits for them to finish. It
+// returns nil if all calls succeed, or else the Errors of the calls
+// that failed. Calls that haven't started when ctx is done fail with
+// its error.
+//
+// The number of concurrent requests to a service can be limited with
+// the MaxConcurrent field of its Client, or across calls with AllLimit.
+func All(ctx context.Context, calls ...Call) error {
+	return AllLimit(ctx, 0, calls...)
+}
+
+// AllLimit is like All, but runs at most limit calls at a time. A limit
+// of 0 or less runs all calls at once.
+func AllLimit(ctx context.Context, limit int, calls ...Call) error {
+	if limit <= 0 || limit > len(calls) {
+		limit = len(calls)
+	}
+	errs := make([]error, len(calls))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, call Call) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = call(ctx)
+		}(i, call)
+	}
+	wg.Wait()
+	var failed Errors
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
@@ -0,0 +1,90 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	results := make([]int, 5)
+	var calls []Call
+	for i := range results {
+		i := i
+		calls = append(calls, func(ctx context.Context) error {
+			results[i] = i * i
+			if i%2 == 1 {
+				return errors.New("odd")
+			}
+			return nil
+		})
+	}
+	err := All(context.Background(), calls...)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if want := "odd; odd"; err.Error() != want {
+		t.Errorf("want %q, have %q", want, err.Error())
+	}
+	for i, v := range results {
+		if v != i*i {
+			t.Errorf("call %d: want %d, have %d", i, i*i, v)
+		}
+	}
+	ok1 := func(ctx context.Context) error { return nil }
+	if err := All(context.Background(), ok1, ok1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := All(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAllLimit(t *testing.T) {
+	const limit = 2
+	var mu sync.Mutex
+	var inflight, maxInflight int
+	call := func(ctx context.Context) error {
+		mu.Lock()
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+		return nil
+	}
+	if err := AllLimit(context.Background(), limit, call, call, call, call, call); err != nil {
+		t.Fatal(err)
+	}
+	if maxInflight != limit {
+		t.Errorf("want %d concurrent calls, have %d", limit, maxInflight)
+	}
+}
+
+func TestAllLimitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	blocked := func(ctx context.Context) error {
+		cancel()
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() {
+		done <- AllLimit(ctx, 1, blocked, blocked, blocked)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	err := <-done
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 || errs[0] != context.Canceled {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
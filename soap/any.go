@@ -0,0 +1,93 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// AnyElement is an XML element of any name and content, such as those
+// of xsd:any in schemas, kept as is so it encodes back the same. Its
+// content can be decoded into a type with Decode.
+//
+// Namespace prefixes declared by the element are kept, while those of
+// its attributes declared by its ancestors are renamed when encoded.
+type AnyElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content []byte     `xml:",innerxml"`
+}
+
+// UnmarshalXML decodes the element in start. Its namespace declarations
+// are kept as plain attributes, as encoding/xml would otherwise declare
+// them again, except the default one, which is encoded from its name.
+func (a *AnyElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var inner struct {
+		Content []byte `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&inner, &start); err != nil {
+		return err
+	}
+	prefixes := make(map[string]string)
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+	a.XMLName, a.Attrs, a.Content = start.Name, nil, inner.Content
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			continue
+		case attr.Name.Space == "xmlns":
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		case prefixes[attr.Name.Space] != "":
+			attr.Name = xml.Name{Local: prefixes[attr.Name.Space] + ":" + attr.Name.Local}
+		}
+		a.Attrs = append(a.Attrs, attr)
+	}
+	return nil
+}
+
+// Decode decodes the element into v, as xml.Unmarshal.
+func (a *AnyElement) Decode(v interface{}) error {
+	b, err := xml.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// Equal reports whether a and o have the same name, attributes and
+// content, or are both nil, as called by generated Equal methods.
+func (a *AnyElement) Equal(o *AnyElement) bool {
+	if a == nil || o == nil {
+		return a == o
+	}
+	if a.XMLName != o.XMLName || len(a.Attrs) != len(o.Attrs) {
+		return false
+	}
+	for i := range a.Attrs {
+		if a.Attrs[i] != o.Attrs[i] {
+			return false
+		}
+	}
+	return bytes.Equal(a.Content, o.Content)
+}
+
+// DeepCopy returns a copy of a that shares no slices with it, or nil if
+// a is nil, as called by generated DeepCopy methods.
+func (a *AnyElement) DeepCopy() *AnyElement {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	if a.Attrs != nil {
+		c.Attrs = make([]xml.Attr, len(a.Attrs))
+		copy(c.Attrs, a.Attrs)
+	}
+	if a.Content != nil {
+		c.Content = make([]byte, len(a.Content))
+		copy(c.Content, a.Content)
+	}
+	return &c
+}
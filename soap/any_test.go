@@ -0,0 +1,62 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestAnyElement(t *testing.T) {
+	type Doc struct {
+		XMLName xml.Name     `xml:"doc"`
+		Known   string       `xml:"known"`
+		Any     []AnyElement `xml:",any"`
+	}
+	want := `<doc><known>a</known><ext xmlns="urn:ext" xmlns:p="urn:p" p:id="1"><p:b>c</p:b></ext></doc>`
+	var d Doc
+	if err := xml.Unmarshal([]byte(want), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Any) != 1 || d.Any[0].XMLName.Local != "ext" || d.Any[0].XMLName.Space != "urn:ext" {
+		t.Fatalf("unexpected any elements: %+v", d.Any)
+	}
+	have, err := xml.Marshal(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(have) != want {
+		t.Fatalf("round trip mismatch\nwant: %s\nhave: %s", want, have)
+	}
+	var ext struct {
+		ID string `xml:"urn:p id,attr"`
+		B  string `xml:"urn:p b"`
+	}
+	if err := d.Any[0].Decode(&ext); err != nil {
+		t.Fatal(err)
+	}
+	if ext.ID != "1" || ext.B != "c" {
+		t.Fatalf("unexpected decoded element: %+v", ext)
+	}
+}
+
+func TestAnyElementEqualCopy(t *testing.T) {
+	a := &AnyElement{
+		XMLName: xml.Name{Space: "urn:ext", Local: "ext"},
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "id"}, Value: "1"}},
+		Content: []byte("<b>c</b>"),
+	}
+	c := a.DeepCopy()
+	if !a.Equal(c) || !c.Equal(a) {
+		t.Fatalf("copy differs: %+v", c)
+	}
+	c.Attrs[0].Value, c.Content[1] = "2", 'x'
+	if a.Attrs[0].Value != "1" || string(a.Content) != "<b>c</b>" {
+		t.Fatalf("copy shares slices: %+v", a)
+	}
+	if a.Equal(c) {
+		t.Fatal("want different elements")
+	}
+	var none *AnyElement
+	if !none.Equal(nil) || none.Equal(a) || none.DeepCopy() != nil {
+		t.Fatal("unexpected results of nil element")
+	}
+}
@@ -0,0 +1,137 @@
+package soap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// Attachment is a MIME part sent or received along with the SOAP
+// envelope in a multipart/related message, as in SOAP with Attachments.
+type Attachment struct {
+	Name        string // Name of the message part, used as Content-ID
+	ContentType string // Optional Content-Type (default application/octet-stream)
+	Data        []byte
+}
+
+// Attachments is a list of attachments received in a response.
+type Attachments []Attachment
+
+// Data returns the data of the attachment for the named message part,
+// or nil if there's no such attachment. Attachments are matched by
+// their Content-ID, which is either the part name, or starts with it
+// followed by "=" as in the WS-I Attachments Profile, e.g.
+// <result=4a1b3c@example.com>.
+func (a Attachments) Data(name string) []byte {
+	for _, att := range a {
+		if att.Name == name || strings.HasPrefix(att.Name, name+"=") {
+			return att.Data
+		}
+	}
+	return nil
+}
+
+const rootPartID = "<soap-envelope>"
+
+// RoundTripWithAttachments implements the RoundTripper interface for
+// operations with MIME bindings. The SOAP envelope and attachments are
+// sent as a multipart/related request, and the attachments of the
+// response are returned.
+func (c *Client) RoundTripWithAttachments(soapAction string, in, out Message, attachments ...Attachment) (Attachments, error) {
+	var received Attachments
+	headerFunc := func(r *http.Request) {
+		c.setActionHeaders(r, soapAction, in)
+	}
+	err := doRoundTripAttachments(c, headerFunc, c.Header, in, out, attachments, &received)
+	return received, err
+}
+
+// writeMultipart writes the envelope in b and the attachments to a new
+// multipart/related body, and returns its Content-Type. The envelope
+// part has the given Content-Type.
+func writeMultipart(b *bytes.Buffer, ct string, attachments []Attachment) (*bytes.Buffer, string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", ct)
+	h.Set("Content-ID", rootPartID)
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err = io.Copy(pw, b); err != nil {
+		return nil, "", err
+	}
+	for _, att := range attachments {
+		h := make(textproto.MIMEHeader)
+		if att.ContentType != "" {
+			h.Set("Content-Type", att.ContentType)
+		} else {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+		h.Set("Content-ID", "<"+att.Name+">")
+		h.Set("Content-Transfer-Encoding", "binary")
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err = pw.Write(att.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, "", err
+	}
+	typ, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, "", err
+	}
+	mct := mime.FormatMediaType("multipart/related", map[string]string{
+		"type":     typ,
+		"start":    rootPartID,
+		"boundary": mw.Boundary(),
+	})
+	return &body, mct, nil
+}
+
+// readMultipart reads the multipart/related response body r with the
+// given boundary. It returns the SOAP envelope, which is the part
+// identified by start or else the first part, and the attachments.
+func readMultipart(r io.Reader, boundary, start string) (io.Reader, Attachments, error) {
+	var envelope io.Reader
+	var attachments Attachments
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		id := p.Header.Get("Content-ID")
+		if envelope == nil && (start == "" || id == start) {
+			envelope = bytes.NewReader(data)
+			continue
+		}
+		attachments = append(attachments, Attachment{
+			Name:        strings.Trim(id, "<>"),
+			ContentType: p.Header.Get("Content-Type"),
+			Data:        data,
+		})
+	}
+	if envelope == nil {
+		return nil, nil, fmt.Errorf("soap: multipart response has no envelope")
+	}
+	return envelope, attachments, nil
+}
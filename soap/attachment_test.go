@@ -0,0 +1,103 @@
+package soap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripWithAttachments(t *testing.T) {
+	type msgT struct{ A, B string }
+	type respT struct{ M msgT }
+
+	echoEnvelope := `<Envelope><Body><M><A>hello</A><B>world</B></M></Body></Envelope>`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mt, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mt != "multipart/related" {
+			http.Error(w, "unexpected content type", http.StatusBadRequest)
+			return
+		}
+		if params["type"] != "text/xml" {
+			http.Error(w, "unexpected root type", http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var data []byte
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if p.Header.Get("Content-ID") == "<file>" {
+				data, _ = ioutil.ReadAll(p)
+			}
+		}
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Type", "text/xml")
+		h.Set("Content-ID", "<root>")
+		pw, _ := mw.CreatePart(h)
+		pw.Write([]byte(echoEnvelope))
+		h = make(textproto.MIMEHeader)
+		h.Set("Content-Type", "application/octet-stream")
+		h.Set("Content-ID", "<result>")
+		pw, _ = mw.CreatePart(h)
+		pw.Write(bytes.ToUpper(data))
+		mw.Close()
+		w.Header().Set("Content-Type", `multipart/related; type="text/xml"; start="<root>"; boundary=`+mw.Boundary())
+		w.Write(b.Bytes())
+	}))
+	defer s.Close()
+
+	req := &msgT{A: "hello", B: "world"}
+	resp := &respT{}
+	c := &Client{URL: s.URL}
+	attachments, err := c.RoundTripWithAttachments("foo", req, resp, Attachment{
+		Name: "file",
+		Data: []byte("attached"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.M.A != "hello" || resp.M.B != "world" {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	if got := string(attachments.Data("result")); got != "ATTACHED" {
+		t.Fatalf("unexpected attachment: %q", got)
+	}
+	if attachments.Data("missing") != nil {
+		t.Fatal("unexpected data for missing attachment")
+	}
+	if ct := attachments[0].ContentType; !strings.HasPrefix(ct, "application/octet-stream") {
+		t.Fatalf("unexpected attachment content type: %q", ct)
+	}
+}
+
+func TestAttachmentsData(t *testing.T) {
+	a := Attachments{
+		{Name: "file", Data: []byte("file")},
+		{Name: "result=4a1b3c@example.com", Data: []byte("result")},
+		{Name: "resultSet", Data: []byte("resultSet")},
+	}
+	for _, tc := range []struct {
+		Name string
+		Want string
+	}{
+		{Name: "file", Want: "file"},
+		{Name: "result", Want: "result"},
+		{Name: "resultSet", Want: "resultSet"},
+		{Name: "res", Want: ""},
+		{Name: "missing", Want: ""},
+	} {
+		if have := string(a.Data(tc.Name)); have != tc.Want {
+			t.Errorf("%s: want %q, have %q", tc.Name, tc.Want, have)
+		}
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html/charset"
 )
@@ -17,6 +22,12 @@ import (
 // XSINamespace is a link to the XML Schema instance namespace.
 const XSINamespace = "http://www.w3.org/2001/XMLSchema-instance"
 
+// XSDNamespace is a link to the XML Schema namespace.
+const XSDNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// EncodingNamespace is a link to the SOAP 1.1 encoding namespace.
+const EncodingNamespace = "http://schemas.xmlsoap.org/soap/encoding/"
+
 var xmlTyperType reflect.Type = reflect.TypeOf((*XMLTyper)(nil)).Elem()
 
 // A RoundTripper executes a request passing the given req as the SOAP
@@ -43,6 +54,24 @@ type AuthHeader struct {
 	Password  string `xml:"ns:password"`
 }
 
+// Encoding is embedded in the body of rpc/encoded requests. It sets the
+// SOAP encodingStyle attribute and declares the namespace prefixes used
+// in xsi:type and SOAP-ENC:arrayType attributes.
+type Encoding struct {
+	EncodingStyle string `xml:"SOAP-ENV:encodingStyle,attr"`
+	EncAttr       string `xml:"xmlns:SOAP-ENC,attr"`
+	XSDAttr       string `xml:"xmlns:xsd,attr"`
+}
+
+// Encoded returns the Encoding of SOAP 1.1 encoded requests.
+func Encoded() Encoding {
+	return Encoding{
+		EncodingStyle: EncodingNamespace,
+		EncAttr:       EncodingNamespace,
+		XSDAttr:       XSDNamespace,
+	}
+}
+
 // Client is a SOAP client.
 type Client struct {
 	URL                    string               // URL of the server
@@ -58,6 +87,85 @@ type Client struct {
 	Pre                    func(*http.Request)  // Optional hook to modify outbound requests
 	Post                   func(*http.Response) // Optional hook to snoop inbound responses
 	Ctx                    context.Context      // Optional variable to allow Context Tracking.
+	MaxConcurrent          int                  // Optional limit of in-flight round trips (default unlimited)
+	ResolveRefs            bool                 // Optional resolution of href/multiRef references in responses
+	Digest                 string               // Optional request body digest: SHA-256, SHA-512 (Digest header) or MD5 (Content-MD5)
+	AddressingSOAPAction   SOAPActionMode       // Optional SOAPAction header of WS-Addressing requests (default same as wsa:Action)
+	Observe                ObserveFunc          // Optional hook to observe operations of generated code, e.g. for metrics
+	ConnTrace              func(ConnInfo)       // Optional hook to inspect the connection of each round trip, see ConnStats
+	SkipXMLType            bool                 // Optional skip of setting xsi:type attributes of requests with reflection
+	ResponseTransformers   ResponseChain        // Optional transformers of response bodies before decoding, e.g. EscapeAmpersands
+	RequestTransformers    RequestChain         // Optional transformers of encoded request envelopes, e.g. XMLHeader
+	OnResponse             func(*Response)      // Optional hook to get the raw response of each round trip, see WithResponse
+	Namespaces             map[string]string    // Optional namespaces declared by the envelope, by prefix, e.g. the Namespaces of generated code
+
+	semOnce sync.Once
+	sem     chan struct{}
+	parent  *Client // whose round trip slots are shared, see WithHeader
+}
+
+// WithHeader returns a client that sends header as the SOAP Header of
+// its requests, and is otherwise configured as c when called, e.g. to
+// authenticate the calls of a port type without changing the Client
+// shared by others. Both clients share the MaxConcurrent round trips.
+func (c *Client) WithHeader(header Header) *Client {
+	hc := &Client{parent: c}
+	if c.parent != nil {
+		hc.parent = c.parent
+	}
+	src, dst := reflect.ValueOf(c).Elem(), reflect.ValueOf(hc).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		if f := dst.Field(i); f.CanSet() {
+			f.Set(src.Field(i))
+		}
+	}
+	hc.Header = header
+	return hc
+}
+
+// ObserveFunc is called when an operation of generated code is done,
+// with its name as service.port.operation, how long it took and the
+// error it returned, if any. Names are made of lower case letters,
+// digits and underscores, to be used as labels of metrics.
+type ObserveFunc func(op string, d time.Duration, err error)
+
+// Base is embedded by the clients generated for port types. Methods
+// added to them, in files of the generated package that are not
+// overwritten, call the service through the embedded Client.
+type Base struct {
+	Client *Client
+}
+
+// Observe calls the Observe hook of c, if any, with the operation op,
+// the time elapsed since start and err, and returns err. Generated code
+// calls it when generated with operation labels, which name operations
+// as service.port.operation.
+func Observe(c *Client, op string, start time.Time, err error) error {
+	if c != nil && c.Observe != nil {
+		c.Observe(op, time.Since(start), err)
+	}
+	return err
+}
+
+// acquire reserves one of the MaxConcurrent round trip slots, waiting
+// until one is released or ctx is done. The returned function must be
+// called to release the slot.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.parent != nil {
+		return c.parent.acquire(ctx)
+	}
+	if c.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	c.semOnce.Do(func() {
+		c.sem = make(chan struct{}, c.MaxConcurrent)
+	})
+	select {
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // XMLTyper is an abstract interface for types that can set an XML type.
@@ -79,6 +187,8 @@ func setXMLType(v reflect.Value) {
 		ok := v.Type().Implements(xmlTyperType)
 		if ok {
 			v.MethodByName("SetXMLType").Call(nil)
+		} else if name, ok := lookupType(v.Type()); ok {
+			setRegisteredType(v.Elem(), name)
 		}
 		setXMLType(v.Elem())
 	case reflect.Slice:
@@ -97,14 +207,24 @@ func setXMLType(v reflect.Value) {
 }
 
 func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) error {
-	setXMLType(reflect.ValueOf(in))
+	return doRoundTripAttachments(c, setHeaders, c.Header, in, out, nil, nil)
+}
+
+// doRoundTripAttachments sends the attachments along with the envelope
+// when there are any, and stores the attachments of a multipart
+// response in received, if not nil.
+func doRoundTripAttachments(c *Client, setHeaders func(*http.Request), header Header, in, out Message, attachments []Attachment, received *Attachments) error {
+	out, rec := unwrapResponse(out)
+	if !c.SkipXMLType {
+		setXMLType(reflect.ValueOf(in))
+	}
 	req := &Envelope{
 		EnvelopeAttr: c.Envelope,
 		URNAttr:      c.URNamespace,
 		NSAttr:       c.Namespace,
 		TNSAttr:      c.ThisNamespace,
 		XSIAttr:      XSINamespace,
-		Header:       c.Header,
+		Header:       header,
 		Body:         in,
 	}
 
@@ -114,23 +234,60 @@ func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) err
 	if req.NSAttr == "" {
 		req.NSAttr = c.URL
 	}
+	if req.TNSAttr == "" {
+		req.TNSAttr = c.Namespaces["tns"]
+	}
 	if req.TNSAttr == "" {
 		req.TNSAttr = req.NSAttr
 	}
+	req.NSAttrs = namespaceAttrs(c.Namespaces)
 	var b bytes.Buffer
 	err := xml.NewEncoder(&b).Encode(req)
 	if err != nil {
 		return err
 	}
+	if len(c.RequestTransformers) > 0 {
+		data, err := c.RequestTransformers.apply(b.Bytes())
+		if err != nil {
+			return err
+		}
+		b.Reset()
+		b.Write(data)
+	}
 	cli := c.Config
 	if cli == nil {
 		cli = http.DefaultClient
 	}
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	r, err := http.NewRequest("POST", c.URL, &b)
 	if err != nil {
 		return err
 	}
 	setHeaders(r)
+	data := b.Bytes()
+	if len(attachments) > 0 {
+		body, ct, err := writeMultipart(&b, r.Header.Get("Content-Type"), attachments)
+		if err != nil {
+			return err
+		}
+		data = body.Bytes()
+		r.Body = ioutil.NopCloser(body)
+		r.ContentLength = int64(body.Len())
+		r.Header.Set("Content-Type", ct)
+	}
+	if c.Digest != "" {
+		if err = setDigest(r, c.Digest, data); err != nil {
+			return err
+		}
+	}
 	if c.Pre != nil {
 		c.Pre(r)
 	}
@@ -138,12 +295,18 @@ func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) err
 	if c.Ctx != nil {
 		r = r.WithContext(c.Ctx)
 	}
+	if c.ConnTrace != nil {
+		r = traceConn(r, c.ConnTrace)
+	}
 
 	resp, err := cli.Do(r)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if err = c.recordResponse(resp, rec); err != nil {
+		return err
+	}
 	if c.Post != nil {
 		c.Post(resp)
 	}
@@ -163,7 +326,24 @@ func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) err
 		Body    Message
 	}{Body: out}
 
-	decoder := xml.NewDecoder(resp.Body)
+	var body io.Reader = resp.Body
+	if received != nil {
+		mt, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if strings.HasPrefix(mt, "multipart/") {
+			body, *received, err = readMultipart(resp.Body, params["boundary"], params["start"])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	body = c.ResponseTransformers.apply(body)
+	if c.ResolveRefs {
+		body, err = resolveRefs(body)
+		if err != nil {
+			return err
+		}
+	}
+	decoder := xml.NewDecoder(body)
 	decoder.CharsetReader = charset.NewReaderLabel
 	return decoder.Decode(&marshalStructure)
 }
@@ -199,27 +379,46 @@ func (c *Client) RoundTrip(in, out Message) error {
 // that need to set the SOAPAction header.
 func (c *Client) RoundTripWithAction(soapAction string, in, out Message) error {
 	headerFunc := func(r *http.Request) {
-		if c.UserAgent != "" {
-			r.Header.Add("User-Agent", c.UserAgent)
-		}
-		var actionName string
-		ct := c.ContentType
-		if ct == "" {
-			ct = "text/xml"
-		}
-		r.Header.Set("Content-Type", ct)
-		if in != nil {
-			if c.ExcludeActionNamespace {
-				actionName = soapAction
-			} else {
-				actionName = fmt.Sprintf("%s/%s", c.Namespace, soapAction)
-			}
-			r.Header.Add("SOAPAction", actionName)
-		}
+		c.setActionHeaders(r, soapAction, in)
 	}
 	return doRoundTrip(c, headerFunc, in, out)
 }
 
+// setActionHeaders sets the HTTP headers of SOAP 1.1 requests with the
+// given action.
+func (c *Client) setActionHeaders(r *http.Request, soapAction string, in Message) {
+	c.setContentHeaders(r)
+	var actionName string
+	if in != nil {
+		if c.ExcludeActionNamespace || isAbsoluteAction(soapAction) {
+			actionName = soapAction
+		} else {
+			actionName = fmt.Sprintf("%s/%s", c.Namespace, soapAction)
+		}
+		r.Header.Add("SOAPAction", actionName)
+	}
+}
+
+// isAbsoluteAction reports whether the SOAP action is an absolute URI
+// or fragment, such as urn:Company:Service#operation, which is sent as
+// is rather than prefixed with the client namespace.
+func isAbsoluteAction(action string) bool {
+	return strings.ContainsAny(action, ":#")
+}
+
+// setContentHeaders sets the User-Agent and Content-Type headers of
+// SOAP 1.1 requests.
+func (c *Client) setContentHeaders(r *http.Request) {
+	if c.UserAgent != "" {
+		r.Header.Add("User-Agent", c.UserAgent)
+	}
+	ct := c.ContentType
+	if ct == "" {
+		ct = "text/xml"
+	}
+	r.Header.Set("Content-Type", ct)
+}
+
 // RoundTripSoap12 implements the RoundTripper interface for SOAP 1.2.
 func (c *Client) RoundTripSoap12(action string, in, out Message) error {
 	headerFunc := func(r *http.Request) {
@@ -241,12 +440,40 @@ func (e *HTTPError) Error() string {
 
 // Envelope is a SOAP envelope.
 type Envelope struct {
-	XMLName      xml.Name `xml:"SOAP-ENV:Envelope"`
-	EnvelopeAttr string   `xml:"xmlns:SOAP-ENV,attr"`
-	NSAttr       string   `xml:"xmlns:ns,attr"`
-	TNSAttr      string   `xml:"xmlns:tns,attr,omitempty"`
-	URNAttr      string   `xml:"xmlns:urn,attr,omitempty"`
-	XSIAttr      string   `xml:"xmlns:xsi,attr,omitempty"`
-	Header       Message  `xml:"SOAP-ENV:Header"`
-	Body         Message  `xml:"SOAP-ENV:Body"`
+	XMLName      xml.Name   `xml:"SOAP-ENV:Envelope"`
+	EnvelopeAttr string     `xml:"xmlns:SOAP-ENV,attr"`
+	NSAttr       string     `xml:"xmlns:ns,attr"`
+	TNSAttr      string     `xml:"xmlns:tns,attr,omitempty"`
+	URNAttr      string     `xml:"xmlns:urn,attr,omitempty"`
+	XSIAttr      string     `xml:"xmlns:xsi,attr,omitempty"`
+	NSAttrs      []xml.Attr `xml:",any,attr"`
+	Header       Message    `xml:"SOAP-ENV:Header"`
+	Body         Message    `xml:"SOAP-ENV:Body"`
+}
+
+// envelopePrefixes are the prefixes declared by the fields of Envelope.
+var envelopePrefixes = map[string]bool{
+	"SOAP-ENV": true, "ns": true, "tns": true, "urn": true, "xsi": true,
+}
+
+// namespaceAttrs returns the declarations of the namespaces ns, by
+// prefix, sorted by prefix. The prefixes declared by Envelope itself
+// are skipped, and set through the fields of the Client instead, but
+// tns defaults to the one in ns.
+func namespaceAttrs(ns map[string]string) []xml.Attr {
+	prefixes := make([]string, 0, len(ns))
+	for prefix := range ns {
+		if prefix != "" && prefix != "xml" && !envelopePrefixes[prefix] {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	var attrs []xml.Attr
+	for _, prefix := range prefixes {
+		attrs = append(attrs, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + prefix},
+			Value: ns[prefix],
+		})
+	}
+	return attrs
 }
@@ -1,12 +1,18 @@
 package soap
 
 import (
+	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type StructFieldSetXMLData struct {
@@ -194,6 +200,28 @@ func TestRoundTripWithAction(t *testing.T) {
 	}
 }
 
+func TestSetActionHeaders(t *testing.T) {
+	cases := []struct {
+		Exclude bool
+		Action  string
+		Want    string
+	}{
+		{false, "hello", "urn:ns/hello"},
+		{true, "hello", "hello"},
+		{false, "urn:Company:Service#operation", "urn:Company:Service#operation"},
+		{false, "http://example.com/Service/operation", "http://example.com/Service/operation"},
+		{false, "Service#operation", "Service#operation"},
+	}
+	for _, tc := range cases {
+		c := &Client{Namespace: "urn:ns", ExcludeActionNamespace: tc.Exclude}
+		r, _ := http.NewRequest("POST", "http://localhost", nil)
+		c.setActionHeaders(r, tc.Action, struct{}{})
+		if have := r.Header.Get("SOAPAction"); have != tc.Want {
+			t.Errorf("action %q: want %q, have %q", tc.Action, tc.Want, have)
+		}
+	}
+}
+
 func TestRoundTripSoap12(t *testing.T) {
 	type msgT struct{ A, B string }
 	type envT struct{ msgT }
@@ -261,3 +289,234 @@ func TestRoundTripSoap12(t *testing.T) {
 		}
 	}
 }
+
+func TestClientMaxConcurrent(t *testing.T) {
+	c := &Client{MaxConcurrent: 1}
+	release, err := c.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = c.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	release()
+	release, err = c.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestClientMaxConcurrentRoundTrip(t *testing.T) {
+	const max, reqs = 2, 5
+	var mu sync.Mutex
+	var inFlight, peak int
+	arrived := make(chan struct{}, reqs)
+	unblock := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		arrived <- struct{}{}
+		<-unblock
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte(`<Envelope><Body><Out/></Body></Envelope>`))
+	}))
+	defer s.Close()
+
+	c := &Client{URL: s.URL, MaxConcurrent: max}
+	errs := make(chan error, reqs)
+	for i := 0; i < reqs; i++ {
+		go func() {
+			errs <- c.RoundTripWithAction("test", &struct{}{}, &struct{}{})
+		}()
+	}
+	for i := 0; i < max; i++ {
+		<-arrived
+	}
+
+	// all slots are taken, so a round trip waits for one until its
+	// deadline, without reaching the server
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	hc := c.WithHeader(nil)
+	hc.Ctx = ctx
+	start := time.Now()
+	if err := hc.RoundTripWithAction("test", &struct{}{}, &struct{}{}); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("round trip waited %v past its deadline", d)
+	}
+	select {
+	case <-arrived:
+		t.Errorf("request sent with %d round trips in flight", max)
+	default:
+	}
+
+	close(unblock)
+	for i := 0; i < reqs; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if peak != max {
+		t.Errorf("want %d requests in flight at most, have %d", max, peak)
+	}
+}
+
+func TestClientWithHeader(t *testing.T) {
+	var reqs []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		reqs = append(reqs, string(b))
+		w.Write([]byte(`<Envelope><Body><Out/></Body></Envelope>`))
+	}))
+	defer s.Close()
+
+	type authT struct {
+		XMLName xml.Name `xml:"Auth"`
+		Token   string   `xml:"token"`
+	}
+	c := &Client{URL: s.URL, UserAgent: "test", MaxConcurrent: 1}
+	hc := c.WithHeader(&authT{Token: "secret"})
+	if hc.URL != c.URL || hc.UserAgent != c.UserAgent || hc.parent != c || c.Header != nil {
+		t.Fatalf("unexpected client: %+v", hc)
+	}
+	if hc.WithHeader(nil).parent != c {
+		t.Fatal("want round trip slots of the first client")
+	}
+	for _, cli := range []*Client{hc, c} {
+		if err := cli.RoundTripWithAction("test", &struct{}{}, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := `<Auth><token>secret</token></Auth>`; !strings.Contains(reqs[0], want) || strings.Contains(reqs[1], want) {
+		t.Fatalf("want %s only in the first request of %q", want, reqs)
+	}
+	// the slot of c is taken by hc
+	release, err := c.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = hc.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	release()
+}
+
+func TestEncoded(t *testing.T) {
+	type msgT struct {
+		A string `xml:"a"`
+	}
+	body := struct {
+		Encoding
+		M msgT `xml:"tns:Test"`
+	}{
+		Encoded(),
+		msgT{A: "hello"},
+	}
+	b, err := xml.Marshal(&Envelope{Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<SOAP-ENV:Body SOAP-ENV:encodingStyle="` + EncodingNamespace + `" xmlns:SOAP-ENC="` + EncodingNamespace + `" xmlns:xsd="` + XSDNamespace + `"><tns:Test><a>hello</a></tns:Test></SOAP-ENV:Body>`
+	if !strings.Contains(string(b), want) {
+		t.Fatalf("want %s in %s", want, b)
+	}
+}
+
+func TestClientSkipXMLType(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer s.Close()
+
+	for _, skip := range []bool{false, true} {
+		c := &Client{URL: s.URL, SkipXMLType: skip}
+		in := &SetXMLData{}
+		if err := c.RoundTripWithAction("test", in, &struct{ SetXMLData }{}); err != nil {
+			t.Fatal(err)
+		}
+		if set := in.TypeAttrXSI != ""; set == skip {
+			t.Errorf("SkipXMLType %v: unexpected TypeAttrXSI %q", skip, in.TypeAttrXSI)
+		}
+	}
+}
+
+func TestClientNamespaces(t *testing.T) {
+	var req []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`<Envelope><Body><Out/></Body></Envelope>`))
+	}))
+	defer s.Close()
+
+	type msgT struct {
+		XMLName xml.Name `xml:"ord:Order"`
+		ID      string   `xml:"id"`
+	}
+	c := &Client{
+		URL:       s.URL,
+		Namespace: "urn:svc",
+		Namespaces: map[string]string{
+			"ord": "urn:orders",
+			"cst": "urn:customers",
+			"tns": "urn:this",
+			"xsi": "urn:ignored",
+			"":    "urn:default",
+		},
+	}
+	if err := c.RoundTripWithAction("test", &msgT{ID: "1"}, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `xmlns:tns="urn:this" xmlns:xsi="` + XSINamespace + `" xmlns:cst="urn:customers" xmlns:ord="urn:orders">`
+	if !strings.Contains(string(req), want) {
+		t.Fatalf("want %s in %s", want, req)
+	}
+	if want := `<ord:Order><id>1</id></ord:Order>`; !strings.Contains(string(req), want) {
+		t.Fatalf("want %s in %s", want, req)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var ops []string
+	var errs []error
+	c := &Client{
+		Observe: func(op string, d time.Duration, err error) {
+			if d < 0 {
+				t.Errorf("%s: negative duration %v", op, d)
+			}
+			ops = append(ops, op)
+			errs = append(errs, err)
+		},
+	}
+	fail := fmt.Errorf("fail")
+	if err := Observe(c, "svc.port.ok", time.Now(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Observe(c, "svc.port.fail", time.Now(), fail); err != fail {
+		t.Fatalf("want %v, have %v", fail, err)
+	}
+	if want := []string{"svc.port.ok", "svc.port.fail"}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("want ops %q, have %q", want, ops)
+	}
+	if errs[0] != nil || errs[1] != fail {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	// no hook, or no client
+	for _, c := range []*Client{{}, nil} {
+		if err := Observe(c, "svc.port.op", time.Now(), fail); err != fail {
+			t.Fatalf("want %v, have %v", fail, err)
+		}
+	}
+}
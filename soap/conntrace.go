@@ -0,0 +1,96 @@
+package soap
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ConnInfo describes the connection used by a round trip, for
+// diagnosing keep-alive behavior of servers.
+type ConnInfo struct {
+	Reused       bool          // Whether the connection was used before
+	WasIdle      bool          // Whether the connection was idle before
+	IdleTime     time.Duration // How long the connection was idle, if it was
+	Connect      time.Duration // How long it took to connect, if not reused
+	TLSHandshake time.Duration // How long the TLS handshake took, if not reused
+}
+
+// traceConn returns r with a context that calls hook with the
+// connection it gets, which is after connecting and the TLS handshake
+// of new connections.
+func traceConn(r *http.Request, hook func(ConnInfo)) *http.Request {
+	var info ConnInfo
+	var connectStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if !connectStart.IsZero() {
+				info.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			if !tlsStart.IsZero() {
+				info.TLSHandshake = time.Since(tlsStart)
+			}
+		},
+		GotConn: func(ci httptrace.GotConnInfo) {
+			info.Reused = ci.Reused
+			info.WasIdle = ci.WasIdle
+			info.IdleTime = ci.IdleTime
+			hook(info)
+		},
+	}
+	return r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+}
+
+// ConnStats counts the connections of round trips. Its Record method
+// can be set as the ConnTrace hook of Clients:
+//
+//	var stats soap.ConnStats
+//	cli := &soap.Client{URL: "https://server", ConnTrace: stats.Record}
+//	...
+//	log.Printf("%+v", stats.Get())
+type ConnStats struct {
+	mu sync.Mutex
+	s  ConnCounters
+}
+
+// ConnCounters are the counters of ConnStats.
+type ConnCounters struct {
+	New           int64         // Round trips on new connections
+	Reused        int64         // Round trips on reused connections
+	TLSHandshakes int64         // TLS handshakes of new connections
+	TLSHandshake  time.Duration // Total time of the TLS handshakes
+	Connect       time.Duration // Total time of connecting
+}
+
+// Record adds the connection of a round trip to the counters.
+func (s *ConnStats) Record(info ConnInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info.Reused {
+		s.s.Reused++
+	} else {
+		s.s.New++
+	}
+	if info.TLSHandshake > 0 {
+		s.s.TLSHandshakes++
+		s.s.TLSHandshake += info.TLSHandshake
+	}
+	s.s.Connect += info.Connect
+}
+
+// Get returns the current counters.
+func (s *ConnStats) Get() ConnCounters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s
+}
@@ -0,0 +1,46 @@
+package soap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientConnTrace(t *testing.T) {
+	type msgT struct{ A string }
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<Envelope><Body><A>hello</A></Body></Envelope>\n"))
+	}))
+	defer s.Close()
+
+	var stats ConnStats
+	var infos []ConnInfo
+	c := &Client{
+		URL:    s.URL,
+		Config: s.Client(),
+		ConnTrace: func(info ConnInfo) {
+			infos = append(infos, info)
+			stats.Record(info)
+		},
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.RoundTrip(&msgT{A: "world"}, &msgT{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(infos) != 3 {
+		t.Fatalf("want 3 connections traced, have %d", len(infos))
+	}
+	if infos[0].Reused || infos[0].TLSHandshake <= 0 {
+		t.Errorf("want new connection with TLS handshake, have %+v", infos[0])
+	}
+	for _, info := range infos[1:] {
+		if !info.Reused || info.TLSHandshake != 0 {
+			t.Errorf("want reused connection, have %+v", info)
+		}
+	}
+	have := stats.Get()
+	if have.New != 1 || have.Reused != 2 || have.TLSHandshakes != 1 || have.TLSHandshake != infos[0].TLSHandshake {
+		t.Errorf("unexpected counters: %+v", have)
+	}
+}
@@ -0,0 +1,37 @@
+package soap
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// setDigest sets the header with the digest of the request body data,
+// using the given algorithm. MD5 digests are set in the Content-MD5
+// header, and others in the Digest header as in RFC 3230.
+func setDigest(r *http.Request, algorithm string, data []byte) error {
+	var h hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "MD5":
+		h = md5.New()
+	case "SHA-256":
+		h = sha256.New()
+	case "SHA-512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("soap: unsupported digest algorithm %q", algorithm)
+	}
+	h.Write(data)
+	sum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if h.Size() == md5.Size {
+		r.Header.Set("Content-MD5", sum)
+		return nil
+	}
+	r.Header.Set("Digest", strings.ToUpper(algorithm)+"="+sum)
+	return nil
+}
@@ -0,0 +1,58 @@
+package soap
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDigest(t *testing.T) {
+	type msgT struct{ A, B string }
+	var headers http.Header
+	var body []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("<Envelope><Body></Body></Envelope>"))
+	}))
+	defer s.Close()
+
+	b64 := base64.StdEncoding.EncodeToString
+	cases := []struct {
+		Algorithm string
+		Header    string
+		Sum       func([]byte) string
+	}{
+		{"MD5", "Content-MD5", func(b []byte) string {
+			s := md5.Sum(b)
+			return b64(s[:])
+		}},
+		{"SHA-256", "Digest", func(b []byte) string {
+			s := sha256.Sum256(b)
+			return "SHA-256=" + b64(s[:])
+		}},
+		{"sha-512", "Digest", func(b []byte) string {
+			s := sha512.Sum512(b)
+			return "SHA-512=" + b64(s[:])
+		}},
+	}
+	for _, tc := range cases {
+		c := &Client{URL: s.URL, Digest: tc.Algorithm}
+		if err := c.RoundTripWithAction("Foo", &msgT{A: "a", B: "b"}, &msgT{}); err != nil {
+			t.Fatalf("%s: %v", tc.Algorithm, err)
+		}
+		if want, have := tc.Sum(body), headers.Get(tc.Header); have != want {
+			t.Errorf("%s: unexpected %s header: want %q, have %q", tc.Algorithm, tc.Header, want, have)
+		}
+	}
+
+	c := &Client{URL: s.URL, Digest: "CRC32"}
+	if err := c.RoundTripWithAction("Foo", &msgT{}, &msgT{}); err == nil {
+		t.Fatal("unexpected success with unsupported algorithm")
+	}
+}
@@ -0,0 +1,118 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// CallHTTP calls an operation of a WSDL HTTP binding, which is plain
+// HTTP rather than SOAP, with the method verb, GET or POST, at location
+// relative to the URL of the client.
+//
+// The parameters replace their names in parentheses in location, such
+// as /quote/(symbol), if replace is set (http:urlReplacement). The rest
+// are URL encoded, in the query of GET requests or in the body of POST
+// requests (http:urlEncoded). The XML response is decoded into out,
+// unless nil.
+func (c *Client) CallHTTP(verb, location string, params url.Values, replace bool, out Message) error {
+	out, rec := unwrapResponse(out)
+	if replace {
+		params = replaceParams(&location, params)
+	}
+	u := c.URL
+	if location != "" && !strings.HasPrefix(location, "/") {
+		u += "/"
+	}
+	u += location
+	var body io.Reader
+	query := params.Encode()
+	if verb == http.MethodPost {
+		body = strings.NewReader(query)
+	} else if query != "" {
+		if strings.Contains(u, "?") {
+			u += "&" + query
+		} else {
+			u += "?" + query
+		}
+	}
+	cli := c.Config
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	r, err := http.NewRequest(verb, u, body)
+	if err != nil {
+		return err
+	}
+	if verb == http.MethodPost {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.UserAgent != "" {
+		r.Header.Add("User-Agent", c.UserAgent)
+	}
+	if c.Pre != nil {
+		c.Pre(r)
+	}
+	if c.Ctx != nil {
+		r = r.WithContext(c.Ctx)
+	}
+	if c.ConnTrace != nil {
+		r = traceConn(r, c.ConnTrace)
+	}
+	resp, err := cli.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err = c.recordResponse(resp, rec); err != nil {
+		return err
+	}
+	if c.Post != nil {
+		c.Post(resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// read only the first MiB of the body in error case
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Msg:        string(body),
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	decoder := xml.NewDecoder(c.ResponseTransformers.apply(resp.Body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder.Decode(out)
+}
+
+// replaceParams replaces the names of params in parentheses in location
+// with their escaped values, and returns the params left.
+func replaceParams(location *string, params url.Values) url.Values {
+	left := make(url.Values)
+	for name, values := range params {
+		pattern := "(" + name + ")"
+		if len(values) == 0 || !strings.Contains(*location, pattern) {
+			left[name] = values
+			continue
+		}
+		*location = strings.Replace(*location, pattern, url.PathEscape(values[0]), -1)
+	}
+	return left
+}
@@ -0,0 +1,47 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClientCallHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		result := struct {
+			XMLName xml.Name `xml:"urn:test result"`
+			Value   string   `xml:",chardata"`
+		}{Value: fmt.Sprint(r.Method, " ", r.URL.Path, " ", r.Form.Encode())}
+		if err := xml.NewEncoder(w).Encode(&result); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer s.Close()
+
+	cases := []struct {
+		Verb, Location string
+		Replace        bool
+		Want           string
+	}{
+		{"GET", "/GetQuote", false, "GET /svc/GetQuote exchange=NYSE&symbol=A+B"},
+		{"POST", "/GetQuote", false, "POST /svc/GetQuote exchange=NYSE&symbol=A+B"},
+		{"GET", "quote/(symbol)", true, "GET /svc/quote/A B exchange=NYSE"},
+	}
+	for _, tc := range cases {
+		c := &Client{URL: s.URL + "/svc"}
+		params := url.Values{"symbol": {"A B"}, "exchange": {"NYSE"}}
+		var have string
+		if err := c.CallHTTP(tc.Verb, tc.Location, params, tc.Replace, &have); err != nil {
+			t.Fatal(err)
+		}
+		if have != tc.Want {
+			t.Errorf("%s %s: want %q, have %q", tc.Verb, tc.Location, tc.Want, have)
+		}
+	}
+}
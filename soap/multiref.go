@@ -0,0 +1,134 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// refNode is an element of a SOAP encoded document, kept with its raw
+// (prefixed) names so it can be written back as is.
+type refNode struct {
+	start    xml.StartElement
+	children []interface{} // *refNode or xml.CharData
+}
+
+// maxRefsSize is the size of the documents written by resolveRefs, past
+// which it fails. Elements referred to several times are written each
+// time, so without it a small document whose multiRefs refer to the
+// next ones twice expands exponentially.
+var maxRefsSize = 64 << 20
+
+// resolveRefs rewrites the SOAP encoded document in r, replacing the
+// elements that have an href="#id" attribute with the attributes and
+// contents of the element identified by id, usually a multiRef.
+func resolveRefs(r io.Reader) (io.Reader, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	root := &refNode{}
+	stack := []*refNode{root}
+	ids := make(map[string]*refNode)
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &refNode{start: t.Copy()}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+			if id, ok := refAttr(t, "id"); ok {
+				ids[id] = n
+			}
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, t.Copy())
+		}
+	}
+	var b bytes.Buffer
+	for _, c := range root.children {
+		if n, ok := c.(*refNode); ok {
+			if err := n.write(&b, ids, make(map[*refNode]bool)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &b, nil
+}
+
+func refAttr(start xml.StartElement, name string) (string, bool) {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// write writes n to b, resolving references to the elements in ids.
+// Elements already being resolved in seen are not resolved again, as
+// cyclic references can't be represented in a tree. It fails if b grows
+// past maxRefsSize.
+func (n *refNode) write(b *bytes.Buffer, ids map[string]*refNode, seen map[*refNode]bool) error {
+	if b.Len() > maxRefsSize {
+		return fmt.Errorf("soap: response with multiRefs resolved is larger than %d bytes", maxRefsSize)
+	}
+	attrs, children := n.start.Attr, n.children
+	if href, ok := refAttr(n.start, "href"); ok && strings.HasPrefix(href, "#") {
+		if target, ok := ids[href[1:]]; ok && !seen[target] {
+			seen[target] = true
+			defer delete(seen, target)
+			attrs = nil
+			for _, attr := range n.start.Attr {
+				if attr.Name.Space != "" || attr.Name.Local != "href" {
+					attrs = append(attrs, attr)
+				}
+			}
+			for _, attr := range target.start.Attr {
+				if attr.Name.Space != "" || attr.Name.Local != "id" {
+					attrs = append(attrs, attr)
+				}
+			}
+			children = target.children
+		}
+	}
+	name := rawName(n.start.Name)
+	b.WriteString("<" + name)
+	for _, attr := range attrs {
+		b.WriteString(" " + rawName(attr.Name) + `="`)
+		xml.EscapeText(b, []byte(attr.Value))
+		b.WriteString(`"`)
+	}
+	b.WriteString(">")
+	for _, c := range children {
+		switch c := c.(type) {
+		case *refNode:
+			if err := c.write(b, ids, seen); err != nil {
+				return err
+			}
+		case xml.CharData:
+			xml.EscapeText(b, c)
+		}
+	}
+	b.WriteString("</" + name + ">")
+	return nil
+}
+
+func rawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,92 @@
+package soap
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const multiRefResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+ <soapenv:Body>
+  <ns1:getUserResponse xmlns:ns1="urn:test">
+   <getUserReturn href="#id0"/>
+  </ns1:getUserResponse>
+  <multiRef id="id0" xsi:type="ns2:User" xmlns:ns2="urn:test">
+   <name>alice</name>
+   <groups href="#id1"/>
+  </multiRef>
+  <multiRef id="id1" xsi:type="ns3:Groups" xmlns:ns3="urn:test">
+   <item>admin</item>
+   <item>dev</item>
+  </multiRef>
+ </soapenv:Body>
+</soapenv:Envelope>`
+
+func TestResolveRefs(t *testing.T) {
+	type groupsT struct {
+		Items []string `xml:"item"`
+	}
+	type userT struct {
+		Type   string   `xml:"type,attr"`
+		Name   string   `xml:"name"`
+		Groups *groupsT `xml:"groups"`
+	}
+	type respT struct {
+		Return *userT `xml:"getUserResponse>getUserReturn"`
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, multiRefResponse)
+	}))
+	defer s.Close()
+
+	var resp respT
+	c := &Client{URL: s.URL}
+	if err := c.RoundTripWithAction("getUser", struct{}{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Return == nil || resp.Return.Name != "" {
+		t.Fatalf("unexpected response without resolving refs: %#v", resp.Return)
+	}
+
+	resp = respT{}
+	c = &Client{URL: s.URL, ResolveRefs: true}
+	if err := c.RoundTripWithAction("getUser", struct{}{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := &userT{
+		Type:   "ns2:User",
+		Name:   "alice",
+		Groups: &groupsT{Items: []string{"admin", "dev"}},
+	}
+	if !reflect.DeepEqual(resp.Return, want) {
+		t.Fatalf("want %#v, have %#v", want, resp.Return)
+	}
+}
+
+func TestResolveRefsExpansion(t *testing.T) {
+	// each multiRef refers to the next one twice, so the document
+	// expands to 2^40 leaves
+	var b strings.Builder
+	b.WriteString(`<Envelope><Body><getUserResponse><r href="#id0"/></getUserResponse>`)
+	const depth = 40
+	for i := 0; i < depth; i++ {
+		fmt.Fprintf(&b, `<multiRef id="id%d"><a href="#id%d"/><b href="#id%d"/></multiRef>`, i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<multiRef id="id%d">leaf</multiRef></Body></Envelope>`, depth)
+	defer func(size int) { maxRefsSize = size }(maxRefsSize)
+	maxRefsSize = 1 << 20
+	if _, err := resolveRefs(strings.NewReader(b.String())); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("want error of expansion, have %v", err)
+	}
+
+	// within the limit
+	maxRefsSize = len(multiRefResponse) * 2
+	if _, err := resolveRefs(strings.NewReader(multiRefResponse)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"sync"
+)
+
+var typeRegistry = struct {
+	sync.RWMutex
+	m map[reflect.Type]xml.Name
+}{m: make(map[reflect.Type]xml.Name)}
+
+// RegisterType records the XML schema type name of the Go type of v,
+// which must be a pointer to a struct.
+//
+// When a registered struct is sent in a request, usually assigned to
+// an abstract (interface) field, its xsi:type attribute is set from the
+// registry. The struct conveys the attribute in the TypeAttrXSI and
+// TypeNamespace string fields, which can be overridden by setting the
+// OverrideTypeAttrXSI and OverrideTypeNamespace *string fields. Code
+// generated by wsdl2go registers all extension types this way.
+func RegisterType(v interface{}, name xml.Name) {
+	typeRegistry.Lock()
+	typeRegistry.m[reflect.TypeOf(v)] = name
+	typeRegistry.Unlock()
+}
+
+// TypeName returns the XML schema type name registered for the Go type
+// of v, if any.
+func TypeName(v interface{}) (xml.Name, bool) {
+	return lookupType(reflect.TypeOf(v))
+}
+
+// SetRegisteredType sets the xsi:type fields of v, a pointer to a
+// struct registered with RegisterType, to its registered type name, as
+// when it's sent. Nothing is set if the type of v isn't registered.
+func SetRegisteredType(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if name, ok := lookupType(rv.Type()); ok && rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		setRegisteredType(rv.Elem(), name)
+	}
+}
+
+func lookupType(t reflect.Type) (xml.Name, bool) {
+	typeRegistry.RLock()
+	name, ok := typeRegistry.m[t]
+	typeRegistry.RUnlock()
+	return name, ok
+}
+
+// setRegisteredType sets the xsi:type fields of the struct v to name.
+func setRegisteredType(v reflect.Value, name xml.Name) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	typ, ns := "objtype:"+name.Local, name.Space
+	if o := v.FieldByName("OverrideTypeAttrXSI"); o.Kind() == reflect.Ptr && !o.IsNil() {
+		typ = o.Elem().String()
+	}
+	if o := v.FieldByName("OverrideTypeNamespace"); o.Kind() == reflect.Ptr && !o.IsNil() {
+		ns = o.Elem().String()
+	}
+	if f := v.FieldByName("TypeAttrXSI"); f.Kind() == reflect.String && f.CanSet() {
+		f.SetString(typ)
+	}
+	if f := v.FieldByName("TypeNamespace"); f.Kind() == reflect.String && f.CanSet() {
+		f.SetString(ns)
+	}
+}
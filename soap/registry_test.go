@@ -0,0 +1,58 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+type registeredT struct {
+	A                     string
+	TypeAttrXSI           string  `xml:"xsi:type,attr,omitempty"`
+	TypeNamespace         string  `xml:"xmlns:objtype,attr,omitempty"`
+	OverrideTypeAttrXSI   *string `xml:"-"`
+	OverrideTypeNamespace *string `xml:"-"`
+}
+
+func TestRegisterType(t *testing.T) {
+	name := xml.Name{Space: "urn:test", Local: "Registered"}
+	RegisterType((*registeredT)(nil), name)
+	if have, ok := TypeName(&registeredT{}); !ok || have != name {
+		t.Fatalf("want %v, have %v", name, have)
+	}
+
+	type abstractT interface{}
+	override := "objtype:Other"
+	test := struct {
+		A, B abstractT
+	}{
+		A: &registeredT{},
+		B: &registeredT{OverrideTypeAttrXSI: &override},
+	}
+	setXMLType(reflect.ValueOf(&test))
+	a := test.A.(*registeredT)
+	if a.TypeAttrXSI != "objtype:Registered" || a.TypeNamespace != "urn:test" {
+		t.Fatalf("unexpected xsi:type for A: %q %q", a.TypeAttrXSI, a.TypeNamespace)
+	}
+	b := test.B.(*registeredT)
+	if b.TypeAttrXSI != override || b.TypeNamespace != "urn:test" {
+		t.Fatalf("unexpected xsi:type for B: %q %q", b.TypeAttrXSI, b.TypeNamespace)
+	}
+}
+
+func TestSetRegisteredType(t *testing.T) {
+	name := xml.Name{Space: "urn:test", Local: "Registered"}
+	RegisterType((*registeredT)(nil), name)
+	v := &registeredT{}
+	SetRegisteredType(v)
+	if v.TypeAttrXSI != "objtype:Registered" || v.TypeNamespace != "urn:test" {
+		t.Fatalf("unexpected xsi:type: %q %q", v.TypeAttrXSI, v.TypeNamespace)
+	}
+	SetRegisteredType((*registeredT)(nil))
+	type unregisteredT registeredT
+	u := &unregisteredT{}
+	SetRegisteredType(u)
+	if u.TypeAttrXSI != "" || u.TypeNamespace != "" {
+		t.Fatalf("unexpected xsi:type of unregistered type: %q %q", u.TypeAttrXSI, u.TypeNamespace)
+	}
+}
@@ -0,0 +1,64 @@
+package soap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+)
+
+// Response is the raw response of a round trip, kept for auditing or
+// debugging: its HTTP response, whose body is already read, and the
+// body as received, before transformers or decoding.
+type Response struct {
+	HTTP     *http.Response
+	Envelope []byte
+}
+
+// responseCapture is an out message that also stores the raw response
+// of the round trip, see WithResponse.
+type responseCapture struct {
+	out  Message
+	resp *Response
+}
+
+// WithResponse wraps the out message of a round trip so its raw response
+// is stored in resp, even when the round trip fails after receiving it,
+// e.g. with an HTTPError or a SOAP fault. Unlike the OnResponse hook of
+// the Client, it's specific to one call:
+//
+//	var resp soap.Response
+//	err := cli.RoundTripWithAction("Op", in, soap.WithResponse(&out, &resp))
+func WithResponse(out Message, resp *Response) Message {
+	return &responseCapture{out: out, resp: resp}
+}
+
+// unwrapResponse returns the out message wrapped by WithResponse, and
+// where to store the raw response, if anywhere.
+func unwrapResponse(out Message) (Message, *Response) {
+	if rc, ok := out.(*responseCapture); ok {
+		return rc.out, rc.resp
+	}
+	return out, nil
+}
+
+// recordResponse reads the body of resp, when it's wanted by the
+// OnResponse hook of c or stored in rec, and replaces it with the data
+// read so it can still be decoded.
+func (c *Client) recordResponse(resp *http.Response, rec *Response) error {
+	if rec == nil && c.OnResponse == nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	r := &Response{HTTP: resp, Envelope: data}
+	if rec != nil {
+		*rec = *r
+	}
+	if c.OnResponse != nil {
+		c.OnResponse(r)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package soap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientWithResponse(t *testing.T) {
+	const envelope = `<Envelope><Body><Msg><A>hello</A></Msg></Body></Envelope>`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Trace", "42")
+		if strings.Contains(r.Header.Get("SOAPAction"), "Fail") {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(envelope))
+	}))
+	defer s.Close()
+
+	var hooked []*Response
+	c := &Client{URL: s.URL, OnResponse: func(r *Response) { hooked = append(hooked, r) }}
+	var out struct {
+		A string `xml:"Msg>A"`
+	}
+	var resp Response
+	if err := c.RoundTripWithAction("Op", struct{}{}, WithResponse(&out, &resp)); err != nil {
+		t.Fatal(err)
+	}
+	if out.A != "hello" {
+		t.Errorf("unexpected output: %+v", out)
+	}
+	if string(resp.Envelope) != envelope {
+		t.Errorf("want envelope %q, have %q", envelope, resp.Envelope)
+	}
+	if resp.HTTP == nil || resp.HTTP.Header.Get("X-Trace") != "42" {
+		t.Errorf("unexpected HTTP response: %+v", resp.HTTP)
+	}
+
+	err := c.RoundTripWithAction("Fail", struct{}{}, WithResponse(&out, &resp))
+	if _, ok := err.(*HTTPError); !ok {
+		t.Fatalf("want HTTPError, have %v", err)
+	}
+	if resp.HTTP.StatusCode != http.StatusInternalServerError || string(resp.Envelope) != "failed\n" {
+		t.Errorf("unexpected response of failure: %d %q", resp.HTTP.StatusCode, resp.Envelope)
+	}
+	if len(hooked) != 2 || string(hooked[0].Envelope) != envelope {
+		t.Errorf("unexpected responses of hook: %+v", hooked)
+	}
+}
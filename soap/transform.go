@@ -0,0 +1,104 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"regexp"
+)
+
+// ResponseTransformer transforms the body of a response before it is
+// decoded, e.g. to fix invalid XML sent by the server. See the
+// ResponseTransformers field of Client.
+type ResponseTransformer func(io.Reader) io.Reader
+
+// ResponseChain is a chain of ResponseTransformers, applied in order.
+type ResponseChain []ResponseTransformer
+
+func (c ResponseChain) apply(r io.Reader) io.Reader {
+	for _, t := range c {
+		r = t(r)
+	}
+	return r
+}
+
+// bufferedTransform returns a reader of the data of r transformed by
+// f, which is only read from r on the first call to Read.
+func bufferedTransform(r io.Reader, f func([]byte) []byte) io.Reader {
+	return &transformReader{r: r, f: f}
+}
+
+type transformReader struct {
+	r    io.Reader
+	f    func([]byte) []byte
+	data *bytes.Reader
+}
+
+func (t *transformReader) Read(p []byte) (int, error) {
+	if t.data == nil {
+		data, err := ioutil.ReadAll(t.r)
+		if err != nil {
+			return 0, err
+		}
+		t.data = bytes.NewReader(t.f(data))
+	}
+	return t.data.Read(p)
+}
+
+// RequestTransformer transforms an encoded request envelope before it
+// is sent, e.g. to fix namespace prefixes for picky servers. See the
+// RequestTransformers field of Client.
+type RequestTransformer func(envelope []byte) ([]byte, error)
+
+// RequestChain is a chain of RequestTransformers, applied in order.
+type RequestChain []RequestTransformer
+
+func (c RequestChain) apply(envelope []byte) ([]byte, error) {
+	var err error
+	for _, t := range c {
+		if envelope, err = t(envelope); err != nil {
+			return nil, err
+		}
+	}
+	return envelope, nil
+}
+
+// XMLHeader is a RequestTransformer that adds the XML declaration,
+// xml.Header, to envelopes without one.
+func XMLHeader(envelope []byte) ([]byte, error) {
+	if bytes.HasPrefix(envelope, []byte("<?xml")) {
+		return envelope, nil
+	}
+	return append([]byte(xml.Header), envelope...), nil
+}
+
+var ampersand = regexp.MustCompile(`&(?:[a-zA-Z_][a-zA-Z0-9._-]*;|#[0-9]+;|#x[0-9a-fA-F]+;)?`)
+
+// EscapeAmpersands is a ResponseTransformer that escapes the
+// ampersands of r that don't start an entity or character reference,
+// as sent by servers that don't escape text.
+func EscapeAmpersands(r io.Reader) io.Reader {
+	return bufferedTransform(r, func(data []byte) []byte {
+		return ampersand.ReplaceAllFunc(data, func(ref []byte) []byte {
+			if len(ref) == 1 {
+				return []byte("&amp;")
+			}
+			return ref
+		})
+	})
+}
+
+// StripControlChars is a ResponseTransformer that removes the ASCII
+// control characters of r other than tab, newline and carriage return,
+// which aren't allowed in XML documents.
+func StripControlChars(r io.Reader) io.Reader {
+	return bufferedTransform(r, func(data []byte) []byte {
+		return bytes.Map(func(c rune) rune {
+			if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+				return -1
+			}
+			return c
+		}, data)
+	})
+}
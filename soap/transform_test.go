@@ -0,0 +1,82 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseTransformers(t *testing.T) {
+	cases := []struct {
+		T        ResponseTransformer
+		In, Want string
+	}{
+		{EscapeAmpersands, "<a>Tom & Jerry</a>", "<a>Tom &amp; Jerry</a>"},
+		{EscapeAmpersands, "<a>&amp;&lt;&#38;&#x26;&</a>", "<a>&amp;&lt;&#38;&#x26;&amp;</a>"},
+		{EscapeAmpersands, "<a>AT&T;</a>", "<a>AT&T;</a>"},
+		{StripControlChars, "<a>x\x00y\x1b\tz\r\n</a>", "<a>xy\tz\r\n</a>"},
+	}
+	for i, tc := range cases {
+		have, err := ioutil.ReadAll(tc.T(strings.NewReader(tc.In)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(have) != tc.Want {
+			t.Errorf("test %d: want %q, have %q", i, tc.Want, have)
+		}
+	}
+}
+
+func TestClientResponseTransformers(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<Envelope><Body><A>Tom & Jerry\x01</A></Body></Envelope>")
+	}))
+	defer s.Close()
+
+	type msgT struct{ A string }
+	var out msgT
+	c := &Client{URL: s.URL}
+	if err := c.RoundTripWithAction("test", &msgT{}, &out); err == nil {
+		t.Fatal("unexpected success decoding invalid XML")
+	}
+	c.ResponseTransformers = ResponseChain{StripControlChars, EscapeAmpersands}
+	if err := c.RoundTripWithAction("test", &msgT{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.A != "Tom & Jerry" {
+		t.Errorf("unexpected response %q", out.A)
+	}
+}
+
+func TestClientRequestTransformers(t *testing.T) {
+	var body []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		io.WriteString(w, "<Envelope><Body><A>ok</A></Body></Envelope>")
+	}))
+	defer s.Close()
+
+	type msgT struct{ A string }
+	prefix := func(envelope []byte) ([]byte, error) {
+		return bytes.Replace(envelope, []byte("SOAP-ENV:"), []byte("soapenv:"), -1), nil
+	}
+	c := &Client{URL: s.URL, RequestTransformers: RequestChain{XMLHeader, prefix, XMLHeader}}
+	if err := c.RoundTripWithAction("test", &msgT{A: "hello"}, &msgT{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(body, []byte(xml.Header+"<soapenv:Envelope")) || bytes.Count(body, []byte("<?xml")) != 1 {
+		t.Errorf("unexpected request:\n%s", body)
+	}
+
+	fail := errors.New("fail")
+	c.RequestTransformers = RequestChain{func([]byte) ([]byte, error) { return nil, fail }}
+	if err := c.RoundTripWithAction("test", &msgT{}, &msgT{}); err != fail {
+		t.Errorf("want %v, have %v", fail, err)
+	}
+}
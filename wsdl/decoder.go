@@ -1,11 +1,16 @@
-// Package wsdl provides Web Services Description Language (WSDL) decoder.
+// Package wsdl provides Web Services Description Language (WSDL) decoder
+// and encoder.
 //
 // http://www.w3schools.com/xml/xml_wsdl.asp
 package wsdl
 
 import (
+	"bytes"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"regexp"
+	"strings"
 
 	"golang.org/x/net/html/charset"
 )
@@ -14,13 +19,195 @@ import (
 //
 // The Definitions object it returns is an unmarshalled version of the
 // WSDL XML that can be introspected to generate the Web Services API.
+//
+// WSDL documents in SOAP envelopes, such as responses of WS-Metadata
+// Exchange GetMetadata calls, are unmarshaled from the first
+// <definitions> tag in the envelope.
 func Unmarshal(r io.Reader) (*Definitions, error) {
+	return UnmarshalFrom(r, "")
+}
+
+// UnmarshalFrom is like Unmarshal, with loc, the location of the
+// document such as its URL or file name, as the source of the
+// positions of its elements.
+func UnmarshalFrom(r io.Reader, loc string) (*Definitions, error) {
 	var d Definitions
-	decoder := xml.NewDecoder(r)
-	decoder.CharsetReader = charset.NewReaderLabel
-	err := decoder.Decode(&d)
+	err := decode(r, &d, "definitions", loc)
 	if err != nil {
 		return nil, err
 	}
 	return &d, nil
 }
+
+// UnmarshalSchema unmarshals XML Schema documents, such as .xsd files
+// that aren't embedded in WSDL, starting from the <schema> tag.
+func UnmarshalSchema(r io.Reader) (*Schema, error) {
+	return UnmarshalSchemaFrom(r, "")
+}
+
+// UnmarshalSchemaFrom is like UnmarshalSchema, with loc, the location
+// of the document such as its URL or file name, as the source of the
+// positions of its elements.
+func UnmarshalSchemaFrom(r io.Reader, loc string) (*Schema, error) {
+	var s Schema
+	err := decode(r, &s, "schema", loc)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Decode decodes the XML document in r, such as WSDL or XML Schema,
+// into v.
+//
+// Internal entities declared in the document type declaration are
+// expanded, while external entities are rejected with an error rather
+// than resolved.
+func Decode(r io.Reader, v interface{}) error {
+	return DecodeFrom(r, v, "")
+}
+
+// DecodeFrom is like Decode, with loc, the location of the document
+// such as its URL or file name, as the source of the positions of its
+// elements.
+func DecodeFrom(r io.Reader, v interface{}, loc string) error {
+	return decode(r, v, "", loc)
+}
+
+// decode decodes the XML document in r, at loc, into v. If the document
+// is a SOAP envelope and name isn't empty, the first element with that
+// name in the envelope is decoded instead.
+func decode(r io.Reader, v interface{}, name, loc string) error {
+	src := &source{r: r, loc: loc}
+	decoder := xml.NewDecoder(src)
+	sources.Store(decoder, src)
+	defer sources.Delete(decoder)
+	decoder.CharsetReader = charset.NewReaderLabel
+	inEnvelope := false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			entities, err := parseEntities(t)
+			if err != nil {
+				return err
+			}
+			decoder.Entity = entities
+			if len(entities) > 0 {
+				src.entities = entities
+				src.scanned = int(decoder.InputOffset())
+				if err = src.countEntities(); err != nil {
+					return err
+				}
+			}
+		case xml.StartElement:
+			switch {
+			case inEnvelope && t.Name.Local != name:
+				continue
+			case !inEnvelope && name != "" && t.Name.Local == "Envelope":
+				inEnvelope = true
+				continue
+			}
+			return decoder.DecodeElement(v, &t)
+		}
+	}
+}
+
+// maxEntitySize caps the size of expanded entities, to protect against
+// exponential expansion of nested entities (billion laughs).
+const maxEntitySize = 1 << 16
+
+// maxEntitiesSize caps the size of all the references to entities of a
+// document once expanded, as a document that refers to a large entity
+// many times also expands without bound.
+var maxEntitiesSize = 1 << 24
+
+// countEntities adds the size of the references to the entities of the
+// document read since the last call to s.expanded, failing past
+// maxEntitiesSize. A reference that may be cut at the end of the data
+// read is counted in the next call.
+func (s *source) countEntities() error {
+	end := len(s.data)
+	if i := bytes.LastIndexByte(s.data[s.scanned:], '&'); i >= 0 {
+		i += s.scanned
+		if bytes.IndexByte(s.data[i:], ';') < 0 && end-i < 256 {
+			end = i
+		}
+	}
+	for _, m := range entityRef.FindAllSubmatch(s.data[s.scanned:end], -1) {
+		s.expanded += len(s.entities[string(m[1])])
+	}
+	s.scanned = end
+	if s.expanded > maxEntitiesSize {
+		return fmt.Errorf("wsdl: references to entities expand to more than %d bytes", maxEntitiesSize)
+	}
+	return nil
+}
+
+var (
+	entityDecl = regexp.MustCompile(`<!ENTITY\s+(%\s+)?([^\s"']+)\s+(?:"([^"]*)"|'([^']*)'|(SYSTEM|PUBLIC))`)
+	entityRef  = regexp.MustCompile(`&([^;&\s]+);`)
+)
+
+// parseEntities returns the general entities declared in the internal
+// subset of the DOCTYPE directive d, with nested references expanded.
+func parseEntities(d xml.Directive) (map[string]string, error) {
+	if !strings.HasPrefix(string(d), "DOCTYPE") {
+		return nil, nil
+	}
+	decls := make(map[string]string)
+	for _, m := range entityDecl.FindAllStringSubmatch(string(d), -1) {
+		parameter, name := m[1] != "", m[2]
+		if m[5] != "" {
+			return nil, fmt.Errorf("wsdl: external entity %q is not allowed", name)
+		}
+		if parameter {
+			continue
+		}
+		decls[name] = m[3] + m[4]
+	}
+	entities := make(map[string]string)
+	for name := range decls {
+		v, err := expandEntity(name, decls, entities, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		entities[name] = v
+	}
+	return entities, nil
+}
+
+func expandEntity(name string, decls, entities map[string]string, seen map[string]bool) (string, error) {
+	if v, ok := entities[name]; ok {
+		return v, nil
+	}
+	if seen[name] {
+		return "", fmt.Errorf("wsdl: entity %q references itself", name)
+	}
+	seen[name] = true
+	defer delete(seen, name)
+	var err error
+	v := entityRef.ReplaceAllStringFunc(decls[name], func(ref string) string {
+		ref = ref[1 : len(ref)-1]
+		if _, ok := decls[ref]; !ok || err != nil {
+			return "&" + ref + ";"
+		}
+		var nv string
+		nv, err = expandEntity(ref, decls, entities, seen)
+		if err == nil && len(nv) > maxEntitySize {
+			err = fmt.Errorf("wsdl: entity %q is too large", ref)
+		}
+		return nv
+	})
+	if err == nil && len(v) > maxEntitySize {
+		err = fmt.Errorf("wsdl: entity %q is too large", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	entities[name] = v
+	return v, nil
+}
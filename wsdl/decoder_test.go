@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +42,920 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestUnmarshalNamespaces(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "golden1.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"":    "http://schemas.xmlsoap.org/wsdl/",
+		"tns": "http://localhost:9999",
+		"xsd": "http://www.w3.org/2001/XMLSchema",
+	}
+	for prefix, ns := range want {
+		if have := d.Namespaces[prefix]; have != ns {
+			t.Errorf("prefix %q: want %q, have %q", prefix, ns, have)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:tns="urn:orders" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:types" xmlns:t="urn:types" xmlns:tns="urn:other"/>
+    <xs:schema targetNamespace="urn:items" xmlns:t="urn:items"/>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		QName, Space, Local string
+	}{
+		{"tns:Order", "urn:orders", "Order"},
+		{"xs:string", "http://www.w3.org/2001/XMLSchema", "string"},
+		{"t:Item", "urn:types", "Item"},
+		{"Order", "http://schemas.xmlsoap.org/wsdl/", "Order"},
+		{"xml:lang", "http://www.w3.org/XML/1998/namespace", "lang"},
+		{"none:Order", "", "Order"},
+	} {
+		space, local := d.Resolve(tc.QName)
+		if space != tc.Space || local != tc.Local {
+			t.Errorf("%q: want %q %q, have %q %q", tc.QName, tc.Space, tc.Local, space, local)
+		}
+	}
+
+	// names within a schema are resolved by its prefixes first
+	for _, tc := range []struct {
+		Schema              int
+		QName, Space, Local string
+	}{
+		{0, "tns:Order", "urn:other", "Order"},
+		{0, "t:Item", "urn:types", "Item"},
+		{1, "tns:Order", "urn:orders", "Order"},
+		{1, "t:Item", "urn:items", "Item"},
+		{1, "xs:string", "http://www.w3.org/2001/XMLSchema", "string"},
+		{1, "none:Item", "", "Item"},
+	} {
+		space, local := d.Types.Schemas[tc.Schema].Resolve(tc.QName)
+		if space != tc.Space || local != tc.Local {
+			t.Errorf("schema %d %q: want %q %q, have %q %q", tc.Schema, tc.QName, tc.Space, tc.Local, space, local)
+		}
+	}
+}
+
+func TestDocumentationIn(t *testing.T) {
+	doc := Documentation{
+		{Lang: "en", Text: "english"},
+		{Text: "default"},
+		{Lang: "pt-BR", Text: "portuguese"},
+	}
+	cases := []struct {
+		Lang string
+		Want string
+	}{
+		{"", "default"},
+		{"en", "english"},
+		{"EN", "english"},
+		{"en-US", "english"},
+		{"pt", "portuguese"},
+		{"pt-br", "portuguese"},
+		{"de", "default"},
+	}
+	for _, tc := range cases {
+		if have := doc.In(tc.Lang); have != tc.Want {
+			t.Errorf("lang %q: want %q, have %q", tc.Lang, tc.Want, have)
+		}
+	}
+	if have := (Documentation{{Lang: "en", Text: "english"}}).In("de"); have != "english" {
+		t.Errorf("fallback: want %q, have %q", "english", have)
+	}
+}
+
+func TestUnmarshalDocumentation(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/">
+  <portType name="P">
+    <operation name="Op">
+      <documentation xml:lang="en">english</documentation>
+      <documentation xml:lang="fr">french</documentation>
+    </operation>
+  </portType>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := d.DefaultPortType().Operations[0].Doc
+	if len(doc) != 2 || doc[1].Lang != "fr" || doc[1].Text != "french" {
+		t.Fatalf("unexpected documentation: %+v", doc)
+	}
+}
+
+func TestUnmarshalDocumentationElements(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">
+  <message name="M">
+    <documentation>message</documentation>
+    <part name="p" type="xsd:string">
+      <documentation>part</documentation>
+    </part>
+  </message>
+  <binding name="B" type="tns:P">
+    <documentation>binding</documentation>
+    <soap:binding style="document"/>
+  </binding>
+  <service name="S">
+    <documentation>service</documentation>
+    <port name="SP" binding="tns:B">
+      <documentation>port</documentation>
+      <soap:address location="http://localhost"/>
+    </port>
+  </service>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, doc := range map[string]Documentation{
+		"message": d.Messages[0].Doc,
+		"part":    d.Messages[0].Parts[0].Doc,
+		"binding": d.DefaultBinding().Doc,
+		"service": d.Services[0].Doc,
+		"port":    d.Services[0].Ports[0].Doc,
+	} {
+		if have := doc.String(); have != want {
+			t.Errorf("want documentation %q, have %q", want, have)
+		}
+	}
+}
+
+func TestUnmarshalBindings(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:http="http://schemas.xmlsoap.org/wsdl/http/">
+  <binding name="SOAP">
+    <soap:binding style="document"/>
+    <operation name="Op">
+      <soap:operation soapAction="urn:Op"/>
+    </operation>
+  </binding>
+  <binding name="HTTP">
+    <http:binding verb="GET"/>
+    <operation name="Op">
+      <http:operation location="/Op"/>
+    </operation>
+  </binding>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.DefaultBinding().Name != "SOAP" || len(d.DefaultBinding().Operations) != 1 {
+		t.Fatalf("unexpected binding: %+v", d.DefaultBinding())
+	}
+	if bt := d.DefaultBinding().BindingType; bt.Style != "document" || bt.Verb != "" {
+		t.Fatalf("unexpected binding type: %+v", bt)
+	}
+}
+
+func TestUnmarshalOperationStyles(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/">
+  <binding name="SOAP">
+    <soap:binding style="document"/>
+    <operation name="Doc">
+      <soap:operation soapAction="urn:Doc"/>
+    </operation>
+    <operation name="RPC">
+      <soap:operation soapAction="urn:RPC" style="rpc"/>
+    </operation>
+    <operation name="RPC12">
+      <soap12:operation soapAction="urn:RPC12" style="rpc"/>
+    </operation>
+  </binding>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := d.DefaultBinding().Operations
+	if len(ops) != 3 {
+		t.Fatalf("unexpected operations: %+v", ops)
+	}
+	if ops[0].Operation11.Style != "" || ops[1].Operation11.Style != "rpc" || ops[2].Operation.Style != "rpc" {
+		t.Errorf("unexpected styles: %q, %q, %q", ops[0].Operation11.Style, ops[1].Operation11.Style, ops[2].Operation.Style)
+	}
+}
+
+func TestUnmarshalBindingHeaders(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:orders">
+  <binding name="SOAP">
+    <soap:binding style="document"/>
+    <operation name="Order">
+      <soap:operation soapAction="urn:Order"/>
+      <input>
+        <soap:header message="tns:Auth" part="token" use="literal">
+          <soap:headerfault message="tns:AuthFault" part="reason" use="literal"/>
+        </soap:header>
+        <soap:header message="tns:Trace" part="id" use="literal"/>
+        <soap:body use="literal"/>
+      </input>
+      <output>
+        <soap:body use="literal"/>
+      </output>
+    </operation>
+  </binding>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bo := d.DefaultBinding().Operations[0]
+	if bo.Input == nil || bo.Input.Use != "literal" {
+		t.Fatalf("unexpected input body: %+v", bo.Input)
+	}
+	if len(bo.InputHeaders) != 2 || len(bo.OutputHeaders) != 0 {
+		t.Fatalf("unexpected headers: %+v, %+v", bo.InputHeaders, bo.OutputHeaders)
+	}
+	h := bo.InputHeaders[0]
+	if h.Message != "tns:Auth" || h.Part != "token" || h.Use != "literal" {
+		t.Errorf("unexpected header: %+v", h)
+	}
+	if len(h.Faults) != 1 || h.Faults[0].Message != "tns:AuthFault" || h.Faults[0].Part != "reason" {
+		t.Errorf("unexpected header faults: %+v", h.Faults)
+	}
+	if h := bo.InputHeaders[1]; h.Message != "tns:Trace" || len(h.Faults) != 0 {
+		t.Errorf("unexpected header: %+v", h)
+	}
+}
+
+func TestUnmarshalSOAPVersions(t *testing.T) {
+	for _, tc := range []struct {
+		Prefix, Space, Version string
+	}{
+		{"soap", SOAPBindingNamespace, "1.1"},
+		{"soap12", SOAP12BindingNamespace, "1.2"},
+		{"http", HTTPBindingNamespace, ""},
+	} {
+		src := strings.Replace(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+  xmlns:http="http://schemas.xmlsoap.org/wsdl/http/">
+  <binding name="B">
+    <P:binding style="document"/>
+    <operation name="Op">
+      <input>
+        <P:header message="tns:H" part="h" use="literal"/>
+        <P:body use="literal"/>
+      </input>
+    </operation>
+  </binding>
+  <service name="S">
+    <port name="SP" binding="tns:B">
+      <P:address location="http://localhost"/>
+    </port>
+  </service>
+</definitions>`, "P:", tc.Prefix+":", -1)
+		d, err := Unmarshal(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Prefix, err)
+		}
+		if v := d.DefaultBinding().BindingType.SOAPVersion(); v != tc.Version {
+			t.Errorf("%s: want binding version %q, have %q", tc.Prefix, tc.Version, v)
+		}
+		if v := d.Services[0].Ports[0].Address.SOAPVersion(); v != tc.Version {
+			t.Errorf("%s: want address version %q, have %q", tc.Prefix, tc.Version, v)
+		}
+		bo := d.DefaultBinding().Operations[0]
+		if bo.Input == nil || bo.Input.XMLName.Space != tc.Space {
+			t.Errorf("%s: unexpected body: %+v", tc.Prefix, bo.Input)
+		}
+		if len(bo.InputHeaders) != 1 || bo.InputHeaders[0].XMLName.Space != tc.Space {
+			t.Errorf("%s: unexpected headers: %+v", tc.Prefix, bo.InputHeaders)
+		}
+	}
+}
+
+func TestUnmarshalBindingTransports(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">
+  <binding name="HTTP">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http/"/>
+  </binding>
+  <binding name="JMS">
+    <soap:binding style="document" transport="http://www.w3.org/2010/soapjms/"/>
+  </binding>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.DefaultBinding().Name != "HTTP" || !d.DefaultBinding().BindingType.HTTPTransport() {
+		t.Fatalf("unexpected binding: %+v", d.DefaultBinding())
+	}
+	for transport, want := range map[string]bool{
+		"":                                     true,
+		SOAPHTTPTransport:                      true,
+		SOAPHTTPTransport + " ":                true,
+		SOAP12HTTPTransport:                    true,
+		"http://www.w3.org/2010/soapjms/":      false,
+		"http://schemas.xmlsoap.org/soap/smtp": false,
+	} {
+		if have := (&BindingType{Transport: transport}).HTTPTransport(); have != want {
+			t.Errorf("transport %q: want %v, have %v", transport, want, have)
+		}
+	}
+}
+
+func TestUnmarshalFaults(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:tns="urn:orders">
+  <message name="OrderRequest"/>
+  <message name="OrderResponse"/>
+  <message name="OrderFault"/>
+  <message name="AuthFault"/>
+  <portType name="Orders">
+    <operation name="Order">
+      <input message="tns:OrderRequest"/>
+      <output message="tns:OrderResponse"/>
+      <fault name="Invalid" message="tns:OrderFault"/>
+      <fault name="Denied" message="AuthFault"/>
+    </operation>
+  </portType>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := d.DefaultPortType().Operations[0]
+	if len(op.Faults) != 2 {
+		t.Fatalf("unexpected faults: %+v", op.Faults)
+	}
+	for i, want := range []struct{ Name, Message string }{
+		{"Invalid", "OrderFault"},
+		{"Denied", "AuthFault"},
+	} {
+		f := op.Faults[i]
+		if f.Name != want.Name {
+			t.Errorf("fault %d: want name %q, have %q", i, want.Name, f.Name)
+		}
+		if m := d.Message(f.Message); m == nil || m.Name != want.Message {
+			t.Errorf("fault %d: want message %q, have %+v", i, want.Message, m)
+		}
+	}
+	if m := d.Message("tns:Missing"); m != nil {
+		t.Errorf("unexpected message: %+v", m)
+	}
+}
+
+func TestUnmarshalSchemas(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a" xmlns:a="urn:a">
+      <xs:element name="Order" type="a:Order"/>
+      <xs:complexType name="Order"/>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:b" xmlns:a="urn:other" xmlns:b="urn:b">
+      <xs:import namespace="urn:c" schemaLocation="c.xsd"/>
+      <xs:simpleType name="Code">
+        <xs:restriction base="xs:string"/>
+      </xs:simpleType>
+      <xs:complexType name="Item"/>
+    </xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Types.Schemas) != 2 {
+		t.Fatalf("want 2 schemas, have %d", len(d.Types.Schemas))
+	}
+	for i, want := range []string{"urn:a", "urn:b"} {
+		if s := d.Types.Schemas[i]; s.TargetNamespace != want {
+			t.Errorf("schema %d: want target namespace %q, have %q", i, want, s.TargetNamespace)
+		}
+	}
+	if s := d.Types.Schemas[1]; len(s.ComplexTypes) != 1 || s.ComplexTypes[0].Name != "Item" {
+		t.Errorf("unexpected types of schema 1: %+v", s.ComplexTypes)
+	}
+	s := d.Schema
+	if s.TargetNamespace != "urn:a" {
+		t.Errorf("merged schema: want target namespace %q, have %q", "urn:a", s.TargetNamespace)
+	}
+	if len(s.Elements) != 1 || len(s.ComplexTypes) != 2 || len(s.SimpleTypes) != 1 || len(s.Imports) != 1 {
+		t.Errorf("unexpected merged schema: %+v", s)
+	}
+	if s.Namespaces["a"] != "urn:a" || s.Namespaces["b"] != "urn:b" {
+		t.Errorf("unexpected merged namespaces: %v", s.Namespaces)
+	}
+}
+
+func TestUnmarshalSchema(t *testing.T) {
+	s, err := UnmarshalSchemaFrom(strings.NewReader("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n"+
+		`<xs:schema targetNamespace="urn:a" xmlns:a="urn:a" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="Order" type="a:Order">
+    <xs:annotation><xs:documentation>Pedido de caf`+"\xe9"+`</xs:documentation></xs:annotation>
+  </xs:element>
+  <xs:complexType name="Order"/>
+</xs:schema>`), "orders.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TargetNamespace != "urn:a" || s.Namespaces["a"] != "urn:a" {
+		t.Errorf("unexpected schema: %+v", s)
+	}
+	if len(s.Elements) != 1 || s.Elements[0].Doc.String() != "Pedido de café" {
+		t.Errorf("unexpected elements: %+v", s.Elements)
+	}
+	if len(s.ComplexTypes) != 1 || s.ComplexTypes[0].Pos.String() != "orders.xsd:6:3" {
+		t.Errorf("unexpected complex types: %+v", s.ComplexTypes)
+	}
+	_, err = UnmarshalSchema(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`))
+	if err == nil {
+		t.Error("want error unmarshaling definitions as a schema")
+	}
+}
+
+func TestUnmarshalPolicies(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:wsp="http://schemas.xmlsoap.org/ws/2004/09/policy"
+  xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+  xmlns:sp="http://schemas.xmlsoap.org/ws/2005/07/securitypolicy">
+  <wsp:Policy wsu:Id="Binding_policy">
+    <wsp:ExactlyOne>
+      <wsp:All>
+        <sp:SymmetricBinding>
+          <wsp:Policy>
+            <sp:ProtectionToken/>
+          </wsp:Policy>
+        </sp:SymmetricBinding>
+        <wsaw:UsingAddressing xmlns:wsaw="http://www.w3.org/2006/05/addressing/wsdl"/>
+      </wsp:All>
+    </wsp:ExactlyOne>
+  </wsp:Policy>
+  <wsp:Policy wsu:Id="Binding_Op_Input_policy">
+    <sp:SignedParts>
+      <sp:Body/>
+    </sp:SignedParts>
+  </wsp:Policy>
+  <binding name="Binding">
+    <wsp:PolicyReference URI="#Binding_policy"/>
+    <soap:binding style="document"/>
+    <operation name="Op">
+      <soap:operation soapAction="urn:Op"/>
+      <input>
+        <wsp:PolicyReference URI="#Binding_Op_Input_policy"/>
+        <soap:body use="literal"/>
+      </input>
+    </operation>
+  </binding>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Policies) != 2 || len(d.DefaultBinding().PolicyReferences) != 1 {
+		t.Fatalf("unexpected policies: %+v, references: %+v", d.Policies, d.DefaultBinding().PolicyReferences)
+	}
+	p := d.Policy(d.DefaultBinding().PolicyReferences[0].URI)
+	if p == nil || p.ID != "Binding_policy" {
+		t.Fatalf("unexpected binding policy: %+v", p)
+	}
+	if !p.Asserts("SymmetricBinding") || !p.Asserts("ProtectionToken") || p.Asserts("TransportBinding") {
+		t.Errorf("unexpected assertions: %+v", p.Assertions)
+	}
+	if !p.Addressing {
+		t.Errorf("want addressing asserted")
+	}
+	if a := p.Assertions; len(a) != 1 || a[0].Name.Local != "ExactlyOne" || a[0].Name.Space != "http://schemas.xmlsoap.org/ws/2004/09/policy" {
+		t.Errorf("unexpected operator: %+v", a)
+	}
+	refs := d.DefaultBinding().Operations[0].InputPolicyReferences
+	if len(refs) != 1 {
+		t.Fatalf("unexpected input policy references: %+v", refs)
+	}
+	if p := d.Policy(refs[0].URI); p == nil || !p.Asserts("SignedParts") || p.Addressing {
+		t.Errorf("unexpected input policy: %+v", p)
+	}
+	if d.Policy("#Missing") != nil {
+		t.Errorf("unexpected policy for #Missing")
+	}
+}
+
+func TestUnmarshalFacets(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a">
+      <xs:simpleType name="Code">
+        <xs:restriction base="xs:string">
+          <xs:pattern value="[A-Z]{3}"/>
+          <xs:pattern value="[0-9]{3}"/>
+          <xs:length value="3" fixed="true"/>
+          <xs:whiteSpace value="collapse"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:simpleType name="Name">
+        <xs:restriction base="xs:string">
+          <xs:minLength value="1"/>
+          <xs:maxLength value="64"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:simpleType name="Price">
+        <xs:restriction base="xs:decimal">
+          <xs:totalDigits value="10"/>
+          <xs:fractionDigits value="2"/>
+          <xs:minInclusive value="0"/>
+          <xs:maxExclusive value="1000000"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:simpleType name="Percent">
+        <xs:restriction base="xs:int">
+          <xs:minExclusive value="-1"/>
+          <xs:maxInclusive value="100"/>
+        </xs:restriction>
+      </xs:simpleType>
+    </xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := d.Schema.SimpleTypes
+	if len(st) != 4 {
+		t.Fatalf("want 4 simple types, have %d", len(st))
+	}
+	code, name, price, percent := st[0].Restriction, st[1].Restriction, st[2].Restriction, st[3].Restriction
+	if len(code.Patterns) != 2 || code.Patterns[1].Value != "[0-9]{3}" {
+		t.Errorf("unexpected patterns: %+v", code.Patterns)
+	}
+	for _, tc := range []struct {
+		Facet *Facet
+		Want  string
+	}{
+		{code.Length, "3"},
+		{code.WhiteSpace, "collapse"},
+		{name.MinLength, "1"},
+		{name.MaxLength, "64"},
+		{price.TotalDigits, "10"},
+		{price.FractionDigits, "2"},
+		{price.MinInclusive, "0"},
+		{price.MaxExclusive, "1000000"},
+		{percent.MinExclusive, "-1"},
+		{percent.MaxInclusive, "100"},
+	} {
+		if tc.Facet == nil || tc.Facet.Value != tc.Want {
+			t.Errorf("want facet %q, have %+v", tc.Want, tc.Facet)
+		}
+	}
+	if !code.Length.Fixed || name.MinLength.Fixed {
+		t.Errorf("unexpected fixed facets: %+v, %+v", code.Length, name.MinLength)
+	}
+	if name.Length != nil || price.MaxInclusive != nil {
+		t.Errorf("unexpected facets: %+v, %+v", name.Length, price.MaxInclusive)
+	}
+}
+
+func TestUnmarshalInlineSimpleTypes(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a">
+      <xs:element name="Code">
+        <xs:simpleType>
+          <xs:restriction base="xs:string">
+            <xs:maxLength value="8"/>
+          </xs:restriction>
+        </xs:simpleType>
+      </xs:element>
+      <xs:complexType name="Order">
+        <xs:attribute name="priority">
+          <xs:simpleType>
+            <xs:restriction base="xs:int">
+              <xs:enumeration value="1"/>
+              <xs:enumeration value="2"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:attribute>
+      </xs:complexType>
+    </xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := d.Schema.Elements[0].SimpleType
+	if st == nil || st.Restriction == nil || st.Restriction.Base != "xs:string" || st.Restriction.MaxLength.Value != "8" {
+		t.Errorf("unexpected simple type of element: %+v", st)
+	}
+	st = d.Schema.ComplexTypes[0].Attributes[0].SimpleType
+	if st == nil || st.Restriction == nil || st.Restriction.Base != "xs:int" || len(st.Restriction.Enum) != 2 {
+		t.Errorf("unexpected simple type of attribute: %+v", st)
+	}
+}
+
+func TestUnmarshalIdentityConstraints(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a" xmlns:a="urn:a">
+      <xs:element name="Catalog" type="a:Catalog">
+        <xs:key name="ProductKey">
+          <xs:selector xpath="a:Product"/>
+          <xs:field xpath="@id"/>
+        </xs:key>
+        <xs:keyref name="OfferProduct" refer="a:ProductKey">
+          <xs:selector xpath="a:Offer"/>
+          <xs:field xpath="@product"/>
+        </xs:keyref>
+        <xs:unique name="OfferCode">
+          <xs:selector xpath="a:Offer"/>
+          <xs:field xpath="a:Region"/>
+          <xs:field xpath="a:Code"/>
+        </xs:unique>
+      </xs:element>
+    </xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := d.Schema.Elements[0]
+	if len(el.Keys) != 1 || len(el.KeyRefs) != 1 || len(el.Uniques) != 1 {
+		t.Fatalf("unexpected identity constraints: %+v, %+v, %+v", el.Keys, el.KeyRefs, el.Uniques)
+	}
+	key, ref, unique := el.Keys[0], el.KeyRefs[0], el.Uniques[0]
+	if key.Name != "ProductKey" || key.Selector.XPath != "a:Product" || len(key.Fields) != 1 || key.Fields[0].XPath != "@id" {
+		t.Errorf("unexpected key: %+v", key)
+	}
+	if ref.Name != "OfferProduct" || ref.Refer != "a:ProductKey" || ref.Selector.XPath != "a:Offer" {
+		t.Errorf("unexpected keyref: %+v", ref)
+	}
+	if len(unique.Fields) != 2 || unique.Fields[1].XPath != "a:Code" || unique.XMLName.Local != "unique" {
+		t.Errorf("unexpected unique: %+v", unique)
+	}
+}
+
+func TestUnmarshalSubstitutionGroups(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xs:schema targetNamespace="urn:a" xmlns:a="urn:a">
+      <xs:element name="Shape" type="a:Shape" abstract="true"/>
+      <xs:element name="Circle" type="a:Circle" substitutionGroup="a:Shape"/>
+    </xs:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape, circle := d.Schema.Elements[0], d.Schema.Elements[1]
+	if !shape.Abstract || shape.SubstitutionGroup != "" {
+		t.Errorf("unexpected head element: %+v", shape)
+	}
+	if circle.Abstract || circle.SubstitutionGroup != "a:Shape" {
+		t.Errorf("unexpected member element: %+v", circle)
+	}
+}
+
+func TestUnmarshalExtensibilityElements(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:wsa="http://www.w3.org/2005/08/addressing"
+  xmlns:v="urn:vendor">
+  <types>
+    <v:typeSystem name="T"/>
+  </types>
+  <binding name="B" type="tns:P">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <v:retry wsdl:required="true" count="3"><v:delay>5</v:delay></v:retry>
+  </binding>
+  <service name="S">
+    <port name="SP" binding="tns:B">
+      <soap:address location="http://localhost"/>
+      <wsa:EndpointReference><wsa:Address>http://localhost</wsa:Address></wsa:EndpointReference>
+    </port>
+  </service>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext := d.Types.Extensions; len(ext) != 1 || ext[0].XMLName != (xml.Name{Space: "urn:vendor", Local: "typeSystem"}) {
+		t.Errorf("unexpected types extensions: %+v", ext)
+	}
+	if len(d.DefaultBinding().Extensions) != 1 {
+		t.Fatalf("want 1 binding extension, have %d", len(d.DefaultBinding().Extensions))
+	}
+	ext := d.DefaultBinding().Extensions[0]
+	if ext.XMLName != (xml.Name{Space: "urn:vendor", Local: "retry"}) || ext.InnerXML != "<v:delay>5</v:delay>" {
+		t.Errorf("unexpected binding extension: %+v", ext)
+	}
+	want := []xml.Attr{
+		{Name: xml.Name{Space: "http://schemas.xmlsoap.org/wsdl/", Local: "required"}, Value: "true"},
+		{Name: xml.Name{Local: "count"}, Value: "3"},
+	}
+	if !reflect.DeepEqual(ext.Attrs, want) {
+		t.Errorf("want attributes %+v, have %+v", want, ext.Attrs)
+	}
+	if d.DefaultBinding().BindingType == nil {
+		t.Error("missing binding type")
+	}
+	port := d.Services[0].Ports[0]
+	if len(port.Extensions) != 1 || port.Extensions[0].XMLName.Local != "EndpointReference" {
+		t.Errorf("unexpected port extensions: %+v", port.Extensions)
+	}
+	if port.Address.Location != "http://localhost" {
+		t.Errorf("unexpected address: %+v", port.Address)
+	}
+}
+
+func TestUnmarshalNestedSequences(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xsd:schema>
+      <xsd:complexType name="T">
+        <xsd:sequence>
+          <xsd:element name="a" type="xsd:string"/>
+          <xsd:sequence>
+            <xsd:element name="b" type="xsd:string"/>
+            <xsd:sequence maxOccurs="unbounded">
+              <xsd:element name="r" type="xsd:string"/>
+            </xsd:sequence>
+            <xsd:sequence>
+              <xsd:element name="c" type="xsd:string"/>
+            </xsd:sequence>
+          </xsd:sequence>
+          <xsd:element name="d" type="xsd:string"/>
+          <xsd:any/>
+        </xsd:sequence>
+      </xsd:complexType>
+    </xsd:schema>
+  </types>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := d.Schema.ComplexTypes[0].Sequence
+	var have []string
+	for _, el := range seq.Elements {
+		have = append(have, el.Name)
+	}
+	if strings.Join(have, ",") != "a,b,c,d" || len(seq.Any) != 1 {
+		t.Fatalf("unexpected sequence: %v, %d any", have, len(seq.Any))
+	}
+	// repeated sequences are kept, with their position among elements
+	if len(seq.Sequences) != 1 {
+		t.Fatalf("unexpected repeated sequences: %d", len(seq.Sequences))
+	}
+	if r := seq.Sequences[0]; r.Max != "unbounded" || r.Position != 2 || len(r.Elements) != 1 {
+		t.Fatalf("unexpected repeated sequence: %+v", r)
+	}
+}
+
+func TestUnmarshalEnvelope(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+  <s:Header>
+    <a:Action xmlns:a="http://www.w3.org/2005/08/addressing">http://schemas.xmlsoap.org/ws/2004/09/transfer/GetResponse</a:Action>
+  </s:Header>
+  <s:Body>
+    <Metadata xmlns="http://schemas.xmlsoap.org/ws/2004/09/mex">
+      <MetadataSection Dialect="http://www.w3.org/2001/XMLSchema">
+        <xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"/>
+      </MetadataSection>
+      <MetadataSection Dialect="http://schemas.xmlsoap.org/wsdl/">
+        <wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" name="Echo" targetNamespace="urn:echo"/>
+      </MetadataSection>
+    </Metadata>
+  </s:Body>
+</s:Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "Echo" || d.TargetNamespace != "urn:echo" {
+		t.Fatalf("unexpected definitions: %+v", d)
+	}
+}
+
+func TestUnmarshalEntities(t *testing.T) {
+	cases := []struct {
+		Doc  string
+		Name string
+		Fail bool
+	}{
+		{
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY vendor "Acme">
+  <!ENTITY service "&vendor;Service">
+]>
+<definitions name="&service;"/>`,
+			Name: "AcmeService",
+		},
+		{
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY passwd SYSTEM "file:///etc/passwd">
+]>
+<definitions name="&passwd;"/>`,
+			Fail: true,
+		},
+		{
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY a "&b;">
+  <!ENTITY b "&a;">
+]>
+<definitions name="&a;"/>`,
+			Fail: true,
+		},
+		{
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY a "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa">
+  <!ENTITY b "&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;">
+  <!ENTITY c "&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;">
+  <!ENTITY d "&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;">
+]>
+<definitions name="&d;"/>`,
+			Fail: true,
+		},
+		{
+			// a large entity, referred to many times
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY a "` + strings.Repeat("a", 1<<15) + `">
+]>
+<definitions><documentation>` + strings.Repeat("&a;", 1<<10) + `</documentation></definitions>`,
+			Fail: true,
+		},
+		{
+			Doc: `<?xml version="1.0"?>
+<!DOCTYPE definitions [
+  <!ENTITY a "` + strings.Repeat("a", 1<<15) + `">
+]>
+<definitions name="Service"><documentation>` + strings.Repeat("&a;", 1<<8) + `</documentation></definitions>`,
+			Name: "Service",
+		},
+	}
+	for i, tc := range cases {
+		d, err := Unmarshal(strings.NewReader(tc.Doc))
+		if tc.Fail {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if d.Name != tc.Name {
+			t.Errorf("test %d: want %q, have %q", i, tc.Name, d.Name)
+		}
+	}
+}
+
+func TestUnmarshalPositions(t *testing.T) {
+	doc := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+<types><xs:schema>
+  <xs:complexType name="Order"><xs:sequence/></xs:complexType>
+  <xs:simpleType
+      name="Code"><xs:restriction base="xs:string"/></xs:simpleType>
+</xs:schema></types>
+<portType name="Orders">
+  <operation name="Get"><input message="GetIn"/><output message="GetOut"/></operation>
+</portType>
+<binding name="OrdersBinding" type="Orders"/>
+</definitions>`
+	d, err := UnmarshalFrom(strings.NewReader(doc), "orders.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := d.DefaultPortType().Operations[0]
+	for i, tc := range []struct {
+		Have Pos
+		Want string
+	}{
+		{d.Schema.ComplexTypes[0].Pos, "orders.wsdl:3:3"},
+		{d.Schema.SimpleTypes[0].Pos, "orders.wsdl:4:3"},
+		{op.Input.Pos, "orders.wsdl:8:25"},
+		{op.Output.Pos, "orders.wsdl:8:49"},
+		{d.DefaultBinding().Pos, "orders.wsdl:10:1"},
+	} {
+		if have := tc.Have.String(); have != tc.Want {
+			t.Errorf("test %d: want %q, have %q", i, tc.Want, have)
+		}
+	}
+	if d, err = Unmarshal(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	if have := d.DefaultBinding().Pos.String(); have != "10:1" {
+		t.Errorf("want position 10:1 without source, have %q", have)
+	}
+}
@@ -0,0 +1,27 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// Marshal writes d to w as an indented WSDL document, such as one
+// unmarshaled and then edited.
+//
+// The schemas written are those of d.Types, rather than d.Schema which
+// merges them. The namespace prefixes of d.Namespaces and of each schema
+// are declared again, so qualified names such as tns:Order keep their
+// meaning; names without prefix are in the namespace of WSDL, or of XML
+// Schema within schemas.
+func Marshal(w io.Writer, d *Definitions) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,281 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	d, err := Unmarshal(strings.NewReader(`<wsdl:definitions name="Store"
+  targetNamespace="urn:store"
+  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+  xmlns:wsp="http://www.w3.org/ns/ws-policy"
+  xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema"
+  xmlns:v="urn:vendor"
+  xmlns:tns="urn:store">
+  <wsp:Policy wsu:Id="P">
+    <wsp:ExactlyOne><wsp:All><sp:TransportBinding xmlns:sp="urn:sp"/></wsp:All></wsp:ExactlyOne>
+  </wsp:Policy>
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:store" elementFormDefault="qualified">
+      <xs:element name="Get">
+        <xs:annotation><xs:documentation>Gets a key.</xs:documentation></xs:annotation>
+        <xs:complexType>
+          <xs:sequence>
+            <xs:element name="Key" type="xs:string"/>
+            <xs:element name="Default" type="xs:string" minOccurs="0"/>
+            <xs:sequence maxOccurs="unbounded">
+              <xs:element name="Tag" type="xs:string"/>
+            </xs:sequence>
+            <xs:element name="Limit" type="xs:int" minOccurs="2"/>
+          </xs:sequence>
+        </xs:complexType>
+      </xs:element>
+    </xs:schema>
+    <v:typeSystem name="T"/>
+  </wsdl:types>
+  <wsdl:message name="GetRequest"><wsdl:part name="parameters" element="tns:Get"/></wsdl:message>
+  <wsdl:portType name="StorePort">
+    <wsdl:operation name="Get"><wsdl:input message="tns:GetRequest"/></wsdl:operation>
+    <wsdl:operation name="Delete"><wsdl:input message="tns:GetRequest"/></wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="StoreBinding" type="tns:StorePort">
+    <soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsp:PolicyReference URI="#P"/>
+    <v:retry wsdl:required="true" xmlns:r="urn:retry"><r:delay>5</r:delay></v:retry>
+    <wsdl:operation name="Get">
+      <soap12:operation soapAction="urn:get"/>
+      <wsdl:input><soap12:body use="literal"/></wsdl:input>
+    </wsdl:operation>
+    <wsdl:operation name="Delete">
+      <soap12:operation soapAction="urn:delete"/>
+      <wsdl:input><soap12:body use="literal"/></wsdl:input>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="Store">
+    <wsdl:port name="StorePort" binding="tns:StoreBinding">
+      <soap12:address location="http://localhost/store"/>
+      <v:endpoint><v:region>eu</v:region></v:endpoint>
+    </wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.DefaultPortType().Operations = d.DefaultPortType().Operations[:1]
+	d.DefaultBinding().Operations = d.DefaultBinding().Operations[:1]
+	d.Services[0].Ports[0].Address.Location = "https://example.com/store"
+	var b bytes.Buffer
+	if err = Marshal(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "<input></input>") || strings.Contains(b.String(), "<output>") {
+		t.Errorf("unexpected empty input or output:\n%s", &b)
+	}
+	// extensibility elements of bindings precede their operations
+	binding := b.String()[strings.Index(b.String(), "<binding "):]
+	if i, j := strings.Index(binding, "<retry "), strings.Index(binding, "<operation "); i < 0 || i > j {
+		t.Errorf("binding extension not before the operations:\n%s", &b)
+	}
+	d, err = Unmarshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := d.Namespaces["tns"]; v != "urn:store" {
+		t.Errorf("unexpected tns namespace: %q", v)
+	}
+	if a := d.Services[0].Ports[0].Address; a.Location != "https://example.com/store" || a.SOAPVersion() != "1.2" {
+		t.Errorf("unexpected address: %+v", a)
+	}
+	if len(d.DefaultBinding().Operations) != 1 || d.DefaultBinding().Operations[0].Operation.Action != "urn:get" {
+		t.Errorf("unexpected binding operations: %+v", d.DefaultBinding().Operations)
+	}
+	if bo := d.DefaultBinding().Operations[0]; bo.Input == nil || bo.Input.Use != "literal" || bo.Operation11.XMLName.Local != "" {
+		t.Errorf("unexpected binding operation: %+v", bo)
+	}
+	if bt := d.DefaultBinding().BindingType; bt == nil || bt.SOAPVersion() != "1.2" {
+		t.Errorf("unexpected binding type: %+v", bt)
+	}
+	if p := d.Policy("#P"); p == nil || !p.Asserts("TransportBinding") {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+	if len(d.DefaultBinding().PolicyReferences) != 1 || d.DefaultBinding().PolicyReferences[0].URI != "#P" {
+		t.Errorf("unexpected policy references: %+v", d.DefaultBinding().PolicyReferences)
+	}
+	if ext := d.Types.Extensions; len(ext) != 1 || ext[0].XMLName.Local != "typeSystem" {
+		t.Errorf("unexpected types extensions: %+v", ext)
+	}
+	if ext := d.DefaultBinding().Extensions; len(ext) != 1 || ext[0].InnerXML != "<r:delay>5</r:delay>" {
+		t.Errorf("unexpected binding extensions: %+v", ext)
+	} else {
+		required := xml.Attr{Name: xml.Name{Space: WSDLNamespace, Local: "required"}, Value: "true"}
+		var found bool
+		for _, attr := range ext[0].Attrs {
+			found = found || attr == required
+		}
+		if !found {
+			t.Errorf("missing %+v in %+v", required, ext[0].Attrs)
+		}
+	}
+	if ext := d.Services[0].Ports[0].Extensions; len(ext) != 1 || ext[0].XMLName != (xml.Name{Space: "urn:vendor", Local: "endpoint"}) {
+		t.Errorf("unexpected port extensions: %+v", ext)
+	}
+	if s := d.Schema; s.TargetNamespace != "urn:store" || s.ElementFormDefault != "qualified" {
+		t.Errorf("unexpected schema: %+v", s)
+	}
+	if len(d.Schema.Elements) != 1 {
+		t.Fatalf("unexpected elements: %+v", d.Schema.Elements)
+	}
+	el := d.Schema.Elements[0]
+	if el.Doc.String() != "Gets a key." || el.minGiven {
+		t.Errorf("unexpected element: %+v", el)
+	}
+	seq := el.ComplexType.Sequence
+	var have []string
+	for _, el := range seq.Elements {
+		have = append(have, el.Name)
+	}
+	if want := []string{"Key", "Default", "Limit"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("want elements %q, have %q", want, have)
+	}
+	if seq.Elements[0].minGiven || !seq.Elements[1].minGiven || seq.Elements[2].Min != 2 {
+		t.Errorf("unexpected minOccurs: %+v, %+v, %+v", seq.Elements[0], seq.Elements[1], seq.Elements[2])
+	}
+	if len(seq.Sequences) != 1 || seq.Sequences[0].Position != 2 || seq.Sequences[0].Max != "unbounded" {
+		t.Errorf("unexpected nested sequences: %+v", seq.Sequences)
+	}
+}
+
+func TestMarshalGolden(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "golden1.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err = Marshal(&b, want); err != nil {
+		t.Fatal(err)
+	}
+	have, err := Unmarshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearPositions(have)
+	clearPositions(want)
+	have.Schema, want.Schema = Schema{}, Schema{}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("marshaled definitions differ:\n%s", &b)
+	}
+}
+
+// clearPositions clears the positions of the elements of d, which are
+// those of the document it was unmarshaled from.
+func clearPositions(d *Definitions) {
+	d.Binding.Pos = Pos{}
+	for _, b := range d.Bindings {
+		b.Pos = Pos{}
+	}
+	for _, pt := range d.PortTypes {
+		for _, op := range pt.Operations {
+			for _, io := range append([]*IO{op.Input, op.Output}, op.Faults...) {
+				if io != nil {
+					io.Pos = Pos{}
+				}
+			}
+		}
+	}
+	for _, s := range d.Types.Schemas {
+		for _, st := range s.SimpleTypes {
+			st.Pos = Pos{}
+		}
+		for _, ct := range s.ComplexTypes {
+			ct.Pos = Pos{}
+		}
+	}
+}
+
+func TestMarshalBindings(t *testing.T) {
+	want, err := Unmarshal(strings.NewReader(`<definitions name="Store"
+  targetNamespace="urn:store"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+  xmlns:tns="urn:store">
+  <message name="Empty"/>
+  <portType name="PA">
+    <operation name="Get"><input message="tns:Empty"/></operation>
+  </portType>
+  <portType name="PB">
+    <operation name="Put"><input message="tns:Empty"/></operation>
+  </portType>
+  <binding name="B11" type="tns:PA">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="Get"><soap:operation soapAction="urn:get"/></operation>
+  </binding>
+  <binding name="B12" type="tns:PB">
+    <soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="Put"><soap12:operation soapAction="urn:put"/></operation>
+  </binding>
+  <service name="Store">
+    <port name="P11" binding="tns:B11"><soap:address location="http://localhost/11"/></port>
+  </service>
+  <service name="Store12">
+    <port name="P12" binding="tns:B12"><soap12:address location="http://localhost/12"/></port>
+  </service>
+</definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.PortTypes) != 2 || len(want.Bindings) != 2 {
+		t.Fatalf("want 2 port types and bindings, have %d and %d", len(want.PortTypes), len(want.Bindings))
+	}
+	if b, pt := want.DefaultBinding(), want.DefaultPortType(); b.Name != "B12" || pt.Name != "PB" || len(pt.Operations) != 1 {
+		t.Errorf("unexpected binding %q of port type %q", b.Name, pt.Name)
+	}
+	if len(want.Services) != 2 || want.DefaultService().Name != "Store12" {
+		t.Errorf("want services Store and Store12, have %+v", want.Services)
+	}
+	// deprecated copies of the defaults
+	if want.Binding.Name != "B12" || want.PortType.Name != "PB" || want.Service.Name != "Store12" {
+		t.Errorf("unexpected binding %q, port type %q and service %q", want.Binding.Name, want.PortType.Name, want.Service.Name)
+	}
+	var b bytes.Buffer
+	if err = Marshal(&b, want); err != nil {
+		t.Fatal(err)
+	}
+	// the elements of WSDL 1.1 in order, without empty types
+	if strings.Contains(b.String(), "<types") {
+		t.Errorf("unexpected types:\n%s", &b)
+	}
+	last := 0
+	for _, name := range []string{"<message ", "<portType ", "<binding ", "<service ", "</definitions>"} {
+		i := strings.Index(b.String(), name)
+		if i < last {
+			t.Errorf("%s out of order:\n%s", name, &b)
+		}
+		last = i
+	}
+	have, err := Unmarshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearPositions(have)
+	clearPositions(want)
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("marshaled definitions differ:\n%s", &b)
+	}
+	if problems := have.Validate(); len(problems) > 0 {
+		t.Errorf("unexpected problems: %v\n%s", problems, &b)
+	}
+}
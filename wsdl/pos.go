@@ -0,0 +1,98 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Pos is the position of the start tag of an element in the document it
+// was decoded from, for error messages.
+type Pos struct {
+	Source string // location of the document, if known
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as source:line:column, or line:column if
+// the source isn't known, or "" if the position isn't.
+func (p Pos) String() string {
+	switch {
+	case !p.IsValid():
+		return ""
+	case p.Source == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Source, p.Line, p.Column)
+}
+
+// source is the document read by a decoder, with the offsets where its
+// lines start, to find the positions of elements.
+type source struct {
+	r     io.Reader
+	loc   string
+	data  []byte
+	lines []int64 // offsets of the lines after the first
+
+	// entities declared by the document, and the size of their
+	// references in data up to offset scanned, see countEntities
+	entities map[string]string
+	scanned  int
+	expanded int
+}
+
+func (s *source) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i, c := range p[:n] {
+		if c == '\n' {
+			s.lines = append(s.lines, int64(len(s.data)+i+1))
+		}
+	}
+	s.data = append(s.data, p[:n]...)
+	if s.entities != nil {
+		if cerr := s.countEntities(); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+// pos returns the position of the start tag that ends before offset.
+// Documents in other encodings than UTF-8 are converted as they're
+// decoded, so their columns may be off.
+func (s *source) pos(offset int64) Pos {
+	if offset > int64(len(s.data)) {
+		offset = int64(len(s.data))
+	}
+	// '<' isn't allowed in attribute values
+	if i := bytes.LastIndexByte(s.data[:offset], '<'); i >= 0 {
+		offset = int64(i)
+	}
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+	start := int64(0)
+	if line > 0 {
+		start = s.lines[line-1]
+	}
+	return Pos{Source: s.loc, Line: line + 1, Column: int(offset-start) + 1}
+}
+
+// sources are the documents being decoded, by decoder.
+var sources sync.Map
+
+// position returns the position of the start tag of the element just
+// read by d.
+func position(d *xml.Decoder) Pos {
+	s, ok := sources.Load(d)
+	if !ok {
+		return Pos{}
+	}
+	return s.(*source).pos(d.InputOffset())
+}
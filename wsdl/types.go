@@ -2,69 +2,247 @@ package wsdl
 
 // TODO: Add all types from the spec.
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 // Definitions is the root element of a WSDL document.
 type Definitions struct {
 	XMLName         xml.Name          `xml:"definitions"`
-	Name            string            `xml:"name,attr"`
-	TargetNamespace string            `xml:"targetNamespace,attr"`
+	Name            string            `xml:"name,attr,omitempty"`
+	TargetNamespace string            `xml:"targetNamespace,attr,omitempty"`
+	Version         string            `xml:"version,attr,omitempty"`
 	Namespaces      map[string]string `xml:"-"`
-	SOAPEnv         string            `xml:"SOAP-ENV,attr"`
-	SOAPEnc         string            `xml:"SOAP-ENC,attr"`
-	Service         Service           `xml:"service"`
+	SOAPEnv         string            `xml:"SOAP-ENV,attr,omitempty"`
+	SOAPEnc         string            `xml:"SOAP-ENC,attr,omitempty"`
 	Imports         []*Import         `xml:"import"`
-	Schema          Schema            `xml:"types>schema"`
+	Policies        []*Policy         `xml:"Policy"`
+	Schema          Schema            `xml:"-"` // Types.Schemas merged
+	Types           Types             `xml:"types"`
 	Messages        []*Message        `xml:"message"`
-	PortType        PortType          `xml:"portType"` // TODO: PortType slice?
-	Binding         Binding           `xml:"binding"`
+	PortTypes       []*PortType       `xml:"portType"`
+	Bindings        []*Binding        `xml:"binding"`
+	Services        []*Service        `xml:"service"`
+
+	// Deprecated: Service, PortType and Binding are copies of those
+	// returned by DefaultService, DefaultPortType and DefaultBinding,
+	// set when the definitions are unmarshaled. They aren't marshaled;
+	// use Services, PortTypes and Bindings instead.
+	Service  Service  `xml:"-"`
+	PortType PortType `xml:"-"`
+	Binding  Binding  `xml:"-"`
 }
 
 type definitionDup Definitions
 
 // UnmarshalXML implements the xml.Unmarshaler interface.
 func (def *Definitions) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	for _, attr := range start.Attr {
-		if attr.Name.Space == "xmlns" {
-			if def.Namespaces == nil {
-				def.Namespaces = make(map[string]string)
+	def.Namespaces = namespaces(def.Namespaces, start.Attr)
+	n := len(def.Types.Schemas)
+	if err := d.DecodeElement((*definitionDup)(def), &start); err != nil {
+		return err
+	}
+	for _, s := range def.Types.Schemas[n:] {
+		s.parent = def.Namespaces
+	}
+	mergeSchemas(&def.Schema, def.Types.Schemas[n:])
+	def.Service = *def.DefaultService()
+	def.PortType = *def.DefaultPortType()
+	def.Binding = *def.DefaultBinding()
+	return nil
+}
+
+// Namespaces of WSDL 1.1 and XML Schema.
+const (
+	WSDLNamespace      = "http://schemas.xmlsoap.org/wsdl/"
+	XMLSchemaNamespace = "http://www.w3.org/2001/XMLSchema"
+)
+
+// MarshalXML implements the xml.Marshaler interface. The namespace
+// prefixes of the definitions are declared again, as attributes such as
+// type refer to them, and the default namespace is that of WSDL.
+func (def *Definitions) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: def.XMLName.Space, Local: "definitions"}
+	if start.Name.Space == "" {
+		start.Name.Space = WSDLNamespace
+	}
+	start.Attr = append(start.Attr, namespaceAttrs(def.Namespaces)...)
+	return e.EncodeElement((*definitionDup)(def), start)
+}
+
+// xmlNamespace is the namespace bound to the xml prefix.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Resolve returns the namespace and local name of qname, a qualified
+// name such as tns:Order, using the namespace prefixes declared by the
+// definitions element, or else by the schema elements. Names without a
+// prefix are in the default namespace. The namespace is "" if the prefix
+// isn't declared, or there's no default namespace.
+//
+// Prefixes declared by several schemas resolve to the namespace of the
+// first, as in the merged Schema. Names used within a schema are to be
+// resolved with the Resolve method of the schema using them.
+func (def *Definitions) Resolve(qname string) (space, local string) {
+	space, local, _ = resolve(qname, def.Namespaces, def.Schema.Namespaces)
+	return space, local
+}
+
+// mergeSchemas merges schemas into m, which has the target namespace,
+// version and form defaults of the first schema merged. Namespace
+// prefixes declared by several schemas keep the namespace of the first.
+func mergeSchemas(m *Schema, schemas []*Schema) {
+	for _, s := range schemas {
+		if m.XMLName.Local == "" {
+			m.XMLName = s.XMLName
+			m.TargetNamespace = s.TargetNamespace
+			m.Version = s.Version
+			m.ElementFormDefault = s.ElementFormDefault
+			m.AttributeFormDefault = s.AttributeFormDefault
+		}
+		for prefix, ns := range s.Namespaces {
+			if _, exists := m.Namespaces[prefix]; !exists {
+				if m.Namespaces == nil {
+					m.Namespaces = make(map[string]string)
+				}
+				m.Namespaces[prefix] = ns
 			}
-			def.Namespaces[attr.Name.Local] = attr.Value
 		}
+		m.Imports = append(m.Imports, s.Imports...)
+		m.Includes = append(m.Includes, s.Includes...)
+		m.Redefines = append(m.Redefines, s.Redefines...)
+		m.SimpleTypes = append(m.SimpleTypes, s.SimpleTypes...)
+		m.ComplexTypes = append(m.ComplexTypes, s.ComplexTypes...)
+		m.Elements = append(m.Elements, s.Elements...)
+	}
+}
+
+// Types is the types element of the definitions, with their schemas.
+type Types struct {
+	Schemas    []*Schema               `xml:"schema"`
+	Extensions []*ExtensibilityElement `xml:",any"` // other type systems
+}
+
+type typesDup Types
+
+// MarshalXML implements the xml.Marshaler interface. Nothing is written
+// for definitions without types.
+func (t *Types) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(t.Schemas) == 0 && len(t.Extensions) == 0 {
+		return nil
+	}
+	return e.EncodeElement((*typesDup)(t), start)
+}
+
+// ExtensibilityElement is an element unknown to the package, such as a
+// vendor extension of WSDL, kept as raw XML to be marshaled back as it
+// was. Its inner XML may use the namespace prefixes declared by the
+// definitions.
+type ExtensibilityElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// MarshalXML implements the xml.Marshaler interface. The namespace
+// declarations among the attributes are written with their prefixes,
+// as are the attributes in those namespaces.
+func (ext *ExtensibilityElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = ext.XMLName
+	prefixes := make(map[string]string)
+	for _, attr := range ext.Attrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+	for _, attr := range ext.Attrs {
+		switch prefix, ok := prefixes[attr.Name.Space]; {
+		case attr.Name.Space == "xmlns":
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		case attr.Name == xml.Name{Local: "xmlns"}:
+			continue // declared by the encoder
+		case ok:
+			attr.Name = xml.Name{Local: prefix + ":" + attr.Name.Local}
+		}
+		start.Attr = append(start.Attr, attr)
 	}
-	return d.DecodeElement((*definitionDup)(def), &start)
+	return e.EncodeElement(struct {
+		InnerXML string `xml:",innerxml"`
+	}{ext.InnerXML}, start)
 }
 
 // Service defines a WSDL service and with a location, like an HTTP server.
 type Service struct {
-	Doc   string  `xml:"documentation"`
-	Ports []*Port `xml:"port"`
+	Name  string        `xml:"name,attr"`
+	Doc   Documentation `xml:"documentation"`
+	Ports []*Port       `xml:"port"`
+}
+
+// DefaultService returns the service with a port bound to the binding
+// returned by DefaultBinding, or else the first service. It returns an
+// empty service if the definitions have none.
+func (def *Definitions) DefaultService() *Service {
+	b := def.DefaultBinding()
+	for _, s := range def.Services {
+		for _, p := range s.Ports {
+			if b.Name != "" && p.Binding[strings.LastIndex(p.Binding, ":")+1:] == b.Name {
+				return s
+			}
+		}
+	}
+	if len(def.Services) > 0 {
+		return def.Services[0]
+	}
+	return &Service{}
 }
 
 // Port for WSDL service.
 type Port struct {
-	XMLName xml.Name `xml:"port"`
-	Name    string   `xml:"name,attr"`
-	Binding string   `xml:"binding,attr"`
-	Address Address  `xml:"address"`
+	XMLName xml.Name      `xml:"port"`
+	Name    string        `xml:"name,attr"`
+	Binding string        `xml:"binding,attr"`
+	Doc     Documentation `xml:"documentation"`
+	Address Address       `xml:"address"`
+
+	Extensions []*ExtensibilityElement `xml:",any"`
 }
 
-// Address of WSDL service.
+// Address of WSDL service. Its XMLName is that of the soap:address,
+// soap12:address or http:address element.
 type Address struct {
-	XMLName  xml.Name `xml:"address"`
-	Location string   `xml:"location,attr"`
+	XMLName  xml.Name
+	Location string `xml:"location,attr"`
+}
+
+// SOAPVersion returns the version of SOAP of the address, "1.1" or
+// "1.2", or "" if it's not a SOAP address.
+func (a *Address) SOAPVersion() string {
+	return soapVersion(a.XMLName.Space)
 }
 
 // Schema of WSDL document.
 type Schema struct {
 	XMLName         xml.Name          `xml:"schema"`
-	TargetNamespace string            `xml:"targetNamespace,attr"`
+	TargetNamespace string            `xml:"targetNamespace,attr,omitempty"`
+	Version         string            `xml:"version,attr,omitempty"`
 	Namespaces      map[string]string `xml:"-"`
-	Imports         []*ImportSchema   `xml:"import"`
-	Includes        []*IncludeSchema  `xml:"include"`
-	SimpleTypes     []*SimpleType     `xml:"simpleType"`
-	ComplexTypes    []*ComplexType    `xml:"complexType"`
-	Elements        []*Element        `xml:"element"`
+
+	// whether local elements and attributes are qualified or unqualified
+	ElementFormDefault   string `xml:"elementFormDefault,attr,omitempty"`
+	AttributeFormDefault string `xml:"attributeFormDefault,attr,omitempty"`
+
+	Imports      []*ImportSchema   `xml:"import"`
+	Includes     []*IncludeSchema  `xml:"include"`
+	Redefines    []*RedefineSchema `xml:"redefine"`
+	SimpleTypes  []*SimpleType     `xml:"simpleType"`
+	ComplexTypes []*ComplexType    `xml:"complexType"`
+	Elements     []*Element        `xml:"element"`
+
+	// namespace prefixes in scope of the schema element, declared by
+	// the definitions it's embedded in
+	parent map[string]string
 }
 
 // Unmarshaling solution from Matt Harden (http://grokbase.com/t/gg/golang-nuts/14bk21xb7a/go-nuts-extending-encoding-xml-to-capture-unknown-attributes)
@@ -76,39 +254,183 @@ type schemaDup Schema
 
 // UnmarshalXML implements the xml.Unmarshaler interface.
 func (schema *Schema) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	for _, attr := range start.Attr {
-		if attr.Name.Space == "xmlns" {
-			if schema.Namespaces == nil {
-				schema.Namespaces = make(map[string]string)
+	schema.Namespaces = namespaces(schema.Namespaces, start.Attr)
+	return d.DecodeElement((*schemaDup)(schema), &start)
+}
+
+// Resolve returns the namespace and local name of qname, a qualified
+// name used within the schema, such as tns:Order, using the namespace
+// prefixes declared by the schema element, or else by the definitions
+// it's embedded in. The namespace is "" if the prefix isn't declared.
+func (schema *Schema) Resolve(qname string) (space, local string) {
+	space, local, _ = resolve(qname, schema.Namespaces, schema.parent)
+	return space, local
+}
+
+// MarshalXML implements the xml.Marshaler interface. The namespace
+// prefixes of the schema are declared again, and the default namespace
+// is that of XML Schema.
+func (schema *Schema) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: schema.XMLName.Space, Local: "schema"}
+	if start.Name.Space == "" {
+		start.Name.Space = XMLSchemaNamespace
+	}
+	start.Attr = append(start.Attr, namespaceAttrs(schema.Namespaces)...)
+	return e.EncodeElement((*schemaDup)(schema), start)
+}
+
+// namespaces adds the namespace declarations in attrs to m, keyed by
+// prefix. The default namespace is keyed by the empty string.
+func namespaces(m map[string]string, attrs []xml.Attr) map[string]string {
+	for _, attr := range attrs {
+		var prefix string
+		switch {
+		case attr.Name.Space == "xmlns":
+			prefix = attr.Name.Local
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			prefix = ""
+		default:
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[prefix] = attr.Value
+	}
+	return m
+}
+
+// namespaceAttrs returns the declarations of the namespaces in m keyed
+// by prefix, sorted by prefix. The default namespace is left out, as
+// it's that of the element declaring them.
+func namespaceAttrs(m map[string]string) []xml.Attr {
+	prefixes := make([]string, 0, len(m))
+	for prefix := range m {
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	attrs := make([]xml.Attr, len(prefixes))
+	for i, prefix := range prefixes {
+		attrs[i] = xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: m[prefix]}
+	}
+	return attrs
+}
+
+// Documentation is the text of documentation elements, which may be
+// given in several languages using the xml:lang attribute.
+type Documentation []*DocText
+
+// DocText is the text of a documentation element.
+type DocText struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// In returns the documentation in the given language, such as "en" or
+// "pt-BR". Languages match regardless of case, or else by their primary
+// subtag, so "en" matches "en-US" and the other way around. If there's
+// no match, the documentation without language is returned, or else
+// the first one.
+func (doc Documentation) In(lang string) string {
+	if len(doc) == 0 {
+		return ""
+	}
+	if lang != "" {
+		for _, t := range doc {
+			if strings.EqualFold(t.Lang, lang) {
+				return t.Text
+			}
+		}
+		for _, t := range doc {
+			if strings.EqualFold(primaryLang(t.Lang), primaryLang(lang)) {
+				return t.Text
 			}
-			schema.Namespaces[attr.Name.Local] = attr.Value
 		}
 	}
-	return d.DecodeElement((*schemaDup)(schema), &start)
+	for _, t := range doc {
+		if t.Lang == "" {
+			return t.Text
+		}
+	}
+	return doc[0].Text
+}
+
+// String returns the documentation without language, or the first one.
+func (doc Documentation) String() string {
+	return doc.In("")
+}
+
+func primaryLang(lang string) string {
+	return strings.SplitN(lang, "-", 2)[0]
+}
+
+// annotation is the annotation element of schema components, written
+// with their documentation.
+type annotation struct {
+	Doc Documentation `xml:"documentation"`
+}
+
+// newAnnotation returns the annotation with doc, or nil if there's no
+// documentation.
+func newAnnotation(doc Documentation) *annotation {
+	if len(doc) == 0 {
+		return nil
+	}
+	return &annotation{doc}
 }
 
 // SimpleType describes a simple type, such as string.
 type SimpleType struct {
 	XMLName         xml.Name     `xml:"simpleType"`
-	Name            string       `xml:"name,attr"`
+	Name            string       `xml:"name,attr,omitempty"`
 	Union           *Union       `xml:"union"`
 	Restriction     *Restriction `xml:"restriction"`
-	TargetNamespace string
+	TargetNamespace string       `xml:"-"`
+	Pos             Pos          `xml:"-"`
+}
+
+type simpleTypeDup SimpleType
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (st *SimpleType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	st.Pos = position(d)
+	return d.DecodeElement((*simpleTypeDup)(st), &start)
 }
 
 // Union is a mix of multiple types in a union.
 type Union struct {
 	XMLName     xml.Name `xml:"union"`
-	MemberTypes string   `xml:"memberTypes,attr"`
+	MemberTypes string   `xml:"memberTypes,attr,omitempty"`
 }
 
 // Restriction describes the WSDL type of the simple type and
 // optionally its allowed values.
 type Restriction struct {
-	XMLName    xml.Name     `xml:"restriction"`
-	Base       string       `xml:"base,attr"`
-	Enum       []*Enum      `xml:"enumeration"`
-	Attributes []*Attribute `xml:"attribute"`
+	XMLName        xml.Name     `xml:"restriction"`
+	Base           string       `xml:"base,attr,omitempty"`
+	Enum           []*Enum      `xml:"enumeration"`
+	Patterns       []*Facet     `xml:"pattern"` // values match any of them
+	Length         *Facet       `xml:"length"`
+	MinLength      *Facet       `xml:"minLength"`
+	MaxLength      *Facet       `xml:"maxLength"`
+	WhiteSpace     *Facet       `xml:"whiteSpace"`
+	TotalDigits    *Facet       `xml:"totalDigits"`
+	FractionDigits *Facet       `xml:"fractionDigits"`
+	MinInclusive   *Facet       `xml:"minInclusive"`
+	MaxInclusive   *Facet       `xml:"maxInclusive"`
+	MinExclusive   *Facet       `xml:"minExclusive"`
+	MaxExclusive   *Facet       `xml:"maxExclusive"`
+	Attributes     []*Attribute `xml:"attribute"`
+}
+
+// Facet is a constraining facet of a Restriction other than its
+// enumeration, such as its length or bounds, with the value given in
+// the schema.
+type Facet struct {
+	Value string `xml:"value,attr"`
+	Fixed bool   `xml:"fixed,attr,omitempty"` // whether restrictions may change it
 }
 
 // Enum describes one possible value for a Restriction.
@@ -120,16 +442,42 @@ type Enum struct {
 // ComplexType describes a complex type, such as a struct.
 type ComplexType struct {
 	XMLName         xml.Name        `xml:"complexType"`
-	Name            string          `xml:"name,attr"`
-	Abstract        bool            `xml:"abstract,attr"`
-	Doc             string          `xml:"annotation>documentation"`
+	Name            string          `xml:"name,attr,omitempty"`
+	Abstract        bool            `xml:"abstract,attr,omitempty"`
+	Doc             Documentation   `xml:"annotation>documentation"`
 	AllElements     []*Element      `xml:"all>element"`
 	ComplexContent  *ComplexContent `xml:"complexContent"`
 	SimpleContent   *SimpleContent  `xml:"simpleContent"`
 	Sequence        *Sequence       `xml:"sequence"`
 	Choice          *Choice         `xml:"choice"`
 	Attributes      []*Attribute    `xml:"attribute"`
-	TargetNamespace string
+	TargetNamespace string          `xml:"-"`
+	Pos             Pos             `xml:"-"`
+}
+
+type complexTypeDup ComplexType
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (ct *ComplexType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	ct.Pos = position(d)
+	return d.DecodeElement((*complexTypeDup)(ct), &start)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (ct *ComplexType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := struct {
+		Annotation *annotation `xml:"annotation"`
+		All        *struct {
+			Elements []*Element `xml:"element"`
+		} `xml:"all"`
+		*complexTypeDup
+	}{Annotation: newAnnotation(ct.Doc), complexTypeDup: (*complexTypeDup)(ct)}
+	if len(ct.AllElements) > 0 {
+		v.All = &struct {
+			Elements []*Element `xml:"element"`
+		}{ct.AllElements}
+	}
+	return e.EncodeElement(v, start)
 }
 
 // SimpleContent describes simple content within a complex type.
@@ -159,49 +507,303 @@ type Extension struct {
 // Sequence describes a list of elements (parameters) of a type.
 type Sequence struct {
 	XMLName      xml.Name       `xml:"sequence"`
+	Min          int            `xml:"minOccurs,attr"`
+	Max          string         `xml:"maxOccurs,attr"` // can be # or unbounded
 	ComplexTypes []*ComplexType `xml:"complexType"`
 	Elements     []*Element     `xml:"element"`
 	Any          []*AnyElement  `xml:"any"`
 	Choices      []*Choice      `xml:"choice"`
+	Sequences    []*Sequence    `xml:"sequence"` // repeated ones only
+
+	// Position is the number of elements of the parent sequence that
+	// come before this one, when repeated
+	Position int `xml:"-"`
+
+	minGiven bool // whether minOccurs was given, as it's 1 if not
+}
+
+// repeated reports whether max, the maxOccurs of a compositor, allows
+// more than one occurrence.
+func repeated(max string) bool {
+	return max != "" && max != "0" && max != "1"
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. Sequences
+// nested in s are flattened into it, their content in place of them,
+// unless repeated.
+func (s *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	s.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			s.Min, _ = strconv.Atoi(attr.Value)
+			s.minGiven = true
+		case "maxOccurs":
+			s.Max = attr.Value
+		}
+	}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "element":
+				el := new(Element)
+				err = d.DecodeElement(el, &t)
+				s.Elements = append(s.Elements, el)
+			case "any":
+				any := new(AnyElement)
+				err = d.DecodeElement(any, &t)
+				s.Any = append(s.Any, any)
+			case "choice":
+				choice := new(Choice)
+				err = d.DecodeElement(choice, &t)
+				s.Choices = append(s.Choices, choice)
+			case "complexType":
+				ct := new(ComplexType)
+				err = d.DecodeElement(ct, &t)
+				s.ComplexTypes = append(s.ComplexTypes, ct)
+			case "sequence":
+				var seq Sequence
+				err = d.DecodeElement(&seq, &t)
+				if repeated(seq.Max) {
+					seq.Position = len(s.Elements)
+					s.Sequences = append(s.Sequences, &seq)
+					break
+				}
+				for _, nested := range seq.Sequences {
+					nested.Position += len(s.Elements)
+				}
+				s.Elements = append(s.Elements, seq.Elements...)
+				s.Any = append(s.Any, seq.Any...)
+				s.Choices = append(s.Choices, seq.Choices...)
+				s.ComplexTypes = append(s.ComplexTypes, seq.ComplexTypes...)
+				s.Sequences = append(s.Sequences, seq.Sequences...)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalXML implements the xml.Marshaler interface. Repeated nested
+// sequences are written back in their position among the elements.
+func (s *Sequence) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, occursAttrs(s.Min, s.Max, s.minGiven)...)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	nested := s.Sequences
+	for i, el := range s.Elements {
+		for len(nested) > 0 && nested[0].Position <= i {
+			if err := encodeNamed(e, nested[0], "sequence"); err != nil {
+				return err
+			}
+			nested = nested[1:]
+		}
+		if err := encodeNamed(e, el, "element"); err != nil {
+			return err
+		}
+	}
+	for _, v := range []struct {
+		v    interface{}
+		name string
+	}{
+		{nested, "sequence"},
+		{s.Choices, "choice"},
+		{s.Any, "any"},
+		{s.ComplexTypes, "complexType"},
+	} {
+		if err := encodeNamed(e, v.v, v.name); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeNamed encodes v, or each element of v if it's a slice, as an
+// element of the given local name.
+func encodeNamed(e *xml.Encoder, v interface{}, name string) error {
+	return e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// occursAttrs returns the minOccurs and maxOccurs attributes of a
+// particle, leaving out those not given. The minOccurs of 0 is only
+// written if it was given, as it's 1 if absent.
+func occursAttrs(min int, max string, minGiven bool) []xml.Attr {
+	var attrs []xml.Attr
+	if min != 0 || minGiven {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "minOccurs"}, Value: strconv.Itoa(min)})
+	}
+	if max != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "maxOccurs"}, Value: max})
+	}
+	return attrs
+}
+
+// hasAttr reports whether attrs has one of the given local name.
+func hasAttr(attrs []xml.Attr, local string) bool {
+	for _, attr := range attrs {
+		if attr.Name.Local == local {
+			return true
+		}
+	}
+	return false
 }
 
 // Choice describes a list of elements (parameters) of a type.
 type Choice struct {
 	XMLName      xml.Name       `xml:"choice"`
+	Min          int            `xml:"minOccurs,attr,omitempty"`
+	Max          string         `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
 	ComplexTypes []*ComplexType `xml:"complexType"`
 	Elements     []*Element     `xml:"element"`
 	Any          []*AnyElement  `xml:"any"`
+
+	minGiven bool // whether minOccurs was given, as it's 1 if not
+}
+
+type choiceDup Choice
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (c *Choice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	c.minGiven = hasAttr(start.Attr, "minOccurs")
+	return d.DecodeElement((*choiceDup)(c), &start)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (c *Choice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if c.Min == 0 && c.minGiven {
+		start.Attr = append(start.Attr, occursAttrs(0, "", true)...)
+	}
+	return e.EncodeElement((*choiceDup)(c), start)
 }
 
 // Attribute describes an attribute of a given type.
 type Attribute struct {
-	XMLName   xml.Name `xml:"attribute"`
-	Name      string   `xml:"name,attr"`
-	Ref       string   `xml:"ref,attr"`
-	Type      string   `xml:"type,attr"`
-	ArrayType string   `xml:"arrayType,attr"`
-	Min       int      `xml:"minOccurs,attr"`
-	Max       string   `xml:"maxOccurs,attr"` // can be # or unbounded
-	Nillable  bool     `xml:"nillable,attr"`
+	XMLName   xml.Name      `xml:"attribute"`
+	Name      string        `xml:"name,attr,omitempty"`
+	Ref       string        `xml:"ref,attr,omitempty"`
+	Type      string        `xml:"type,attr,omitempty"`
+	ArrayType string        `xml:"arrayType,attr,omitempty"`
+	Min       int           `xml:"minOccurs,attr,omitempty"`
+	Max       string        `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+	Nillable  bool          `xml:"nillable,attr,omitempty"`
+	Use       string        `xml:"use,attr,omitempty"` // required, optional or prohibited
+	Default   string        `xml:"default,attr,omitempty"`
+	Fixed     string        `xml:"fixed,attr,omitempty"` // the only value allowed
+	Doc       Documentation `xml:"annotation>documentation"`
+
+	// the anonymous type of the attribute, if it has no type
+	SimpleType *SimpleType `xml:"simpleType"`
+}
+
+type attributeDup Attribute
+
+// MarshalXML implements the xml.Marshaler interface.
+func (attr *Attribute) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Annotation *annotation `xml:"annotation"`
+		*attributeDup
+	}{newAnnotation(attr.Doc), (*attributeDup)(attr)}, start)
 }
 
 // Element describes an element of a given type.
 type Element struct {
-	XMLName     xml.Name     `xml:"element"`
-	Name        string       `xml:"name,attr"`
-	Ref         string       `xml:"ref,attr"`
-	Type        string       `xml:"type,attr"`
-	Min         int          `xml:"minOccurs,attr"`
-	Max         string       `xml:"maxOccurs,attr"` // can be # or unbounded
-	Nillable    bool         `xml:"nillable,attr"`
-	ComplexType *ComplexType `xml:"complexType"`
+	XMLName     xml.Name      `xml:"element"`
+	Name        string        `xml:"name,attr,omitempty"`
+	Ref         string        `xml:"ref,attr,omitempty"`
+	Type        string        `xml:"type,attr,omitempty"`
+	Min         int           `xml:"minOccurs,attr,omitempty"`
+	Max         string        `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+	Nillable    bool          `xml:"nillable,attr,omitempty"`
+	Default     string        `xml:"default,attr,omitempty"`
+	Doc         Documentation `xml:"annotation>documentation"`
+	ComplexType *ComplexType  `xml:"complexType"`
+	SimpleType  *SimpleType   `xml:"simpleType"` // anonymous, if it has no type
+
+	// Elements of the substitution group of the global element named
+	// SubstitutionGroup may appear in its place. Abstract elements only
+	// appear substituted.
+	SubstitutionGroup string `xml:"substitutionGroup,attr,omitempty"`
+	Abstract          bool   `xml:"abstract,attr,omitempty"`
+
+	// identity constraints of the content of the element
+	Keys    []*IdentityConstraint `xml:"key"`
+	KeyRefs []*IdentityConstraint `xml:"keyref"`
+	Uniques []*IdentityConstraint `xml:"unique"`
+
+	minGiven bool // whether minOccurs was given, as it's 1 if not
+}
+
+type elementDup Element
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (el *Element) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	el.minGiven = hasAttr(start.Attr, "minOccurs")
+	return d.DecodeElement((*elementDup)(el), &start)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (el *Element) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if el.Min == 0 && el.minGiven {
+		start.Attr = append(start.Attr, occursAttrs(0, "", true)...)
+	}
+	return e.EncodeElement(struct {
+		Annotation *annotation `xml:"annotation"`
+		*elementDup
+	}{newAnnotation(el.Doc), (*elementDup)(el)}, start)
+}
+
+// IdentityConstraint is a key, keyref or unique constraint of an
+// element: the values of its fields, in each node selected within the
+// element, are unique, or else refer to the values of the key or
+// unique constraint named by Refer.
+type IdentityConstraint struct {
+	XMLName  xml.Name
+	Name     string          `xml:"name,attr"`
+	Refer    string          `xml:"refer,attr,omitempty"` // of keyrefs
+	Selector *IdentityPath   `xml:"selector"`
+	Fields   []*IdentityPath `xml:"field"`
+}
+
+// IdentityPath is the selector or a field of an IdentityConstraint, a
+// restricted XPath expression.
+type IdentityPath struct {
+	XPath string `xml:"xpath,attr"`
 }
 
 // AnyElement describes an element of an undefined type.
 type AnyElement struct {
 	XMLName xml.Name `xml:"any"`
-	Min     int      `xml:"minOccurs,attr"`
-	Max     string   `xml:"maxOccurs,attr"` // can be # or unbounded
+	Min     int      `xml:"minOccurs,attr,omitempty"`
+	Max     string   `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+
+	minGiven bool // whether minOccurs was given, as it's 1 if not
+}
+
+type anyElementDup AnyElement
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (any *AnyElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	any.minGiven = hasAttr(start.Attr, "minOccurs")
+	return d.DecodeElement((*anyElementDup)(any), &start)
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (any *AnyElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if any.Min == 0 && any.minGiven {
+		start.Attr = append(start.Attr, occursAttrs(0, "", true)...)
+	}
+	return e.EncodeElement((*anyElementDup)(any), start)
 }
 
 // Import points to another WSDL to be imported at root level.
@@ -214,31 +816,56 @@ type Import struct {
 // ImportSchema points to another WSDL to be imported at schema level.
 type ImportSchema struct {
 	XMLName   xml.Name `xml:"import"`
-	Namespace string   `xml:"namespace,attr"`
-	Location  string   `xml:"schemaLocation,attr"`
+	Namespace string   `xml:"namespace,attr,omitempty"`
+	Location  string   `xml:"schemaLocation,attr,omitempty"`
 }
 
 // IncludeSchema points to another WSDL to be imported at schema level.
 type IncludeSchema struct {
 	XMLName   xml.Name `xml:"include"`
-	Namespace string   `xml:"namespace,attr"`
+	Namespace string   `xml:"namespace,attr,omitempty"`
 	Location  string   `xml:"schemaLocation,attr"`
 }
 
+// RedefineSchema includes another schema at schema level, overriding
+// some of its types. Redefined types usually extend or restrict the
+// types they override, referring to them by their own name.
+type RedefineSchema struct {
+	XMLName      xml.Name       `xml:"redefine"`
+	Location     string         `xml:"schemaLocation,attr"`
+	SimpleTypes  []*SimpleType  `xml:"simpleType"`
+	ComplexTypes []*ComplexType `xml:"complexType"`
+}
+
 // Message describes the data being communicated, such as functions
 // and their parameters.
 type Message struct {
-	XMLName xml.Name `xml:"message"`
-	Name    string   `xml:"name,attr"`
-	Parts   []*Part  `xml:"part"`
+	XMLName xml.Name      `xml:"message"`
+	Name    string        `xml:"name,attr"`
+	Doc     Documentation `xml:"documentation"`
+	Parts   []*Part       `xml:"part"`
+}
+
+// Message returns the message referred to by name, as in the message
+// attribute of the input, output and faults of operations, with or
+// without a namespace prefix, or nil if it's not defined.
+func (def *Definitions) Message(name string) *Message {
+	name = name[strings.LastIndex(name, ":")+1:]
+	for _, m := range def.Messages {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
 }
 
 // Part describes what Type or Element to use from the PortType.
 type Part struct {
-	XMLName xml.Name `xml:"part"`
-	Name    string   `xml:"name,attr"`
-	Type    string   `xml:"type,attr,omitempty"`
-	Element string   `xml:"element,attr,omitempty"` // TODO: not sure omitempty
+	XMLName xml.Name      `xml:"part"`
+	Name    string        `xml:"name,attr"`
+	Type    string        `xml:"type,attr,omitempty"`
+	Element string        `xml:"element,attr,omitempty"` // TODO: not sure omitempty
+	Doc     Documentation `xml:"documentation"`
 }
 
 // PortType describes a set of operations.
@@ -248,35 +875,320 @@ type PortType struct {
 	Operations []*Operation `xml:"operation"`
 }
 
+// DefaultPortType returns the port type of the binding returned by
+// DefaultBinding, or else the first port type. It returns an empty port
+// type if the definitions have none.
+func (def *Definitions) DefaultPortType() *PortType {
+	if pt := def.portType(def.DefaultBinding().Type); pt != nil {
+		return pt
+	}
+	if len(def.PortTypes) > 0 {
+		return def.PortTypes[0]
+	}
+	return &PortType{}
+}
+
+// portType returns the port type referred to by name, as in the type
+// attribute of bindings, with or without a namespace prefix, or nil if
+// it's not defined.
+func (def *Definitions) portType(name string) *PortType {
+	name = name[strings.LastIndex(name, ":")+1:]
+	for _, pt := range def.PortTypes {
+		if pt.Name == name {
+			return pt
+		}
+	}
+	return nil
+}
+
 // Operation describes an operation.
 type Operation struct {
-	XMLName xml.Name `xml:"operation"`
-	Name    string   `xml:"name,attr"`
-	Doc     string   `xml:"documentation"`
-	Input   *IO      `xml:"input"`
-	Output  *IO      `xml:"output"`
+	XMLName xml.Name      `xml:"operation"`
+	Name    string        `xml:"name,attr"`
+	Doc     Documentation `xml:"documentation"`
+	Input   *IO           `xml:"input"`
+	Output  *IO           `xml:"output"`
+	Faults  []*IO         `xml:"fault"`
 }
 
 // IO describes which message is linked to an operation, for input
-// or output parameters.
+// or output parameters, or for faults.
 type IO struct {
 	XMLName xml.Name
+	Name    string `xml:"name,attr,omitempty"` // required for faults
 	Message string `xml:"message,attr"`
+	Action  string `xml:"Action,attr,omitempty"` // WS-Addressing action (wsam or wsaw)
+	Pos     Pos    `xml:"-"`
+}
+
+type ioDup IO
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (o *IO) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	o.Pos = position(d)
+	return d.DecodeElement((*ioDup)(o), &start)
 }
 
 // Binding describes SOAP to WSDL binding.
 type Binding struct {
-	XMLName     xml.Name            `xml:"binding"`
-	Name        string              `xml:"name,attr"`
-	Type        string              `xml:"type,attr"`
-	BindingType *BindingType        `xml:"binding"`
-	Operations  []*BindingOperation `xml:"operation"`
+	XMLName          xml.Name           `xml:"binding"`
+	Name             string             `xml:"name,attr"`
+	Type             string             `xml:"type,attr"`
+	Doc              Documentation      `xml:"documentation"`
+	BindingType      *BindingType       `xml:"binding"`
+	UsingAddressing  *UsingAddressing   `xml:"UsingAddressing"`
+	Policies         []*Policy          `xml:"Policy"`
+	PolicyReferences []*PolicyReference `xml:"PolicyReference"`
+
+	// extensibility elements other than those above, written before
+	// the operations
+	Extensions []*ExtensibilityElement `xml:",any"`
+
+	Operations []*BindingOperation `xml:"operation"`
+	Pos        Pos                 `xml:"-"`
+}
+
+type bindingDup Binding
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (b *Binding) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	b.Pos = position(d)
+	return d.DecodeElement((*bindingDup)(b), &start)
+}
+
+// DefaultBinding returns the binding that code is generated for, as
+// only one is used: a SOAP binding rather than an HTTP one, and of SOAP
+// bindings, one over HTTP rather than other transports. Of those, the
+// last one is returned. It returns an empty binding if the definitions
+// have none.
+func (def *Definitions) DefaultBinding() *Binding {
+	var b *Binding
+	for _, next := range def.Bindings {
+		if b != nil {
+			if prev := b.BindingType; prev != nil && prev.Verb == "" {
+				bt := next.BindingType
+				if bt == nil || bt.Verb != "" || (prev.HTTPTransport() && !bt.HTTPTransport()) {
+					continue
+				}
+			}
+		}
+		b = next
+	}
+	if b == nil {
+		return &Binding{}
+	}
+	return b
+}
+
+// UsingAddressing marks a binding as using WS-Addressing. Its XMLName
+// is that of the wsaw:UsingAddressing element.
+type UsingAddressing struct {
+	XMLName  xml.Name
+	Required bool `xml:"required,attr,omitempty"`
+}
+
+// Policy is a WS-Policy expression, such as the security requirements
+// of a binding.
+type Policy struct {
+	XMLName    xml.Name // of the wsp:Policy element
+	ID         string
+	Name       string
+	Addressing bool // Whether WS-Addressing is asserted
+
+	// The elements of the policy: assertions such as
+	// sp:SymmetricBinding, and operators such as wsp:ExactlyOne.
+	Assertions []*PolicyAssertion
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (p *Policy) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "Id":
+			p.ID = attr.Value
+		case "Name":
+			p.Name = attr.Value
+		}
+	}
+	var err error
+	if p.Assertions, err = policyAssertions(d); err != nil {
+		return err
+	}
+	p.Addressing = p.Asserts("Addressing") || p.Asserts("UsingAddressing")
+	return nil
+}
+
+// Namespaces of WS-Policy 1.5, and of the wsu:Id attribute of policies.
+const (
+	PolicyNamespace  = "http://www.w3.org/ns/ws-policy"
+	UtilityNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+)
+
+// MarshalXML implements the xml.Marshaler interface. Policies without
+// XMLName are written in the namespace of WS-Policy 1.5.
+func (p *Policy) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = p.XMLName
+	if start.Name.Local == "" {
+		start.Name = xml.Name{Space: PolicyNamespace, Local: "Policy"}
+	}
+	if p.ID != "" {
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:wsu"}, Value: UtilityNamespace},
+			xml.Attr{Name: xml.Name{Local: "wsu:Id"}, Value: p.ID},
+		)
+	}
+	if p.Name != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "Name"}, Value: p.Name})
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodePolicyAssertions(e, p.Assertions); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Asserts reports whether the policy has an assertion or operator of
+// the given local name, such as "AsymmetricBinding", at any depth.
+func (p *Policy) Asserts(name string) bool {
+	return asserts(p.Assertions, name)
+}
+
+// PolicyAssertion is an element of a Policy, with its attributes and
+// the elements it contains, such as those of its nested policy.
+type PolicyAssertion struct {
+	Name       xml.Name
+	Attrs      []xml.Attr
+	Assertions []*PolicyAssertion
+}
+
+// policyAssertions decodes the elements of d up to the end of the
+// element being decoded.
+func policyAssertions(d *xml.Decoder) ([]*PolicyAssertion, error) {
+	var assertions []*PolicyAssertion
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			a := &PolicyAssertion{Name: t.Name, Attrs: t.Attr}
+			if a.Assertions, err = policyAssertions(d); err != nil {
+				return nil, err
+			}
+			assertions = append(assertions, a)
+		case xml.EndElement:
+			return assertions, nil
+		}
+	}
+}
+
+// encodePolicyAssertions encodes assertions to e, with their attributes
+// other than namespace declarations, as the encoder declares them.
+func encodePolicyAssertions(e *xml.Encoder, assertions []*PolicyAssertion) error {
+	for _, a := range assertions {
+		start := xml.StartElement{Name: a.Name}
+		for _, attr := range a.Attrs {
+			if attr.Name.Space != "xmlns" && attr.Name != (xml.Name{Local: "xmlns"}) {
+				start.Attr = append(start.Attr, attr)
+			}
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := encodePolicyAssertions(e, a.Assertions); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(start.End()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func asserts(assertions []*PolicyAssertion, name string) bool {
+	for _, a := range assertions {
+		if a.Name.Local == name || asserts(a.Assertions, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyReference refers to a Policy by URI, such as #id. Its XMLName
+// is that of the wsp:PolicyReference element.
+type PolicyReference struct {
+	XMLName xml.Name
+	URI     string `xml:"URI,attr"`
+}
+
+// Policy returns the policy of the definitions referred to by uri, as
+// in PolicyReference, or nil if it's not defined.
+func (def *Definitions) Policy(uri string) *Policy {
+	for _, p := range def.Policies {
+		if uri == "#"+p.ID || (p.Name != "" && uri == p.Name) {
+			return p
+		}
+	}
+	return nil
 }
 
 // BindingType contains additional meta data on how to implement the binding.
+// Its XMLName is that of the soap:binding, soap12:binding or http:binding
+// element.
 type BindingType struct {
-	Style     string `xml:"style,attr"`
-	Transport string `xml:"transport,attr"`
+	XMLName   xml.Name
+	Style     string `xml:"style,attr,omitempty"`
+	Transport string `xml:"transport,attr,omitempty"`
+	Verb      string `xml:"verb,attr,omitempty"` // HTTP method of HTTP bindings
+}
+
+// SOAPVersion returns the version of SOAP of the binding, "1.1" or "1.2",
+// or "" if it's not a SOAP binding.
+func (bt *BindingType) SOAPVersion() string {
+	return soapVersion(bt.XMLName.Space)
+}
+
+// Namespaces of the extensions of WSDL for SOAP 1.1, SOAP 1.2 and HTTP
+// bindings.
+const (
+	SOAPBindingNamespace   = "http://schemas.xmlsoap.org/wsdl/soap/"
+	SOAP12BindingNamespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	HTTPBindingNamespace   = "http://schemas.xmlsoap.org/wsdl/http/"
+)
+
+// soapVersion returns the version of SOAP of the binding extensions of
+// namespace space, or "" if they're not of SOAP.
+func soapVersion(space string) string {
+	switch space {
+	case SOAPBindingNamespace:
+		return "1.1"
+	case SOAP12BindingNamespace:
+		return "1.2"
+	}
+	return ""
+}
+
+// Transports of SOAP 1.1 and 1.2 bindings over HTTP.
+const (
+	SOAPHTTPTransport   = "http://schemas.xmlsoap.org/soap/http"
+	SOAP12HTTPTransport = "http://www.w3.org/2003/05/soap/bindings/HTTP/"
+)
+
+// HTTPTransport reports whether the binding is over HTTP: an HTTP
+// binding, or a SOAP binding with the HTTP transport. SOAP bindings
+// that don't declare their transport are assumed to be over HTTP.
+func (bt *BindingType) HTTPTransport() bool {
+	if bt.Verb != "" {
+		return true
+	}
+	t := strings.TrimSuffix(strings.TrimSpace(bt.Transport), "/")
+	return t == "" ||
+		t == SOAPHTTPTransport ||
+		t == strings.TrimSuffix(SOAP12HTTPTransport, "/")
 }
 
 // BindingOperation describes the requirement for binding SOAP to WSDL
@@ -288,6 +1200,73 @@ type BindingOperation struct {
 	Operation11 SOAP11Operation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
 	Input       *BindingIO      `xml:"input>body"`
 	Output      *BindingIO      `xml:"output>body"`
+	InputMIME   *MIMEMultipart  `xml:"input>multipartRelated"`
+	OutputMIME  *MIMEMultipart  `xml:"output>multipartRelated"`
+
+	InputHeaders  []*BindingHeader `xml:"input>header"`
+	OutputHeaders []*BindingHeader `xml:"output>header"`
+
+	// WS-Policy attachments of the operation, and of its input and
+	// output messages
+	Policies               []*Policy          `xml:"Policy"`
+	PolicyReferences       []*PolicyReference `xml:"PolicyReference"`
+	InputPolicyReferences  []*PolicyReference `xml:"input>PolicyReference"`
+	OutputPolicyReferences []*PolicyReference `xml:"output>PolicyReference"`
+
+	// HTTP bindings: the location of the operation, and whether its
+	// parts replace their names in it rather than being URL encoded
+	HTTPOperation  *HTTPOperation `xml:"http://schemas.xmlsoap.org/wsdl/http/ operation"`
+	URLReplacement *struct{}      `xml:"input>urlReplacement"`
+}
+
+// bindingMessage is the input or output element of a BindingOperation.
+type bindingMessage struct {
+	Body             *BindingIO         `xml:"body"`
+	MIME             *MIMEMultipart     `xml:"multipartRelated"`
+	Headers          []*BindingHeader   `xml:"header"`
+	PolicyReferences []*PolicyReference `xml:"PolicyReference"`
+	URLReplacement   *struct{}          `xml:"http://schemas.xmlsoap.org/wsdl/http/ urlReplacement"`
+}
+
+// MarshalXML implements the xml.Marshaler interface. The operation of
+// SOAP 1.1 or 1.2 is only written if given, and the input and output
+// only if there's something in them.
+func (op *BindingOperation) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := struct {
+		Name             string             `xml:"name,attr"`
+		Operation        *SOAP12Operation   `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+		Operation11      *SOAP11Operation   `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+		HTTPOperation    *HTTPOperation     `xml:"http://schemas.xmlsoap.org/wsdl/http/ operation"`
+		Policies         []*Policy          `xml:"Policy"`
+		PolicyReferences []*PolicyReference `xml:"PolicyReference"`
+		Input            *bindingMessage    `xml:"input"`
+		Output           *bindingMessage    `xml:"output"`
+	}{
+		Name:             op.Name,
+		HTTPOperation:    op.HTTPOperation,
+		Policies:         op.Policies,
+		PolicyReferences: op.PolicyReferences,
+	}
+	if op.Operation != (SOAP12Operation{}) {
+		v.Operation = &op.Operation
+	}
+	if op.Operation11 != (SOAP11Operation{}) {
+		v.Operation11 = &op.Operation11
+	}
+	input := bindingMessage{op.Input, op.InputMIME, op.InputHeaders, op.InputPolicyReferences, op.URLReplacement}
+	if input.Body != nil || input.MIME != nil || len(input.Headers) > 0 || len(input.PolicyReferences) > 0 || input.URLReplacement != nil {
+		v.Input = &input
+	}
+	output := bindingMessage{op.Output, op.OutputMIME, op.OutputHeaders, op.OutputPolicyReferences, nil}
+	if output.Body != nil || output.MIME != nil || len(output.Headers) > 0 || len(output.PolicyReferences) > 0 {
+		v.Output = &output
+	}
+	return e.EncodeElement(v, start)
+}
+
+// HTTPOperation describes an operation of an HTTP binding.
+type HTTPOperation struct {
+	Location string `xml:"location,attr"`
 }
 
 // SOAP12Operation describes a SOAP 1.2 operation. The soap12 namespace is
@@ -296,18 +1275,73 @@ type BindingOperation struct {
 // (application/xml; charset=UTF-8; action='foobar')
 type SOAP12Operation struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
-	Action  string   `xml:"soapAction,attr"`
+	Action  string   `xml:"soapAction,attr,omitempty"`
+	Style   string   `xml:"style,attr,omitempty"`
 }
 
 // SOAP11Operation describes a SOAP 1.1 operation.  If it is specified in the wsdl,
 // the soapAction will use this value instead of the default value
 type SOAP11Operation struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
-	Action  string   `xml:"soapAction,attr"`
+	Action  string   `xml:"soapAction,attr,omitempty"`
+	Style   string   `xml:"style,attr,omitempty"`
 }
 
 // BindingIO describes the IO binding of SOAP operations. See IO for details.
+// Its XMLName is that of the soap:body or soap12:body element.
 type BindingIO struct {
-	Parts string `xml:"parts,attr"`
-	Use   string `xml:"use,attr"`
+	XMLName       xml.Name
+	Parts         string `xml:"parts,attr,omitempty"`
+	Use           string `xml:"use,attr,omitempty"`
+	Namespace     string `xml:"namespace,attr,omitempty"`
+	EncodingStyle string `xml:"encodingStyle,attr,omitempty"`
+}
+
+// BindingHeader describes a message part sent in the SOAP header of
+// operation input or output, rather than in its body. Its XMLName is
+// that of the soap:header or soap12:header element.
+type BindingHeader struct {
+	XMLName       xml.Name
+	Message       string                `xml:"message,attr"`
+	Part          string                `xml:"part,attr"`
+	Use           string                `xml:"use,attr,omitempty"`
+	Namespace     string                `xml:"namespace,attr,omitempty"`
+	EncodingStyle string                `xml:"encodingStyle,attr,omitempty"`
+	Faults        []*BindingHeaderFault `xml:"headerfault"`
+}
+
+// BindingHeaderFault describes a message part sent in the SOAP header
+// of a fault caused by the processing of a BindingHeader. Its XMLName is
+// that of the soap:headerfault or soap12:headerfault element.
+type BindingHeaderFault struct {
+	XMLName       xml.Name
+	Message       string `xml:"message,attr"`
+	Part          string `xml:"part,attr"`
+	Use           string `xml:"use,attr,omitempty"`
+	Namespace     string `xml:"namespace,attr,omitempty"`
+	EncodingStyle string `xml:"encodingStyle,attr,omitempty"`
+}
+
+// MIMEMultipart describes the MIME binding of operation input or output,
+// where the SOAP envelope and attachments are sent in separate parts of
+// a multipart/related message. Its XMLName, and those of its parts and
+// their contents, are in the namespace of the MIME binding.
+type MIMEMultipart struct {
+	XMLName xml.Name
+	Parts   []*MIMEPart `xml:"part"`
+}
+
+// MIMEPart is one part of a multipart/related message, which contains
+// either the SOAP body or the content of message parts.
+type MIMEPart struct {
+	XMLName  xml.Name
+	Body     *BindingIO     `xml:"body"`
+	Contents []*MIMEContent `xml:"content"`
+}
+
+// MIMEContent binds a message part to a MIME part of the given type.
+type MIMEContent struct {
+	XMLName xml.Name
+	Part    string `xml:"part,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
 }
@@ -0,0 +1,530 @@
+package wsdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problem is a structural problem of definitions found by Validate, such
+// as a reference to a message that isn't defined.
+type Problem struct {
+	Pos     Pos // of the element with the problem, if known
+	Message string
+}
+
+// String returns the problem as pos: message, or the message if the
+// position isn't known.
+func (p Problem) String() string {
+	if p.Pos.IsValid() {
+		return p.Pos.String() + ": " + p.Message
+	}
+	return p.Message
+}
+
+// Validate checks the referential integrity of the definitions: that the
+// elements and types referred to by schemas and messages, the messages
+// of operations, the port types of bindings and the bindings of ports
+// are defined, and that names aren't defined twice. Of imports and
+// includes, it only flags those without a location, except imports in
+// schemas of namespaces the definitions have or that are built in; the
+// locations aren't followed. It returns the problems found, in the order
+// of the document.
+//
+// Names in namespaces of documents imported or included with their
+// location aren't checked, as Unmarshal doesn't read those.
+func (def *Definitions) Validate() []Problem {
+	v := &validator{
+		def:      def,
+		local:    make(map[string][]*Schema),
+		external: make(map[string]bool),
+	}
+	v.imports()
+	for _, s := range def.Types.Schemas {
+		v.schema(s)
+	}
+	v.messages()
+	v.portTypes()
+	v.bindings()
+	v.services()
+	return v.problems
+}
+
+// builtinNamespaces are the namespaces whose types and elements are
+// known without importing them, such as those of XML Schema.
+var builtinNamespaces = map[string]bool{
+	XMLSchemaNamespace:                          true,
+	"http://www.w3.org/2000/10/XMLSchema":       true,
+	"http://www.w3.org/1999/XMLSchema":          true,
+	"http://schemas.xmlsoap.org/soap/encoding/": true,
+	"http://www.w3.org/2003/05/soap-encoding":   true,
+	WSDLNamespace:                               true,
+	xmlNamespace:                                true,
+}
+
+// validator finds the problems of definitions.
+type validator struct {
+	def      *Definitions
+	local    map[string][]*Schema // schemas of the definitions, by namespace
+	external map[string]bool      // namespaces of documents not read
+	problems []Problem
+}
+
+func (v *validator) report(pos Pos, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// imports checks the imports of the definitions and their schemas, and
+// records the namespaces defined in the definitions or elsewhere.
+func (v *validator) imports() {
+	for _, imp := range v.def.Imports {
+		if imp.Location == "" {
+			v.report(Pos{}, "import of namespace %q has no location", imp.Namespace)
+			continue
+		}
+		v.external[imp.Namespace] = true
+	}
+	for _, s := range v.def.Types.Schemas {
+		v.local[s.TargetNamespace] = append(v.local[s.TargetNamespace], s)
+		for _, imp := range s.Imports {
+			if imp.Location != "" {
+				v.external[imp.Namespace] = true
+			}
+		}
+		if len(s.Includes) > 0 || len(s.Redefines) > 0 {
+			v.external[s.TargetNamespace] = true
+		}
+		for _, inc := range s.Includes {
+			if inc.Location == "" {
+				v.report(Pos{}, "include in schema of namespace %q has no schemaLocation", s.TargetNamespace)
+			}
+		}
+	}
+	for _, s := range v.def.Types.Schemas {
+		for _, imp := range s.Imports {
+			ns := imp.Namespace
+			if imp.Location == "" && v.local[ns] == nil && !v.external[ns] && !builtinNamespaces[ns] {
+				v.report(Pos{}, "import of namespace %q has no schemaLocation, and no schema of the definitions has that target namespace", ns)
+			}
+		}
+	}
+}
+
+// resolve returns the namespace and local name of qname, using the
+// namespace prefixes declared in scopes, innermost first, and whether
+// its prefix is declared.
+func resolve(qname string, scopes ...map[string]string) (space, local string, ok bool) {
+	prefix := ""
+	local = qname
+	if i := strings.Index(qname, ":"); i >= 0 {
+		prefix, local = qname[:i], qname[i+1:]
+	}
+	if prefix == "xml" {
+		return xmlNamespace, local, true
+	}
+	for _, scope := range scopes {
+		if space, ok = scope[prefix]; ok {
+			return space, local, true
+		}
+	}
+	return "", local, prefix == ""
+}
+
+// checkRef reports what, which refers to the qname of kind, such as
+// type, if it isn't defined by the schemas of the definitions.
+func (v *validator) checkRef(pos Pos, what, kind, qname string, scopes []map[string]string) {
+	if qname == "" {
+		return
+	}
+	space, local, ok := resolve(qname, scopes...)
+	if !ok {
+		v.report(pos, "%s refers to %s %q, whose prefix isn't declared", what, kind, qname)
+		return
+	}
+	if builtinNamespaces[space] || v.external[space] || v.defines(space, kind, local) {
+		return
+	}
+	for _, s := range v.def.Types.Schemas {
+		if v.defines(s.TargetNamespace, kind, local) {
+			v.report(pos, "%s refers to %s %q of namespace %q, which is defined in namespace %q", what, kind, qname, space, s.TargetNamespace)
+			return
+		}
+	}
+	if v.local[space] == nil {
+		v.report(pos, "%s refers to %s %q of namespace %q, which isn't imported", what, kind, qname, space)
+		return
+	}
+	v.report(pos, "%s refers to %s %q, which isn't defined", what, kind, qname)
+}
+
+// defines reports whether the schemas of namespace space define the
+// type or element of the given name.
+func (v *validator) defines(space, kind, name string) bool {
+	for _, s := range v.local[space] {
+		if kind == "element" {
+			for _, el := range s.Elements {
+				if el.Name == name {
+					return true
+				}
+			}
+			continue
+		}
+		for _, st := range s.SimpleTypes {
+			if st.Name == name {
+				return true
+			}
+		}
+		for _, ct := range s.ComplexTypes {
+			if ct.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schema checks the names defined by s, and those it refers to.
+func (v *validator) schema(s *Schema) {
+	scopes := []map[string]string{s.Namespaces, v.def.Namespaces}
+	types := make(map[string]bool)
+	for _, others := range v.local[s.TargetNamespace] {
+		if others == s {
+			break
+		}
+		for _, st := range others.SimpleTypes {
+			types[st.Name] = true
+		}
+		for _, ct := range others.ComplexTypes {
+			types[ct.Name] = true
+		}
+	}
+	for _, st := range s.SimpleTypes {
+		if types[st.Name] {
+			v.report(st.Pos, "type %q of namespace %q is defined twice", st.Name, s.TargetNamespace)
+		}
+		types[st.Name] = true
+		v.simpleType(st, st.Pos, fmt.Sprintf("type %q", st.Name), scopes)
+	}
+	for _, ct := range s.ComplexTypes {
+		if types[ct.Name] {
+			v.report(ct.Pos, "type %q of namespace %q is defined twice", ct.Name, s.TargetNamespace)
+		}
+		types[ct.Name] = true
+		v.complexType(ct, fmt.Sprintf("type %q", ct.Name), scopes)
+	}
+	elements := make(map[string]bool)
+	for _, others := range v.local[s.TargetNamespace] {
+		if others == s {
+			break
+		}
+		for _, el := range others.Elements {
+			elements[el.Name] = true
+		}
+	}
+	for _, el := range s.Elements {
+		if elements[el.Name] {
+			v.report(Pos{}, "element %q of namespace %q is defined twice", el.Name, s.TargetNamespace)
+		}
+		elements[el.Name] = true
+		v.element(el, Pos{}, "", scopes)
+	}
+}
+
+// simpleType checks the types that st, within what, refers to.
+func (v *validator) simpleType(st *SimpleType, pos Pos, what string, scopes []map[string]string) {
+	if st.Restriction != nil {
+		v.checkRef(pos, what, "type", st.Restriction.Base, scopes)
+	}
+	if st.Union != nil {
+		for _, member := range strings.Fields(st.Union.MemberTypes) {
+			v.checkRef(pos, what, "type", member, scopes)
+		}
+	}
+}
+
+// complexType checks the elements and types that ct, within what,
+// refers to.
+func (v *validator) complexType(ct *ComplexType, what string, scopes []map[string]string) {
+	pos := ct.Pos
+	for _, el := range ct.AllElements {
+		v.element(el, pos, what, scopes)
+	}
+	var exts []*Extension
+	var restrictions []*Restriction
+	if cc := ct.ComplexContent; cc != nil {
+		exts, restrictions = append(exts, cc.Extension), append(restrictions, cc.Restriction)
+	}
+	if sc := ct.SimpleContent; sc != nil {
+		exts, restrictions = append(exts, sc.Extension), append(restrictions, sc.Restriction)
+	}
+	for _, ext := range exts {
+		if ext != nil {
+			v.checkRef(pos, what, "type", ext.Base, scopes)
+			v.sequence(ext.Sequence, pos, what, scopes)
+			v.choice(ext.Choice, pos, what, scopes)
+			v.attributes(ext.Attributes, pos, what, scopes)
+		}
+	}
+	for _, r := range restrictions {
+		if r != nil {
+			v.checkRef(pos, what, "type", r.Base, scopes)
+			v.attributes(r.Attributes, pos, what, scopes)
+		}
+	}
+	v.sequence(ct.Sequence, pos, what, scopes)
+	v.choice(ct.Choice, pos, what, scopes)
+	v.attributes(ct.Attributes, pos, what, scopes)
+}
+
+func (v *validator) sequence(seq *Sequence, pos Pos, what string, scopes []map[string]string) {
+	if seq == nil {
+		return
+	}
+	for _, el := range seq.Elements {
+		v.element(el, pos, what, scopes)
+	}
+	for _, choice := range seq.Choices {
+		v.choice(choice, pos, what, scopes)
+	}
+	for _, nested := range seq.Sequences {
+		v.sequence(nested, pos, what, scopes)
+	}
+	for _, ct := range seq.ComplexTypes {
+		v.complexType(ct, what, scopes)
+	}
+}
+
+func (v *validator) choice(choice *Choice, pos Pos, what string, scopes []map[string]string) {
+	if choice == nil {
+		return
+	}
+	for _, el := range choice.Elements {
+		v.element(el, pos, what, scopes)
+	}
+	for _, ct := range choice.ComplexTypes {
+		v.complexType(ct, what, scopes)
+	}
+}
+
+func (v *validator) attributes(attrs []*Attribute, pos Pos, what string, scopes []map[string]string) {
+	for _, attr := range attrs {
+		name := fmt.Sprintf("attribute %q of %s", attr.Name, what)
+		v.checkRef(pos, name, "type", attr.Type, scopes)
+		if attr.SimpleType != nil {
+			v.simpleType(attr.SimpleType, pos, name, scopes)
+		}
+	}
+}
+
+// element checks the elements and types that el, within what if it's
+// a local element, refers to.
+func (v *validator) element(el *Element, pos Pos, what string, scopes []map[string]string) {
+	name := fmt.Sprintf("element %q", el.Name)
+	if el.Name == "" {
+		name = fmt.Sprintf("element reference %q", el.Ref)
+	}
+	if what != "" {
+		name += " of " + what
+	}
+	v.checkRef(pos, name, "element", el.Ref, scopes)
+	v.checkRef(pos, name, "element", el.SubstitutionGroup, scopes)
+	v.checkRef(pos, name, "type", el.Type, scopes)
+	if el.ComplexType != nil {
+		v.complexType(el.ComplexType, name, scopes)
+	}
+	if el.SimpleType != nil {
+		v.simpleType(el.SimpleType, el.SimpleType.Pos, name, scopes)
+	}
+}
+
+// messages checks the names of the messages, and the elements and
+// types their parts refer to.
+func (v *validator) messages() {
+	scopes := []map[string]string{v.def.Namespaces, v.def.Schema.Namespaces}
+	names := make(map[string]bool)
+	for _, m := range v.def.Messages {
+		if names[m.Name] {
+			v.report(Pos{}, "message %q is defined twice", m.Name)
+		}
+		names[m.Name] = true
+		parts := make(map[string]bool)
+		for _, part := range m.Parts {
+			what := fmt.Sprintf("part %q of message %q", part.Name, m.Name)
+			if parts[part.Name] {
+				v.report(Pos{}, "%s is defined twice", what)
+			}
+			parts[part.Name] = true
+			if part.Element == "" && part.Type == "" {
+				v.report(Pos{}, "%s has neither element nor type", what)
+			}
+			v.checkRef(Pos{}, what, "element", part.Element, scopes)
+			v.checkRef(Pos{}, what, "type", part.Type, scopes)
+		}
+	}
+}
+
+// message returns the message referred to by qname, and whether it
+// may be defined by a document imported, rather than the definitions.
+func (v *validator) message(qname string) (m *Message, imported bool) {
+	space, _, _ := resolve(qname, v.def.Namespaces, v.def.Schema.Namespaces)
+	if v.external[space] {
+		return nil, true
+	}
+	return v.def.Message(qname), false
+}
+
+// portTypes checks the names of the port types and of their
+// operations, and the messages these refer to.
+func (v *validator) portTypes() {
+	names := make(map[string]bool)
+	for _, pt := range v.def.PortTypes {
+		if names[pt.Name] {
+			v.report(Pos{}, "port type %q is defined twice", pt.Name)
+		}
+		names[pt.Name] = true
+		v.portType(pt)
+	}
+}
+
+// portType checks the names of the operations of pt, and the messages
+// they refer to. Operations may be overloaded, with inputs of different
+// names.
+func (v *validator) portType(pt *PortType) {
+	names := make(map[[2]string]bool)
+	for _, op := range pt.Operations {
+		name := [2]string{op.Name, ""}
+		if op.Input != nil {
+			name[1] = op.Input.Name
+		}
+		if names[name] {
+			v.report(Pos{}, "operation %q of port type %q is defined twice", op.Name, pt.Name)
+		}
+		names[name] = true
+		ios := []*IO{op.Input, op.Output}
+		for _, io := range append(ios, op.Faults...) {
+			if io == nil {
+				continue
+			}
+			what := fmt.Sprintf("%s of operation %q", io.XMLName.Local, op.Name)
+			if io.XMLName.Local == "fault" {
+				if io.Name == "" {
+					v.report(io.Pos, "fault of operation %q has no name", op.Name)
+				}
+				what = fmt.Sprintf("fault %q of operation %q", io.Name, op.Name)
+			}
+			if io.Message == "" {
+				v.report(io.Pos, "%s has no message", what)
+				continue
+			}
+			if m, imported := v.message(io.Message); m == nil && !imported {
+				v.report(io.Pos, "%s refers to message %q, which isn't defined", what, io.Message)
+			}
+		}
+	}
+}
+
+// bindings checks the names of the bindings, and each of them.
+func (v *validator) bindings() {
+	names := make(map[string]bool)
+	for _, b := range v.def.Bindings {
+		if names[b.Name] {
+			v.report(b.Pos, "binding %q is defined twice", b.Name)
+		}
+		names[b.Name] = true
+		v.binding(b)
+	}
+}
+
+// binding checks that the port type of b is defined and has its
+// operations, and the messages of its headers.
+func (v *validator) binding(b *Binding) {
+	space, _, ok := resolve(b.Type, v.def.Namespaces, v.def.Schema.Namespaces)
+	pt := v.def.portType(b.Type)
+	switch {
+	case !ok:
+		v.report(b.Pos, "binding %q refers to port type %q, whose prefix isn't declared", b.Name, b.Type)
+		return
+	case v.external[space]:
+		return
+	case pt == nil:
+		v.report(b.Pos, "binding %q refers to port type %q, which isn't defined", b.Name, b.Type)
+		return
+	}
+	ops := make(map[string]int) // overloads by name
+	for _, op := range pt.Operations {
+		ops[op.Name]++
+	}
+	names := make(map[string]int)
+	for _, op := range b.Operations {
+		names[op.Name]++
+		switch n := ops[op.Name]; {
+		case n == 0:
+			v.report(b.Pos, "operation %q of binding %q isn't in port type %q", op.Name, b.Name, pt.Name)
+		case names[op.Name] > n:
+			v.report(b.Pos, "operation %q of binding %q is defined twice", op.Name, b.Name)
+		}
+		for _, h := range append(append([]*BindingHeader{}, op.InputHeaders...), op.OutputHeaders...) {
+			m, imported := v.message(h.Message)
+			switch {
+			case imported:
+			case m == nil:
+				v.report(b.Pos, "header of operation %q of binding %q refers to message %q, which isn't defined", op.Name, b.Name, h.Message)
+			case !m.hasPart(h.Part):
+				v.report(b.Pos, "header of operation %q of binding %q refers to part %q, which isn't in message %q", op.Name, b.Name, h.Part, h.Message)
+			}
+		}
+	}
+}
+
+func (m *Message) hasPart(name string) bool {
+	for _, part := range m.Parts {
+		if part.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// services checks the names of the services, and the names, bindings
+// and addresses of their ports.
+func (v *validator) services() {
+	services := make(map[string]bool)
+	for _, s := range v.def.Services {
+		if services[s.Name] {
+			v.report(Pos{}, "service %q is defined twice", s.Name)
+		}
+		services[s.Name] = true
+		names := make(map[string]bool)
+		for _, port := range s.Ports {
+			if names[port.Name] {
+				v.report(Pos{}, "port %q of service %q is defined twice", port.Name, s.Name)
+			}
+			names[port.Name] = true
+			v.portBinding(s, port)
+			if port.Address.Location == "" {
+				v.report(Pos{}, "port %q of service %q has no address", port.Name, s.Name)
+			}
+		}
+	}
+}
+
+// portBinding checks that the binding of port is defined.
+func (v *validator) portBinding(s *Service, port *Port) {
+	if port.Binding == "" {
+		v.report(Pos{}, "port %q of service %q has no binding", port.Name, s.Name)
+		return
+	}
+	space, local, ok := resolve(port.Binding, v.def.Namespaces, v.def.Schema.Namespaces)
+	switch {
+	case !ok:
+		v.report(Pos{}, "port %q of service %q refers to binding %q, whose prefix isn't declared", port.Name, s.Name, port.Binding)
+		return
+	case v.external[space]:
+		return
+	}
+	for _, b := range v.def.Bindings {
+		if b.Name == local {
+			return
+		}
+	}
+	v.report(Pos{}, "port %q of service %q refers to binding %q, which isn't defined", port.Name, s.Name, port.Binding)
+}
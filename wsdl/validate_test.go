@@ -0,0 +1,174 @@
+package wsdl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	const header = `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema"
+  xmlns:tns="urn:t" targetNamespace="urn:t">`
+	for _, tc := range []struct {
+		Name string
+		Src  string
+		Want []string
+	}{
+		{
+			Name: "valid",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:t">
+      <xs:complexType name="Item"><xs:sequence><xs:element name="ID" type="xs:int"/></xs:sequence></xs:complexType>
+      <xs:element name="Get"><xs:complexType><xs:sequence><xs:element name="Item" type="tns:Item"/></xs:sequence></xs:complexType></xs:element>
+    </xs:schema>
+  </types>
+  <message name="GetRequest"><part name="parameters" element="tns:Get"/></message>
+  <portType name="P"><operation name="Get"><input message="tns:GetRequest"/></operation></portType>
+  <binding name="B" type="tns:P"><operation name="Get"/></binding>
+  <service name="S"><port name="SP" binding="tns:B"><soap:address location="http://localhost"/></port></service>`,
+		},
+		{
+			Name: "missing references",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:t">
+      <xs:element name="Get" type="tns:Missing"/>
+    </xs:schema>
+  </types>
+  <message name="GetRequest"><part name="parameters" element="tns:Got"/></message>
+  <portType name="P"><operation name="Get"><input message="tns:GotRequest"/></operation></portType>
+  <binding name="B" type="tns:Q"/>`,
+			Want: []string{
+				`element "Get" refers to type "tns:Missing", which isn't defined`,
+				`part "parameters" of message "GetRequest" refers to element "tns:Got", which isn't defined`,
+				`input of operation "Get" refers to message "tns:GotRequest", which isn't defined`,
+				`binding "B" refers to port type "tns:Q", which isn't defined`,
+			},
+		},
+		{
+			Name: "several bindings",
+			Src: `<message name="M"/>
+  <portType name="PA"><operation name="Get"><input message="tns:M"/></operation></portType>
+  <portType name="PB"><operation name="Put"><input message="tns:M"/></operation></portType>
+  <binding name="B11" type="tns:PA"><operation name="Get"/></binding>
+  <binding name="B12" type="tns:PB"><operation name="Put"/><operation name="Get"/></binding>
+  <service name="S">
+    <port name="SP11" binding="tns:B11"><soap:address location="http://localhost/11"/></port>
+    <port name="SP12" binding="tns:B12"><soap:address location="http://localhost/12"/></port>
+    <port name="SP" binding="tns:Missing"><soap:address location="http://localhost"/></port>
+  </service>`,
+			Want: []string{
+				`operation "Get" of binding "B12" isn't in port type "PB"`,
+				`port "SP" of service "S" refers to binding "tns:Missing", which isn't defined`,
+			},
+		},
+		{
+			Name: "namespaces",
+			Src: `<types>
+    <xs:schema>
+      <xs:element name="Get" type="xs:string"/>
+    </xs:schema>
+  </types>
+  <message name="GetRequest">
+    <part name="a" element="tns:Get"/>
+    <part name="b" type="other:T"/>
+    <part name="c" type="xs:string"/>
+  </message>`,
+			Want: []string{
+				`part "a" of message "GetRequest" refers to element "tns:Get" of namespace "urn:t", which is defined in namespace ""`,
+				`part "b" of message "GetRequest" refers to type "other:T", whose prefix isn't declared`,
+			},
+		},
+		{
+			Name: "duplicates",
+			Src: `<types>
+    <xs:schema targetNamespace="urn:t">
+      <xs:simpleType name="T"><xs:restriction base="xs:string"/></xs:simpleType>
+      <xs:complexType name="T"/>
+    </xs:schema>
+  </types>
+  <message name="M"><part name="a" type="tns:T"/><part name="a" type="tns:T"/></message>
+  <message name="M"><part name="a" type="tns:T"/></message>
+  <portType name="P">
+    <operation name="Get"><input name="ByID" message="tns:M"/></operation>
+    <operation name="Get"><input name="ByName" message="tns:M"/></operation>
+    <operation name="Put"><input message="tns:M"/></operation>
+    <operation name="Put"><input message="tns:M"/></operation>
+  </portType>
+  <binding name="B" type="tns:P">
+    <operation name="Get"/><operation name="Get"/><operation name="Get"/><operation name="Delete"/>
+  </binding>
+  <portType name="P"/>
+  <binding name="B" type="tns:P"/>
+  <service name="S">
+    <port name="SP" binding="tns:B"><soap:address location="http://localhost"/></port>
+    <port name="SP" binding="tns:B"/>
+  </service>
+  <service name="S"/>`,
+			Want: []string{
+				`type "T" of namespace "urn:t" is defined twice`,
+				`part "a" of message "M" is defined twice`,
+				`message "M" is defined twice`,
+				`operation "Put" of port type "P" is defined twice`,
+				`port type "P" is defined twice`,
+				`operation "Get" of binding "B" is defined twice`,
+				`operation "Delete" of binding "B" isn't in port type "P"`,
+				`binding "B" is defined twice`,
+				`port "SP" of service "S" is defined twice`,
+				`port "SP" of service "S" has no address`,
+				`service "S" is defined twice`,
+			},
+		},
+		{
+			Name: "imports",
+			Src: `<import namespace="urn:a"/>
+  <import namespace="urn:b" location="b.wsdl"/>
+  <types>
+    <xs:schema targetNamespace="urn:t" xmlns:b="urn:b" xmlns:c="urn:c" xmlns:d="urn:d">
+      <xs:import namespace="urn:c"/>
+      <xs:import namespace="urn:d" schemaLocation="d.xsd"/>
+      <xs:import namespace="http://schemas.xmlsoap.org/soap/encoding/"/>
+      <xs:element name="C" type="c:T"/>
+      <xs:element name="D" type="d:T"/>
+    </xs:schema>
+  </types>
+  <message name="M"><part name="b" element="b:E"/></message>`,
+			Want: []string{
+				`import of namespace "urn:a" has no location`,
+				`import of namespace "urn:c" has no schemaLocation, and no schema of the definitions has that target namespace`,
+				`element "C" refers to type "c:T" of namespace "urn:c", which isn't imported`,
+			},
+		},
+	} {
+		d, err := Unmarshal(strings.NewReader(header + tc.Src + `</definitions>`))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.Name, err)
+		}
+		var have []string
+		for _, p := range d.Validate() {
+			have = append(have, p.Message)
+		}
+		if !reflect.DeepEqual(have, tc.Want) {
+			t.Errorf("%s: want problems\n%s\nhave\n%s", tc.Name, strings.Join(tc.Want, "\n"), strings.Join(have, "\n"))
+		}
+	}
+}
+
+func TestValidatePositions(t *testing.T) {
+	d, err := UnmarshalFrom(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:tns="urn:t">
+  <binding name="B" type="tns:P"/>
+</definitions>`), "orders.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := d.Validate()
+	if len(problems) != 1 {
+		t.Fatalf("want 1 problem, have %v", problems)
+	}
+	want := `orders.wsdl:3:3: binding "B" refers to port type "tns:P", which isn't defined`
+	if s := problems[0].String(); s != want {
+		t.Errorf("want %q, have %q", want, s)
+	}
+}
@@ -0,0 +1,102 @@
+package wsdlgo
+
+import (
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// anonymousSequence returns the sequence of elements of the anonymous
+// type ct of an element, or of its choice if it has no sequence.
+func anonymousSequence(ct *wsdl.ComplexType) *wsdl.Sequence {
+	if ct.Sequence == nil && ct.Choice != nil {
+		return &wsdl.Sequence{
+			ComplexTypes: ct.Choice.ComplexTypes,
+			Elements:     ct.Choice.Elements,
+			Any:          ct.Choice.Any}
+	}
+	return ct.Sequence
+}
+
+// anonymousSimpleTypeBase returns the base type of the restriction of
+// the anonymous simple type st of an element or attribute, as the type
+// of its field, or "" if st isn't a restriction.
+func anonymousSimpleTypeBase(st *wsdl.SimpleType) string {
+	if st == nil || st.Restriction == nil {
+		return ""
+	}
+	return st.Restriction.Base
+}
+
+// hoistAnonymousTypes declares the anonymous types of the elements of
+// the complex types cached, at any depth, as types named after their
+// parent type and element, e.g. Order_Customer, so they're generated as
+// structs. Anonymous types that only wrap a single element, or any
+// content, are generated as the element they wrap, see elementField.
+func (ge *goEncoder) hoistAnonymousTypes() {
+	for _, name := range ge.sortedComplexTypes() {
+		ge.hoistElementTypes(name, ge.ctypes[name])
+	}
+}
+
+// hoistElementTypes declares the anonymous types of the elements of ct,
+// the type cached as parent, and of their elements in turn.
+func (ge *goEncoder) hoistElementTypes(parent string, ct *wsdl.ComplexType) {
+	for _, el := range complexTypeElements(ct) {
+		act := el.ComplexType
+		if el.Type != "" || act == nil {
+			continue
+		}
+		if seq := anonymousSequence(act); seq != nil {
+			if len(seq.Elements) == 1 {
+				// the wrapped element is generated in place of el
+				act = seq.Elements[0].ComplexType
+				if seq.Elements[0].Type != "" || act == nil {
+					continue
+				}
+			} else if len(seq.Any) == 1 && len(seq.Elements) == 0 {
+				continue
+			}
+		}
+		if _, exists := ge.anonTypes[act]; exists {
+			continue
+		}
+		name := ge.fixNameConflicts(ge.goSymbol(parent)+"_"+ge.goSymbol(el.Name), "Type")
+		hoisted := *act
+		hoisted.Name = name
+		hoisted.TargetNamespace = ct.TargetNamespace
+		ge.declareAs(&hoisted, ct)
+		ge.ctypes[name] = &hoisted
+		ge.anonTypes[act] = name
+		ge.typeSymbols = nil
+		ge.hoistElementTypes(name, &hoisted)
+	}
+}
+
+// complexTypeElements returns the elements of ct generated as fields of
+// its struct, in its content, choices or extension.
+func complexTypeElements(ct *wsdl.ComplexType) []*wsdl.Element {
+	var elements []*wsdl.Element
+	elements = append(elements, ct.AllElements...)
+	sequence := func(seq *wsdl.Sequence) {
+		if seq == nil {
+			return
+		}
+		elements = append(elements, seq.Elements...)
+		for _, choice := range seq.Choices {
+			elements = append(elements, choice.Elements...)
+		}
+		for _, s := range seq.Sequences {
+			elements = append(elements, sequenceGroup(s).elements...)
+		}
+	}
+	sequence(ct.Sequence)
+	if ct.Choice != nil {
+		elements = append(elements, ct.Choice.Elements...)
+	}
+	if cc := ct.ComplexContent; cc != nil && cc.Extension != nil {
+		sequence(cc.Extension.Sequence)
+		if cc.Extension.Choice != nil {
+			elements = append(elements, cc.Extension.Choice.Elements...)
+		}
+	}
+	return elements
+}
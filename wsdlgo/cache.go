@@ -0,0 +1,74 @@
+package wsdlgo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores downloaded documents in a directory, so they're read
+// from there instead of downloaded again, e.g. on later runs.
+type Cache struct {
+	Dir string        // Directory of the documents, created if needed
+	TTL time.Duration // How long documents are reused, or forever if 0
+}
+
+// Get returns the document at the URL loc from the cache, or else
+// downloads it with client and stores it in the cache. Only documents
+// downloaded with status 200 OK are stored.
+func (c *Cache) Get(client *http.Client, loc string) ([]byte, error) {
+	name := filepath.Join(c.Dir, cacheKey(loc))
+	if fi, err := os.Stat(name); err == nil && (c.TTL <= 0 || time.Since(fi.ModTime()) < c.TTL) {
+		return ioutil.ReadFile(name)
+	}
+	resp, err := client.Get(loc)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return data, nil
+	}
+	if err = c.put(name, data); err != nil {
+		return nil, fmt.Errorf("cache %s: %v", loc, err)
+	}
+	return data, nil
+}
+
+// put writes data to the file name atomically, since the same
+// document may be downloaded concurrently.
+func (c *Cache) put(name string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.Dir, ".download")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// cacheKey returns the name of the file of the document at loc.
+func cacheKey(loc string) string {
+	sum := sha256.Sum256([]byte(loc))
+	return hex.EncodeToString(sum[:]) + ".xml"
+}
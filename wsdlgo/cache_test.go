@@ -0,0 +1,60 @@
+package wsdlgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var requests int
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "wsdl2go-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &Cache{Dir: filepath.Join(dir, "cache")}
+
+	get := func(path, want string, wantRequests int) {
+		t.Helper()
+		data, err := c.Get(s.Client(), s.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("%s: want %q, have %q", path, want, data)
+		}
+		if requests != wantRequests {
+			t.Fatalf("%s: want %d requests, have %d", path, wantRequests, requests)
+		}
+	}
+	get("/a.xsd", "/a.xsd", 1)
+	get("/a.xsd", "/a.xsd", 1)
+	get("/b.xsd", "/b.xsd", 2)
+
+	// expired
+	c.TTL = time.Hour
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(c.Dir, cacheKey(s.URL+"/a.xsd")), old, old); err != nil {
+		t.Fatal(err)
+	}
+	get("/a.xsd", "/a.xsd", 3)
+	get("/a.xsd", "/a.xsd", 3)
+
+	// errors aren't cached
+	status = http.StatusNotFound
+	get("/c.xsd", "/c.xsd", 4)
+	get("/c.xsd", "/c.xsd", 5)
+}
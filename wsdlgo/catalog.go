@@ -0,0 +1,71 @@
+package wsdlgo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Catalog maps the namespaces and locations of documents imported by
+// WSDL documents and schemas to local files, which are read instead of
+// downloading the documents.
+type Catalog map[string]string
+
+// LoadCatalog reads a catalog from the file name.
+//
+// Each line of the file maps a namespace or location to a file, which
+// is relative to the catalog unless absolute, separated by white space.
+// Empty lines and lines starting with # are ignored:
+//
+//	# namespace or location               file
+//	http://example.com/stockquote.xsd     schemas/stockquote.xsd
+//	https://example.com/common/types.xsd  schemas/types.xsd
+func LoadCatalog(name string) (Catalog, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	return readCatalog(f, dir, name)
+}
+
+// readCatalog reads a catalog from r, with files relative to dir.
+func readCatalog(r io.Reader, dir, name string) (Catalog, error) {
+	c := make(Catalog)
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		f := strings.Fields(text)
+		if len(f) != 2 {
+			return nil, fmt.Errorf("%s:%d: want namespace or location and file, have %q", name, line, text)
+		}
+		file := f[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		c[f[0]] = file
+	}
+	return c, s.Err()
+}
+
+// lookup returns the file of the document at loc, or in the namespace
+// ns if there's none for loc, and whether there's one.
+func (c Catalog) lookup(loc, ns string) (string, bool) {
+	if file, ok := c[loc]; ok && loc != "" {
+		return file, true
+	}
+	if file, ok := c[ns]; ok && ns != "" {
+		return file, true
+	}
+	return "", false
+}
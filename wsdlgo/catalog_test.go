@@ -0,0 +1,67 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	c, err := LoadCatalog(filepath.Join("testdata", "catalog.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := filepath.Abs(filepath.Join("testdata", "localimport.xsd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c["http://example.invalid/stockquote.xsd"] != want {
+		t.Fatalf("unexpected catalog: %v", c)
+	}
+	_, err = readCatalog(strings.NewReader("\n# ok\nbad line here\n"), "", "bad.txt")
+	if err == nil || !strings.Contains(err.Error(), "bad.txt:3:") {
+		t.Fatalf("want error at bad.txt:3, have %v", err)
+	}
+}
+
+func TestEncoderCatalog(t *testing.T) {
+	file, err := filepath.Abs(filepath.Join("testdata", "localimport.xsd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byLocation, err := LoadCatalog(filepath.Join("testdata", "catalog.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "localimport.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]Catalog{
+		"location":  byLocation,
+		"namespace": {"http://example.com/stockquote.xsd": file},
+	} {
+		d := LoadDefinition(t, "catalog.wsdl", nil)
+		var have bytes.Buffer
+		enc := NewEncoder(&have)
+		enc.SetClient(&http.Client{Transport: failTransport{t}})
+		enc.SetCatalog(c)
+		if err := enc.Encode(d); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(have.Bytes(), want) {
+			t.Errorf("%s: localimport.golden mismatch:\n%s", name, have.Bytes())
+		}
+	}
+}
+
+// failTransport fails tests that make HTTP requests.
+type failTransport struct{ t *testing.T }
+
+func (f failTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.t.Errorf("unexpected request: %s", r.URL)
+	return nil, http.ErrNotSupported
+}
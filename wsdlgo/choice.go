@@ -0,0 +1,153 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+var choiceT = template.Must(template.New("choice").Parse(`
+// {{.Name}} is the choice of elements of {{.Parent}}, of which only one
+// may be set.
+type {{.Name}} struct {
+{{.Fields}}}
+
+// Which returns the name of the element of the choice that is set, or
+// an empty string if none is.
+func (c {{.Name}}) Which() string {
+	switch {
+{{- range .Members}}
+	case {{.IsSet}}:
+		return {{printf "%q" .Name}}
+{{- end}}
+	}
+	return ""
+}
+
+// MarshalXML encodes the element of the choice that is set, and fails
+// if more than one is.
+func (c {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	n := 0
+{{- range .Members}}
+	if {{.IsSet}} {
+		n++
+	}
+{{- end}}
+	if n > 1 {
+		return errors.New("{{.Parent}}: more than one element of the choice is set")
+	}
+	switch {
+{{- range .Members}}
+	case {{.IsSet}}:
+		return e.EncodeElement(c.{{.Field}}, xml.StartElement{Name: xml.Name{Local: {{printf "%q" .Name}}}})
+{{- end}}
+	}
+	return nil
+}
+
+// UnmarshalXML decodes the element of the choice in start.
+func (c *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+{{- range .Members}}
+	case {{printf "%q" .Name}}:
+		return d.DecodeElement(&c.{{.Field}}, &start)
+{{- end}}
+	}
+	return d.Skip()
+}
+
+`))
+
+type choiceMember struct{ Field, Name, IsSet string }
+
+// choiceGroup returns the choice of elements of ct to generate as a
+// struct, if any. Only types with a single choice of plain elements
+// get one, as the parent struct decodes it from elements that match
+// none of its fields, and there can only be one such field.
+func (ge *goEncoder) choiceGroup(ct *wsdl.ComplexType) *wsdl.Choice {
+	if !ge.choiceStructs || ge.choiceField || ge.anyField || ct.Name == "" {
+		return nil
+	}
+	var choices []*wsdl.Choice
+	if ct.Choice != nil {
+		choices = append(choices, ct.Choice)
+	}
+	if ct.Sequence != nil {
+		// repeated groups take the elements that match no other field
+		if len(ct.Sequence.Any) > 0 || len(ct.Sequence.Sequences) > 0 || repeated(ct.Sequence.Max) {
+			return nil
+		}
+		choices = append(choices, ct.Sequence.Choices...)
+	}
+	if len(choices) != 1 {
+		return nil
+	}
+	c := choices[0]
+	if repeated(c.Max) || len(c.Any) > 0 || len(c.ComplexTypes) > 0 || len(c.Elements) < 2 {
+		return nil
+	}
+	if ge.isTypeName(ge.goSymbol(ct.Name) + "Choice") {
+		return nil
+	}
+	for _, el := range c.Elements {
+		if el.Ref != "" {
+			if el, _ = ge.element(el.Ref); el == nil {
+				return nil
+			}
+		}
+		if el.Type == "" && el.ComplexType != nil {
+			return nil
+		}
+	}
+	return c
+}
+
+// genChoiceField writes the field of the choice c of ct to w, and
+// generates its struct, unless it already was for another struct
+// extending ct.
+func (ge *goEncoder) genChoiceField(w io.Writer, ct *wsdl.ComplexType, c *wsdl.Choice) error {
+	parent := ge.goSymbol(ct.Name)
+	name := parent + "Choice"
+	ge.choiceField = true
+	fmt.Fprintf(w, "Choice *%s `xml:\",any\" json:\"Choice,omitempty\" yaml:\"Choice,omitempty\"`\n", name)
+	if ge.choiceTypes[name] {
+		return nil
+	}
+	ge.choiceTypes[name] = true
+	ge.structs = append(ge.structs, name)
+
+	// members are generated as the fields of another struct, and must
+	// be pointers or slices to tell the one that is set
+	fields, structName, ptrFields := ge.fields, ge.structName, ge.ptrFields
+	defer func() { ge.fields, ge.structName, ge.ptrFields = fields, structName, ptrFields }()
+	ge.fields, ge.structName, ge.ptrFields = nil, name, true
+	var b bytes.Buffer
+	for _, el := range c.Elements {
+		member := *el
+		member.Min = 0
+		ge.genElementField(&b, &member)
+	}
+	data := &struct {
+		Name, Parent string
+		Fields       string
+		Members      []choiceMember
+	}{Name: name, Parent: parent, Fields: b.String()}
+	for i, f := range ge.fields {
+		el := c.Elements[i]
+		if el.Ref != "" {
+			el, _ = ge.element(el.Ref)
+		}
+		isSet := "c." + f.Name + " != nil"
+		if strings.HasPrefix(f.Type, "[]") {
+			isSet = "len(c." + f.Name + ") > 0"
+		}
+		data.Members = append(data.Members, choiceMember{f.Name, el.Name, isSet})
+	}
+	ge.needsStdPkg["errors"] = true
+	ge.needsStdPkg["encoding/xml"] = true
+	return ge.template(choiceT).Execute(&ge.choiceDecls, data)
+}
@@ -0,0 +1,236 @@
+// Package conformance is a suite of WSDL documents and the code that
+// wsdl2go generates from them, for forks and authors of custom
+// templates to check their generators against.
+//
+// The documents and their golden files are in wsdlgo/testdata, listed
+// in wsdlgo/testdata/conformance.txt. To submit a document that
+// wsdl2go gets wrong, or doesn't cover yet, add it to testdata with
+// the code it should generate, and list both in conformance.txt.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+	"github.com/fiorix/wsdl2go/wsdlgo"
+)
+
+// Case is a WSDL document of the suite and the code generated from it.
+type Case struct {
+	WSDL   string // file of the WSDL document, in Dir
+	Golden string // file of the generated code, in Dir
+}
+
+// Generator generates Go code from d to w. Relative locations of the
+// documents imported by d are relative to base, which is the location
+// of d.
+type Generator func(w io.Writer, d *wsdl.Definitions, base string) error
+
+// Default generates code with the default encoder of package wsdlgo.
+func Default(w io.Writer, d *wsdl.Definitions, base string) error {
+	return wsdlgo.NewEncoder(w, wsdlgo.WithBaseURL(base)).Encode(d)
+}
+
+// Mode is how the generated code is compared to the golden files.
+type Mode int
+
+// Modes of Run.
+const (
+	// Exact requires the generated code to be the same as the golden
+	// files, byte for byte.
+	Exact Mode = iota
+
+	// Declarations requires the generated code to declare the same
+	// top level constants, variables, types, functions and methods as
+	// the golden files, for generators with custom templates.
+	Declarations
+)
+
+// Dir returns the directory of the documents and golden files.
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "testdata")
+}
+
+// Cases returns the cases of the suite.
+func Cases() ([]Case, error) {
+	name := filepath.Join(Dir(), "conformance.txt")
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cases []Case
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		f := strings.Fields(text)
+		if len(f) != 2 {
+			return nil, fmt.Errorf("%s:%d: want wsdl and golden file, have %q", name, line, text)
+		}
+		cases = append(cases, Case{WSDL: f[0], Golden: f[1]})
+	}
+	return cases, s.Err()
+}
+
+// Load reads the WSDL document of c, and returns it with its location.
+//
+// Documents of the suite import schemas relative to the directory of
+// package wsdlgo, where its tests run, or from file://CURRENT_DIR, which
+// is replaced with that directory. Their location is in that directory
+// too, so the imports are resolved the same way.
+func Load(c Case) (d *wsdl.Definitions, base string, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(Dir(), c.WSDL))
+	if err != nil {
+		return nil, "", err
+	}
+	dir := filepath.Dir(Dir())
+	b = bytes.Replace(b, []byte("CURRENT_DIR"), []byte(dir), -1)
+	d, err = wsdl.Unmarshal(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", c.WSDL, err)
+	}
+	return d, filepath.Join(dir, c.WSDL), nil
+}
+
+// Run runs the suite with gen as subtests of t, comparing the code it
+// generates to the golden files in mode.
+func Run(t *testing.T, gen Generator, mode Mode) {
+	cases, err := Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(strings.TrimSuffix(c.WSDL, ".wsdl"), func(t *testing.T) {
+			if err := Check(c, gen, mode); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Check generates the code of c with gen, and compares it to the
+// golden file of c in mode.
+func Check(c Case, gen Generator, mode Mode) error {
+	d, base, err := Load(c)
+	if err != nil {
+		return err
+	}
+	var have bytes.Buffer
+	if err := gen(&have, d, base); err != nil {
+		return fmt.Errorf("%s: %v", c.WSDL, err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join(Dir(), c.Golden))
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case Exact:
+		if !bytes.Equal(have.Bytes(), want) {
+			return fmt.Errorf("%s: generated code differs from %s:\n%s", c.WSDL, c.Golden, have.Bytes())
+		}
+	case Declarations:
+		wantDecls, err := declarations(c.Golden, want)
+		if err != nil {
+			return err
+		}
+		haveDecls, err := declarations(c.WSDL, have.Bytes())
+		if err != nil {
+			return err
+		}
+		missing, extra := diff(wantDecls, haveDecls)
+		if len(missing) > 0 || len(extra) > 0 {
+			return fmt.Errorf("%s: declarations differ from %s: missing %v, extra %v",
+				c.WSDL, c.Golden, missing, extra)
+		}
+	default:
+		return fmt.Errorf("unknown mode %d", mode)
+	}
+	return nil
+}
+
+// declarations returns the sorted names of the top level declarations
+// of the Go source src, with methods as Type.Method.
+func declarations(name string, src []byte) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), name, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				name = receiver(decl.Recv.List[0].Type) + "." + name
+			}
+			names = append(names, name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						if n.Name != "_" {
+							names = append(names, n.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// receiver returns the name of the type of a method receiver.
+func receiver(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// diff returns the names in want that aren't in have, and the ones in
+// have that aren't in want, both sorted.
+func diff(want, have []string) (missing, extra []string) {
+	count := make(map[string]int)
+	for _, n := range want {
+		count[n]++
+	}
+	for _, n := range have {
+		count[n]--
+	}
+	for n, c := range count {
+		for ; c > 0; c-- {
+			missing = append(missing, n)
+		}
+		for ; c < 0; c++ {
+			extra = append(extra, n)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
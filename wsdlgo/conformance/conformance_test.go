@@ -0,0 +1,39 @@
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+func TestDefault(t *testing.T) {
+	Run(t, Default, Exact)
+}
+
+func TestDeclarations(t *testing.T) {
+	Run(t, Default, Declarations)
+
+	c := Case{WSDL: "memcache.wsdl", Golden: "memcache.golden"}
+	extra := func(w io.Writer, d *wsdl.Definitions, base string) error {
+		if err := Default(w, d, base); err != nil {
+			return err
+		}
+		_, err := fmt.Fprint(w, "\nfunc (c *MemoryServicePortTypeClient) Close() error { return nil }\n")
+		return err
+	}
+	err := Check(c, extra, Declarations)
+	if err == nil || !strings.Contains(err.Error(), "missing [], extra [MemoryServicePortTypeClient.Close]") {
+		t.Fatalf("want extra MemoryServicePortTypeClient.Close, have %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	missing, extra := diff([]string{"A", "B", "B", "C"}, []string{"B", "C", "D"})
+	if !reflect.DeepEqual(missing, []string{"A", "B"}) || !reflect.DeepEqual(extra, []string{"D"}) {
+		t.Fatalf("unexpected diff: missing %v, extra %v", missing, extra)
+	}
+}
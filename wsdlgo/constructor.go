@@ -0,0 +1,142 @@
+package wsdlgo
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// structField is a field of a generated struct, recorded to generate
+// its constructor.
+type structField struct {
+	Name     string
+	Type     string // Go type, such as *string or []Item
+	Required bool   // minOccurs >= 1, or use="required"
+	Default  string // default value in the schema, if any
+}
+
+var constructorT = template.Must(template.New("constructor").Parse(`
+// New{{.Name}} creates a new {{.Name}} with its required fields{{if .Defaults}}, and
+// the default values of its optional fields{{end}}.
+func New{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) *{{.Name}} {
+{{- range .Defaults}}{{if .Local}}
+	{{.Local}} := {{.Value}}
+{{- end}}{{end}}
+	return &{{.Name}}{
+{{- range .Params}}
+		{{.Field}}: {{.Name}},
+{{- end}}
+{{- range .Defaults}}
+		{{.Field}}: {{if .Local}}&{{.Local}}{{else}}{{.Value}}{{end}},
+{{- end}}
+	}
+}
+
+`))
+
+type constructorParam struct{ Field, Name, Type string }
+
+type constructorDefault struct{ Field, Local, Value string }
+
+// genConstructor writes the NewX function of struct name to w, if any
+// of its fields are required or have default values.
+func (ge *goEncoder) genConstructor(w io.Writer, name string, fields []structField) error {
+	fn := "New" + name
+	if ge.isTypeName(fn) {
+		return nil
+	}
+	data := &struct {
+		Name     string
+		Params   []constructorParam
+		Defaults []constructorDefault
+	}{Name: name}
+	seen := make(map[string]bool)
+	local := func(field string) string {
+		v := maskKeywordUsage(paramName(field))
+		for i := 2; seen[v]; i++ {
+			v = maskKeywordUsage(paramName(field)) + strconv.Itoa(i)
+		}
+		seen[v] = true
+		return v
+	}
+	for _, f := range fields {
+		if f.Required {
+			data.Params = append(data.Params, constructorParam{f.Name, local(f.Name), f.Type})
+			continue
+		}
+		if f.Default == "" || strings.HasPrefix(f.Type, "[]") {
+			continue
+		}
+		typ := strings.TrimPrefix(f.Type, "*")
+		value, ok := ge.defaultLiteral(typ, f.Default)
+		if !ok {
+			continue
+		}
+		d := constructorDefault{Field: f.Name, Value: value}
+		if typ != f.Type {
+			d.Local = local(f.Name)
+		}
+		data.Defaults = append(data.Defaults, d)
+	}
+	if len(data.Params) == 0 && len(data.Defaults) == 0 {
+		return nil
+	}
+	return ge.template(constructorT).Execute(w, data)
+}
+
+// defaultLiteral returns the Go expression of the default value v of
+// the type typ, if it's a string, bool or number, or a simple type
+// restricting one.
+func (ge *goEncoder) defaultLiteral(typ, v string) (string, bool) {
+	base := typ
+	for _, st := range ge.stypes {
+		if ge.goSymbol(st.Name) == typ && st.Restriction != nil {
+			restore := ge.inScope(st)
+			base = ge.wsdl2goType(st.Restriction.Base)
+			restore()
+			break
+		}
+	}
+	var err error
+	switch base {
+	case "string":
+		v = strconv.Quote(v)
+	case "bool":
+		_, err = strconv.ParseBool(v)
+	case "int", "int16", "int64":
+		_, err = strconv.ParseInt(v, 10, 64)
+	case "uint", "uint16", "uint64":
+		_, err = strconv.ParseUint(v, 10, 64)
+	case "float64":
+		_, err = strconv.ParseFloat(v, 64)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+	switch typ {
+	case "string", "bool", "int":
+		return v, true
+	}
+	return typ + "(" + v + ")", true
+}
+
+// paramName returns the name of a parameter for the field, with its
+// leading upper case letters in lower case, e.g. ID as id and URLPath
+// as urlPath.
+func paramName(field string) string {
+	r := []rune(field)
+	for i := range r {
+		if !unicode.IsUpper(r[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
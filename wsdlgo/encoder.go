@@ -10,23 +10,25 @@ import (
 	"bytes"
 	"encoding/xml"
 	"fmt"
+	"go/ast"
+	"go/format"
 	"go/parser"
+	"go/printer"
 	"go/token"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"unicode"
 
 	"github.com/fiorix/wsdl2go/wsdl"
-	"golang.org/x/net/html/charset"
 )
 
 const fileHeader = "// Code generated by wsdl2go. DO NOT EDIT."
@@ -47,7 +49,154 @@ type Encoder interface {
 	// SetLocalNamespace allows overriding of the Namespace in XMLName instead
 	// of the one specified in wsdl
 	SetLocalNamespace(namespace string)
-}
+
+	// SetASTHook sets a function that is called with the parsed
+	// generated code before it's formatted, and may modify it to
+	// inject build tags, methods, instrumentation and so on.
+	SetASTHook(hook ASTHook)
+
+	// SetTestWriter sets where to write a _test.go file for the
+	// generated code, with tests that marshal each generated struct
+	// to XML and back.
+	SetTestWriter(w io.Writer)
+
+	// SetEnumWriter sets where to write the tables of valid values
+	// of simple types with large enumerations, as a separate file of
+	// the same package that is cheaper to format and compile. By
+	// default they're written with the rest of the code.
+	SetEnumWriter(w io.Writer)
+
+	// SetDocLang sets the language of the documentation written as
+	// comments, for WSDL documents with documentation in several
+	// languages.
+	SetDocLang(lang string)
+
+	// SetCompat sets the version of wsdl2go whose generated type
+	// names to keep, so existing code using them still compiles. The
+	// only version is "v1", and "" disables it.
+	SetCompat(version string) error
+
+	// SetNaming sets how the words of generated identifiers are
+	// cased: GoNaming, the default, writes their initialisms in upper
+	// case, as in CustomerID, and TitleNaming writes them in title
+	// case, as in CustomerId, as older versions did and compat v1
+	// does unless a naming is set.
+	SetNaming(n Naming) error
+
+	// SetInitialisms sets the initialisms that GoNaming writes in
+	// upper case, DefaultInitialisms by default.
+	SetInitialisms(words []string)
+
+	// SetBaseURL sets the location of the WSDL document, such as
+	// its URL or file name, against which relative locations of
+	// imported documents are resolved.
+	SetBaseURL(loc string)
+
+	// SetCatalog sets the catalog of local files of the documents
+	// imported by the WSDL document and its schemas, which are read
+	// instead of downloading the documents.
+	SetCatalog(c Catalog)
+
+	// SetTemplate replaces the code template name, one of
+	// TemplateNames, with t. The data t is executed with is the same
+	// as the default template, see DefaultTemplate. The generated code
+	// can also be post-processed with SetASTHook.
+	SetTemplate(name string, t *template.Template) error
+
+	// SetCache sets where to keep the downloaded documents imported
+	// by the WSDL document and its schemas, to reuse them later.
+	SetCache(c *Cache)
+
+	// SetOperationLabels sets whether the generated operations call
+	// the Observe hook of the soap.Client, with their names as
+	// service.port.operation, to be used as labels of metrics.
+	SetOperationLabels(enabled bool)
+
+	// SetGetters sets whether to generate GetX methods of the struct
+	// fields X that are pointers, returning their zero value when
+	// they're nil, as in protobuf.
+	SetGetters(enabled bool)
+
+	// SetEqualCopy sets whether to generate Equal and DeepCopy methods
+	// of the structs, which compare their values rather than their
+	// pointers, and copy them without sharing pointers or slices.
+	SetEqualCopy(enabled bool)
+
+	// SetConstructors sets whether to generate NewX functions of the
+	// structs X with required fields, taking them as parameters and
+	// setting the default values of the optional fields.
+	SetConstructors(enabled bool)
+
+	// SetFieldTags sets the struct tags added to the fields of the
+	// generated structs, such as validate, bson or db tags.
+	SetFieldTags(t FieldTags)
+
+	// SetOmitEmpty sets whether optional fields are pointers tagged
+	// with omitempty, which is the default, or values that are always
+	// encoded, for servers that require empty elements to be present.
+	SetOmitEmpty(enabled bool)
+
+	// SetStructOmitEmpty overrides SetOmitEmpty for the fields of the
+	// struct with the given Go name.
+	SetStructOmitEmpty(name string, enabled bool)
+
+	// SetValueScalars sets whether optional fields of simple types are
+	// values rather than pointers, still tagged with omitempty, for
+	// services where zero values can be left out. Nillable fields
+	// remain pointers, to send xsi:nil.
+	SetValueScalars(enabled bool)
+
+	// SetChoiceStructs sets whether to generate the choice of elements
+	// of a type as a struct in its Choice field, which only encodes the
+	// element that is set, and fails if more than one is.
+	SetChoiceStructs(enabled bool)
+
+	// SetResponseWrapper sets whether operations return their output
+	// wrapper struct rather than a value per part of the output
+	// message, which is more stable as the WSDL evolves. Operations
+	// with attachments in their output aren't affected.
+	SetResponseWrapper(enabled bool)
+
+	// SetRawResponse sets whether operations also return the raw
+	// response, its envelope and HTTP response, as a *soap.Response
+	// before the error, also when they fail.
+	SetRawResponse(enabled bool)
+
+	// SetUnwrap sets whether document/literal operations whose
+	// response element has a single element return that element,
+	// e.g. *Customer, rather than the response element. Elements that
+	// aren't pointers or slices are returned as pointers.
+	SetUnwrap(enabled bool)
+
+	// SetStrict sets whether Encode fails when the WSDL references
+	// types that can't be found, listing all of them, rather than
+	// generating code that refers to undeclared types.
+	SetStrict(enabled bool)
+
+	// SetLenient sets whether imported documents that can't be fetched
+	// are skipped with a warning, and the types that can't be found
+	// are generated as placeholders that keep their raw XML, e.g.
+	// type Foo struct { Raw []byte }, so a client can be generated
+	// when the host of an imported schema is down.
+	SetLenient(enabled bool)
+
+	// SetReporter sets the function called with the events of Encode,
+	// such as the documents imported, the operations and constructs
+	// that aren't generated, and warnings, which are logged otherwise.
+	SetReporter(r func(Event))
+
+	// SetMinimal sets whether to generate code without reflection,
+	// for constrained targets such as TinyGo: enumerations are
+	// validated with switch statements, extension types aren't
+	// registered in the soap package, and the client at the service
+	// address sets SkipXMLType. Equal methods that would compare
+	// fields with reflection are an error.
+	SetMinimal(enabled bool)
+}
+
+// ASTHook post-processes the syntax tree of the generated code. The
+// returned error aborts the code generation.
+type ASTHook func(fset *token.FileSet, f *ast.File) error
 
 type goEncoder struct {
 	// where to write Go code
@@ -63,19 +212,56 @@ type goEncoder struct {
 	stypes map[string]*wsdl.SimpleType
 	ctypes map[string]*wsdl.ComplexType
 
-	// elements cache
-	elements map[string]*wsdl.Element
+	// Go names of the types cache, built on demand by isTypeName and
+	// reset whenever the types cache changes
+	typeSymbols map[string]bool
+
+	// types cache keys of qualified type names, and the other way
+	// around; see cacheTypeName
+	typeKeys   map[xml.Name]string
+	typeQNames map[string]xml.Name
+
+	// schemas that declare the types and elements cached, and the
+	// namespace prefixes of the one of the type being generated, which
+	// its qualified names are resolved with; see inScope
+	schemaOf map[interface{}]*wsdl.Schema
+	scope    map[string]string
+
+	// elements cache, by qualified name, and the qualified names of the
+	// first elements of each local name; see element
+	elements     map[xml.Name]*wsdl.Element
+	elementNames map[string]xml.Name
+
+	// types cache keys of the types declared by elements, when not
+	// their names; see cacheTypes
+	elementTypes map[xml.Name]string
+
+	// types cache keys of the anonymous types of elements, see
+	// hoistAnonymousTypes
+	anonTypes map[*wsdl.ComplexType]string
 
 	// funcs cache
 	funcs     map[string]*wsdl.Operation
 	funcnames []string
 
+	// names of overloaded operations, by their name in the WSDL, and
+	// the other way around, see overload
+	overloads map[string][]string
+	wireNames map[string]string
+
+	// operation wrappers generated
+	opStructs map[string]bool
+
 	// messages cache
 	messages map[string]*wsdl.Message
 
 	// soap operations cache
 	soapOps map[string]*wsdl.BindingOperation
 
+	// prefixes of the soap:body namespaces of rpc operations, which
+	// qualify their wrapper elements, by namespace
+	bodyPrefixes map[string]string
+
 	// whether to add supporting types
 	needsDateType     bool
 	needsTimeType     bool
@@ -87,26 +273,173 @@ type goEncoder struct {
 	importedSchemas   map[string]bool
 	usedNamespaces    map[string]string
 
+	// types redefined by the schemas, see redefineTypes
+	redefines []*wsdl.RedefineSchema
+
+	// extension types registered for xsi:type marshaling
+	xsiTypes []*wsdl.ComplexType
+
 	// localNamespace allows overriding of namespace in XMLName
 	localNamespace string
+
+	// astHook post-processes the generated code
+	astHook ASTHook
+
+	// where to write tests for the generated code, if anywhere
+	testw io.Writer
+
+	// language of the documentation written as comments
+	docLang string
+
+	// version of generated type names to keep, see SetCompat
+	compat string
+
+	// how the words of identifiers are cased, see SetNaming, and the
+	// initialisms written in upper case, see SetInitialisms
+	naming      Naming
+	initialisms map[string]bool
+
+	// location of the WSDL document
+	baseURL string
+
+	// whether operations call the Observe hook, see SetOperationLabels
+	opLabels bool
+
+	// whether to generate getters of pointer fields, see SetGetters
+	getters bool
+
+	// whether to generate Equal and DeepCopy methods, see SetEqualCopy
+	equalCopy bool
+
+	// whether to generate constructors of structs, see SetConstructors
+	constructors bool
+
+	// fields of the struct being generated, and how many choices the
+	// current field is in
+	fields  []structField
+	choices int
+
+	// tags added to the fields of structs, see SetFieldTags, the name
+	// of the struct being generated, and whether its optional fields
+	// are always pointers, as those of operation wrappers and choices
+	fieldTags  FieldTags
+	structName string
+	ptrFields  bool
+
+	// whether optional fields are pointers tagged with omitempty, see
+	// SetOmitEmpty, and the overrides of structs
+	omitEmpty       bool
+	structOmitEmpty map[string]bool
+
+	// whether optional fields of simple types are values, see
+	// SetValueScalars
+	valueScalars bool
+
+	// whether to generate choices as structs, see SetChoiceStructs,
+	// whether the struct being generated has a choice field, a field of
+	// a repeated group or an Any field, any of which takes the elements
+	// that match no other field, and the choice and group structs
+	// generated, and yet to be written
+	// after it
+	choiceStructs bool
+	choiceField   bool
+	anyField      bool
+	choiceTypes   map[string]bool
+	groupTypes    map[string]bool
+	choiceDecls   bytes.Buffer
+
+	// whether operations return their output wrapper, see
+	// SetResponseWrapper
+	responseWrapper bool
+
+	// whether operations return their raw response, see SetRawResponse
+	rawResponse bool
+
+	// whether operations return the single element of their response,
+	// see SetUnwrap
+	unwrap bool
+
+	// whether to fail on unresolved types, see SetStrict, or generate
+	// placeholders for them, see SetLenient, the Go names of those
+	// found by qualified name, and the positions of the types that
+	// first reference them, of pos, the type being generated
+	strict        bool
+	lenient       bool
+	unresolved    map[string]string
+	unresolvedPos map[string]wsdl.Pos
+	pos           wsdl.Pos
+
+	// where events are reported, see SetReporter
+	reporter func(Event)
+
+	// whether to generate code without reflection, see SetMinimal
+	minimal bool
+
+	// local files of imported documents
+	catalog Catalog
+
+	// where to keep downloaded documents, if anywhere
+	cache *Cache
+
+	// code templates replaced with SetTemplate
+	templates map[string]*template.Template
+
+	// generated struct types
+	structs []string
+
+	// versions of the schemas, in the order they were merged
+	schemaVersions []schemaVersion
+
+	// where to write the tables of large enumerations, if not in w
+	enumw io.Writer
+	enums bytes.Buffer
+
+	// first error returned by the options of NewEncoder
+	err error
 }
 
-// NewEncoder creates and initializes an Encoder that generates code to w.
-func NewEncoder(w io.Writer) Encoder {
-	return &goEncoder{
+// schemaVersion is the version attribute of a schema.
+type schemaVersion struct {
+	Namespace, Version string
+}
+
+// NewEncoder creates and initializes an Encoder that generates code to w,
+// configured with opts.
+func NewEncoder(w io.Writer, opts ...Option) Encoder {
+	ge := &goEncoder{
 		w:               w,
 		http:            http.DefaultClient,
 		stypes:          make(map[string]*wsdl.SimpleType),
 		ctypes:          make(map[string]*wsdl.ComplexType),
-		elements:        make(map[string]*wsdl.Element),
+		typeKeys:        make(map[xml.Name]string),
+		typeQNames:      make(map[string]xml.Name),
+		schemaOf:        make(map[interface{}]*wsdl.Schema),
+		elements:        make(map[xml.Name]*wsdl.Element),
+		elementNames:    make(map[string]xml.Name),
+		elementTypes:    make(map[xml.Name]string),
+		anonTypes:       make(map[*wsdl.ComplexType]string),
 		funcs:           make(map[string]*wsdl.Operation),
+		overloads:       make(map[string][]string),
+		wireNames:       make(map[string]string),
+		opStructs:       make(map[string]bool),
 		messages:        make(map[string]*wsdl.Message),
 		soapOps:         make(map[string]*wsdl.BindingOperation),
+		bodyPrefixes:    make(map[string]string),
 		needsTag:        make(map[string]string),
 		needsStdPkg:     make(map[string]bool),
 		needsExtPkg:     make(map[string]bool),
 		importedSchemas: make(map[string]bool),
+		omitEmpty:       true,
+		structOmitEmpty: make(map[string]bool),
+		choiceTypes:     make(map[string]bool),
+		groupTypes:      make(map[string]bool),
+	}
+	for _, opt := range opts {
+		if err := opt(ge); err != nil && ge.err == nil {
+			ge.err = err
+		}
 	}
+	return ge
 }
 
 func (ge *goEncoder) SetPackageName(name fmt.Stringer) {
@@ -117,15 +450,6 @@ func (ge *goEncoder) SetClient(c *http.Client) {
 	ge.http = c
 }
 
-func gofmtPath() (string, error) {
-	goroot := os.Getenv("GOROOT")
-	if goroot != "" {
-		return filepath.Join(goroot, "bin", "gofmt"), nil
-	}
-	return exec.LookPath("gofmt")
-
-}
-
 var numberSequence = regexp.MustCompile(`([a-zA-Z])(\d+)([a-zA-Z]?)`)
 var numberReplacement = []byte(`$1 $2 $3`)
 
@@ -136,13 +460,26 @@ func addWordBoundariesToNumbers(s string) string {
 }
 
 func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
+	if ge.err != nil {
+		return ge.err
+	}
 	if d == nil {
 		return nil
 	}
 
 	// default mechanism to set package name
 	if ge.packageName == nil {
-		ge.packageName = BindingPackageName(d.Binding)
+		ge.packageName = BindingPackageName(*d.DefaultBinding())
+	}
+
+	// The generated code is only parsed whole for methods of structs and
+	// the AST hook, which is costly for large WSDLs. Otherwise it's
+	// formatted as it's generated, in chunks.
+	if !(ge.getters || ge.equalCopy) && ge.astHook == nil {
+		if err := ge.encodeChunks(d); err != nil {
+			return err
+		}
+		return ge.encodeSupport()
 	}
 
 	var b bytes.Buffer
@@ -150,61 +487,160 @@ func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
 	if err != nil {
 		return err
 	}
-	if b.Len() == 0 {
-		return nil
+	var h bytes.Buffer
+	ge.writeHeader(&h, d)
+	b.WriteTo(&h)
+	b = bytes.Buffer{}
+	src := h.Bytes()
+	if (ge.getters || ge.equalCopy) && len(ge.structs) > 0 {
+		if src, err = ge.addMethods(src); err != nil {
+			return err
+		}
 	}
-	var errb bytes.Buffer
-	input := b.String()
+	if ge.astHook != nil {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+		if err != nil {
+			return badCode(err, src)
+		}
+		if err = ge.astHook(fset, f); err != nil {
+			return fmt.Errorf("ast hook: %v", err)
+		}
+		var hooked bytes.Buffer
+		if err = printer.Fprint(&hooked, fset, f); err != nil {
+			return err
+		}
+		src = hooked.Bytes()
+	}
+	if err = ge.gofmt(ge.w, src); err != nil {
+		return err
+	}
+	return ge.encodeSupport()
+}
 
-	// try to parse the generated code
-	fset := token.NewFileSet()
-	_, err = parser.ParseFile(fset, "", &b, parser.ParseComments)
+// encodeChunks generates the code of d formatting it in chunks, and
+// writes it to the writer with the imports of the packages it uses.
+// Those are only known once it's all generated, so the code formatted
+// is kept until then, in a temporary file if large.
+func (ge *goEncoder) encodeChunks(d *wsdl.Definitions) error {
+	body := newChunkFormatter()
+	defer body.Close()
+	err := ge.encode(body, d)
+	if err == nil {
+		err = body.Flush()
+	}
 	if err != nil {
-		var src bytes.Buffer
-		s := bufio.NewScanner(strings.NewReader(input))
-		for line := 1; s.Scan(); line++ {
-			fmt.Fprintf(&src, "%5d\t%s\n", line, s.Bytes())
+		return err
+	}
+	decls := newChunkFormatter()
+	defer decls.Close()
+	ge.writeDecls(decls, d)
+	if err = decls.Flush(); err != nil {
+		return err
+	}
+	for name := range decls.pkgs {
+		body.pkgs[name] = true
+	}
+	for name := range decls.decls {
+		body.decls[name] = true
+	}
+	w := bufio.NewWriter(ge.w)
+	fmt.Fprintf(w, "%s\n\npackage %s\n\n", fileHeader, ge.packageName)
+	ge.reportUnknown(writeImports(w, body.packages(), ge.importPaths()))
+	if decls.out.Len() > 0 {
+		if _, err = decls.WriteTo(w); err != nil {
+			return err
 		}
-		return fmt.Errorf("generated bad code: %v\n%s", err, src.String())
+		w.WriteString("\n")
+	}
+	if _, err = body.WriteTo(w); err != nil {
+		return err
 	}
+	return w.Flush()
+}
 
-	// dat pipe to gofmt
-	path, err := gofmtPath()
-	if err != nil {
-		return fmt.Errorf("cannot find gofmt with err: %v", err)
+// encodeSupport writes the enumerations and tests of the generated code
+// to their writers, if set.
+func (ge *goEncoder) encodeSupport() error {
+	if ge.enumw != nil && ge.enums.Len() > 0 {
+		if err := ge.writeEnums(); err != nil {
+			return err
+		}
 	}
-	cmd := exec.Cmd{
-		Path:   path,
-		Stdin:  &b,
-		Stdout: ge.w,
-		Stderr: &errb,
+	if ge.testw == nil || len(ge.structs) == 0 {
+		return nil
 	}
-	err = cmd.Run()
+	var b bytes.Buffer
+	if err := ge.writeTests(&b); err != nil {
+		return err
+	}
+	return ge.gofmt(ge.testw, b.Bytes())
+}
+
+// writeEnums writes the tables of large enumerations to their own
+// file. It only has literals, so it's formatted without fixing imports.
+func (ge *goEncoder) writeEnums() error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n\npackage %s\n", fileHeader, ge.packageName)
+	ge.enums.WriteTo(&b)
+	src, err := format.Source(b.Bytes())
 	if err != nil {
-		var x bytes.Buffer
-		fmt.Fprintf(&x, "gofmt: %v\n", err)
-		if errb.Len() > 0 {
-			fmt.Fprintf(&x, "gofmt stderr:\n%s\n", errb.String())
-		}
-		fmt.Fprintf(&x, "generated code:\n%s\n", input)
-		return fmt.Errorf(x.String())
+		return badCode(err, b.Bytes())
 	}
-	return nil
+	_, err = ge.enumw.Write(src)
+	return err
+}
+
+// gofmt formats the code in src to w, and fixes its imports: packages
+// that end up unused are removed, and missing ones are added, if known,
+// see importPaths. Those that aren't are reported.
+func (ge *goEncoder) gofmt(w io.Writer, src []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return badCode(err, src)
+	}
+	unknown, err := fixImports(w, fset, file, ge.importPaths())
+	ge.reportUnknown(unknown)
+	return err
+}
+
+// reportUnknown warns of the packages used by the generated code that
+// aren't imported, as their paths aren't known, so it doesn't build.
+func (ge *goEncoder) reportUnknown(pkgs []string) {
+	if len(pkgs) > 0 {
+		ge.report(WarningEvent, wsdl.Pos{}, "packages not imported, as their paths aren't known: %s", strings.Join(pkgs, ", "))
+	}
+}
+
+// badCode returns an error for generated code that can't be parsed,
+// with the code and its line numbers.
+func badCode(err error, src []byte) error {
+	var b bytes.Buffer
+	s := bufio.NewScanner(bytes.NewReader(src))
+	s.Buffer(nil, len(src)+1)
+	for line := 1; s.Scan(); line++ {
+		fmt.Fprintf(&b, "%5d\t%s\n", line, s.Bytes())
+	}
+	return fmt.Errorf("generated bad code: %v\n%s", err, b.String())
 }
 
 func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
-	ge.unionSchemasData(d, &d.Schema)
+	// generated clients only send requests over HTTP
+	if bt := d.DefaultBinding().BindingType; bt != nil && !bt.HTTPTransport() {
+		return fmt.Errorf("binding %q has unsupported transport %q", d.DefaultBinding().Name, bt.Transport)
+	}
 	err := ge.importParts(d)
 	ge.usedNamespaces = d.Namespaces
 	if err != nil {
 		return fmt.Errorf("wsdl import: %v", err)
 	}
 	ge.cacheTypes(d)
-	ge.cacheFuncs(d)
 	ge.cacheMessages(d)
+	ge.cacheFuncs(d)
 	ge.cacheSOAPOperations(d)
+	ge.cacheBodyPrefixes(d)
 
-	var b bytes.Buffer
 	var ff []func(io.Writer, *wsdl.Definitions) error
 	if len(ge.soapOps) > 0 {
 		ff = append(ff,
@@ -215,18 +651,30 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 		)
 	} else {
 		// TODO: probably faulty wsdl?
+		if len(ge.funcs) > 0 {
+			ge.report(SkipEvent, d.DefaultBinding().Pos, "no operations are bound, so those of port type %q are generated as functions returning a not implemented error", d.DefaultPortType().Name)
+		}
 		ff = append(ff,
 			ge.writeGoFuncs,
 			ge.writeGoTypes,
 		)
 	}
 	for _, f := range ff {
-		err := f(&b, d)
+		err := f(w, d)
 		if err != nil {
 			return err
 		}
 	}
+	if err = ge.unresolvedErr(); err != nil {
+		return err
+	}
+	ge.writePlaceholders(w)
+	return nil
+}
 
+// writeHeader writes the package clause of the generated code, the
+// imports recorded in needsStdPkg and needsExtPkg, and writeDecls.
+func (ge *goEncoder) writeHeader(w io.Writer, d *wsdl.Definitions) {
 	fmt.Fprintf(w, "%s\n\npackage %s\n\nimport (\n", fileHeader, ge.packageName)
 	for pkg := range ge.needsStdPkg {
 		fmt.Fprintf(w, "%q\n", pkg)
@@ -238,12 +686,20 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 		fmt.Fprintf(w, "%q\n", pkg)
 	}
 	fmt.Fprintf(w, ")\n\n")
+	ge.writeDecls(w, d)
+}
+
+// writeDecls writes the declarations of the namespaces, versions and
+// actions used by the generated code.
+func (ge *goEncoder) writeDecls(w io.Writer, d *wsdl.Definitions) {
 	if d.TargetNamespace != "" {
-		ge.writeComments(w, "Namespace", "")
-		fmt.Fprintf(w, "var Namespace = %q\n\n", d.TargetNamespace)
+		name := ge.namespaceVarName()
+		ge.writeComments(w, name, "")
+		fmt.Fprintf(w, "var %s = %q\n\n", name, d.TargetNamespace)
 	}
-	_, err = io.Copy(w, &b)
-	return err
+	ge.writeNamespaces(w)
+	ge.writeVersions(w, d)
+	ge.writeActions(w)
 }
 
 func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
@@ -251,75 +707,303 @@ func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
 	if err != nil {
 		return err
 	}
-	return ge.importSchema(d)
+	ge.rootSchemasData(d)
+	if err = ge.importSchema(d); err != nil {
+		return err
+	}
+	ge.redefineTypes(d)
+	return nil
 }
 
 func (ge *goEncoder) importRoot(d *wsdl.Definitions) error {
 	for _, imp := range d.Imports {
-		if imp.Location == "" {
-			continue
+		loc, ok := ge.catalog.lookup(imp.Location, imp.Namespace)
+		if !ok {
+			if imp.Location == "" {
+				continue
+			}
+			loc = ge.catalogLocation(resolveLocation(ge.baseURL, imp.Location))
 		}
-		err := ge.importRemote(imp.Location, &d)
+		err := ge.importRemote(loc, &d)
 		if err != nil {
-			return err
+			if !ge.lenient {
+				return err
+			}
+			ge.report(WarningEvent, wsdl.Pos{}, "skipped import of %s: %v", loc, err)
 		}
 	}
 	return nil
 }
 
+// importSchema imports the schemas imported or included by d, and
+// the schemas they import or include, to any depth. Relative locations
+// are resolved against the document that has them. The schemas of each
+// level are downloaded concurrently, but merged into d in document
+// order, each followed by the schemas it imports or includes.
 func (ge *goEncoder) importSchema(d *wsdl.Definitions) error {
-	for _, imp := range d.Schema.Imports {
-		if imp.Location == "" {
-			continue
-		}
-		schema := &wsdl.Schema{}
-		err := ge.importRemote(imp.Location, schema)
+	root := &importedSchema{}
+	var level []*importedSchema
+	var locs []string
+	for _, loc := range ge.schemaLocations(&d.Schema) {
+		loc = ge.catalogLocation(resolveLocation(ge.baseURL, loc))
+		level = append(level, root.add(loc))
+		locs = append(locs, loc)
+	}
+	// Schemas that were already imported are empty, so this ends
+	// even with circular imports. Those are skipped with a warning.
+	for len(locs) > 0 {
+		schemas, err := ge.importRemoteSchemas(locs)
 		if err != nil {
 			return err
 		}
-		ge.unionSchemasData(d, schema)
-		for _, item := range schema.Imports {
-			schema = &wsdl.Schema{}
-			err := ge.importRemote(item.Location, schema)
-			if err != nil {
-				return err
+		var next []*importedSchema
+		var nextLocs []string
+		for i, schema := range schemas {
+			level[i].schema = schema
+			for _, loc := range ge.schemaLocations(schema) {
+				loc = ge.catalogLocation(resolveLocation(locs[i], loc))
+				if cycle := level[i].cycle(loc); cycle != nil {
+					ge.report(WarningEvent, wsdl.Pos{}, "schema import cycle: %s", strings.Join(cycle, " -> "))
+					continue
+				}
+				next = append(next, level[i].add(loc))
+				nextLocs = append(nextLocs, loc)
 			}
-			ge.unionSchemasData(d, schema)
 		}
-		for _, item := range schema.Includes {
-			schema = &wsdl.Schema{}
-			err := ge.importRemote(item.Location, schema)
-			if err != nil {
-				return err
+		level, locs = next, nextLocs
+	}
+	ge.unionImportedSchemas(d, root.imports)
+	return nil
+}
+
+// importedSchema is a schema imported by importSchema, and the schemas
+// it imports or includes.
+type importedSchema struct {
+	loc     string
+	schema  *wsdl.Schema
+	parent  *importedSchema
+	imports []*importedSchema
+}
+
+// add adds the schema at loc imported by s.
+func (s *importedSchema) add(loc string) *importedSchema {
+	imp := &importedSchema{loc: loc, parent: s}
+	s.imports = append(s.imports, imp)
+	return imp
+}
+
+// cycle returns the chain of imports from the schema at loc to s and
+// back to loc, if s was imported by loc, or nil otherwise.
+func (s *importedSchema) cycle(loc string) []string {
+	chain := []string{loc}
+	for imp := s; imp != nil && imp.loc != ""; imp = imp.parent {
+		chain = append(chain, imp.loc)
+		if imp.loc == loc {
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
 			}
-			ge.unionSchemasData(d, schema)
+			return chain
 		}
 	}
 	return nil
 }
 
+func (ge *goEncoder) unionImportedSchemas(d *wsdl.Definitions, schemas []*importedSchema) {
+	for _, s := range schemas {
+		ge.unionSchemasData(d, s.schema)
+		ge.unionImportedSchemas(d, s.imports)
+	}
+}
+
+// resolveLocation resolves the location of a document imported or
+// included by the document at base, which may be relative to it. For
+// local files, a relative location of no file there is relative to the
+// working directory if it has that file, as in earlier versions.
+func resolveLocation(base, loc string) string {
+	if base == "" {
+		return loc
+	}
+	u, err := url.Parse(loc)
+	if err != nil || u.IsAbs() || filepath.IsAbs(loc) {
+		return loc
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return loc
+	}
+	if b.Scheme == "http" || b.Scheme == "https" {
+		return b.ResolveReference(u).String()
+	}
+	file := filepath.Join(filepath.Dir(b.Path), loc)
+	if _, err = os.Stat(file); os.IsNotExist(err) {
+		if _, err = os.Stat(loc); err == nil {
+			return loc
+		}
+	}
+	return file
+}
+
+// schemaLocations returns the locations of the schemas imported or
+// included by s, or their files in the catalog. Imports without a
+// location are only found in the catalog, by namespace.
+func (ge *goEncoder) schemaLocations(s *wsdl.Schema) []string {
+	var locs []string
+	for _, item := range s.Imports {
+		if file, ok := ge.catalog.lookup(item.Location, item.Namespace); ok {
+			locs = append(locs, file)
+		} else if item.Location != "" {
+			locs = append(locs, item.Location)
+		}
+	}
+	for _, item := range s.Includes {
+		if file, ok := ge.catalog.lookup(item.Location, ""); ok {
+			locs = append(locs, file)
+		} else if item.Location != "" {
+			locs = append(locs, item.Location)
+		}
+	}
+	for _, item := range s.Redefines {
+		if file, ok := ge.catalog.lookup(item.Location, ""); ok {
+			locs = append(locs, file)
+		} else if item.Location != "" {
+			locs = append(locs, item.Location)
+		}
+	}
+	return locs
+}
+
+// catalogLocation returns the file of the document at loc in the
+// catalog, if any, or else loc.
+func (ge *goEncoder) catalogLocation(loc string) string {
+	if file, ok := ge.catalog.lookup(loc, ""); ok {
+		return file
+	}
+	return loc
+}
+
+// maxParallelImports is the maximum number of schemas downloaded at
+// the same time.
+const maxParallelImports = 8
+
+// importRemoteSchemas downloads the schemas at locs concurrently, and
+// returns them in the same order. Schemas that were already imported
+// are returned empty.
+func (ge *goEncoder) importRemoteSchemas(locs []string) ([]*wsdl.Schema, error) {
+	schemas := make([]*wsdl.Schema, len(locs))
+	errs := make([]error, len(locs))
+	fetched := make([]bool, len(locs))
+	sem := make(chan struct{}, maxParallelImports)
+	var wg sync.WaitGroup
+	for i, loc := range locs {
+		schemas[i] = &wsdl.Schema{}
+		if !ge.markImported(loc) {
+			continue
+		}
+		fetched[i] = true
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, loc string) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = ge.fetch(loc, schemas[i])
+		}(i, loc)
+	}
+	wg.Wait()
+	// reported in order, by this goroutine
+	for i, err := range errs {
+		if err == nil {
+			if fetched[i] {
+				ge.report(ImportEvent, wsdl.Pos{}, "imported %s", locs[i])
+			}
+			continue
+		}
+		if !ge.lenient {
+			return nil, err
+		}
+		ge.report(WarningEvent, wsdl.Pos{}, "skipped import of %s: %v", locs[i], err)
+		schemas[i] = &wsdl.Schema{}
+	}
+	return schemas, nil
+}
+
+// rootSchemasData records the data of the schemas of d and of the WSDL
+// documents it imports, which are already merged in d.Schema, with the
+// target namespace of each.
+func (ge *goEncoder) rootSchemasData(d *wsdl.Definitions) {
+	schemas := d.Types.Schemas
+	if len(schemas) == 0 {
+		schemas = []*wsdl.Schema{&d.Schema}
+	}
+	for _, s := range schemas {
+		ge.schemaData(d, s)
+	}
+}
+
 func (ge *goEncoder) unionSchemasData(d *wsdl.Definitions, s *wsdl.Schema) {
-	for ns := range s.Namespaces {
-		d.Namespaces[ns] = s.Namespaces[ns]
+	ge.schemaData(d, s)
+	d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, s.ComplexTypes...)
+	d.Schema.SimpleTypes = append(d.Schema.SimpleTypes, s.SimpleTypes...)
+	d.Schema.Elements = append(d.Schema.Elements, s.Elements...)
+}
+
+// schemaData records the namespaces, version, redefinitions and
+// declarations of s, and sets the target namespace of its types.
+func (ge *goEncoder) schemaData(d *wsdl.Definitions, s *wsdl.Schema) {
+	if d.Namespaces == nil {
+		d.Namespaces = make(map[string]string)
 	}
+	// prefixes declared by the WSDL document, or by the schemas merged
+	// first, take precedence
+	for prefix, ns := range s.Namespaces {
+		if _, exists := d.Namespaces[prefix]; !exists {
+			d.Namespaces[prefix] = ns
+		}
+	}
+	if s.Version != "" {
+		ge.addSchemaVersion(s.TargetNamespace, s.Version)
+	}
+	ge.declare(s)
 	for _, ct := range s.ComplexTypes {
 		ct.TargetNamespace = s.TargetNamespace
 	}
 	for _, st := range s.SimpleTypes {
 		st.TargetNamespace = s.TargetNamespace
 	}
-	d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, s.ComplexTypes...)
-	d.Schema.SimpleTypes = append(d.Schema.SimpleTypes, s.SimpleTypes...)
-	d.Schema.Elements = append(d.Schema.Elements, s.Elements...)
+	for _, r := range s.Redefines {
+		for _, ct := range r.ComplexTypes {
+			ct.TargetNamespace = s.TargetNamespace
+		}
+		for _, st := range r.SimpleTypes {
+			st.TargetNamespace = s.TargetNamespace
+		}
+		ge.redefines = append(ge.redefines, r)
+	}
 }
 
 // download xml from url, decode in v.
 func (ge *goEncoder) importRemote(loc string, v interface{}) error {
-	_, alreadyImported := ge.importedSchemas[loc]
-	if alreadyImported {
+	if !ge.markImported(loc) {
 		return nil
 	}
+	if err := ge.fetch(loc, v); err != nil {
+		return err
+	}
+	ge.report(ImportEvent, wsdl.Pos{}, "imported %s", loc)
+	return nil
+}
+
+// markImported records that the document at loc is being imported,
+// and reports whether it wasn't already.
+func (ge *goEncoder) markImported(loc string) bool {
+	if ge.importedSchemas[loc] {
+		return false
+	}
+	ge.importedSchemas[loc] = true
+	return true
+}
 
+// fetch downloads the xml document at loc and decodes it in v. It's
+// safe to call concurrently.
+func (ge *goEncoder) fetch(loc string, v interface{}) error {
 	u, err := url.Parse(loc)
 	if err != nil {
 		return err
@@ -328,11 +1012,18 @@ func (ge *goEncoder) importRemote(loc string, v interface{}) error {
 	var r io.Reader
 	switch u.Scheme {
 	case "http", "https":
+		if ge.cache != nil {
+			data, err := ge.cache.Get(ge.http, loc)
+			if err != nil {
+				return err
+			}
+			r = bytes.NewReader(data)
+			break
+		}
 		resp, err := ge.http.Get(loc)
 		if err != nil {
 			return err
 		}
-		ge.importedSchemas[loc] = true
 		defer resp.Body.Close()
 		r = resp.Body
 	default:
@@ -340,58 +1031,99 @@ func (ge *goEncoder) importRemote(loc string, v interface{}) error {
 		if err != nil {
 			return fmt.Errorf("could not open file raw: %s path: %s escaped: %s : %v", u.RawPath, u.Path, u.EscapedPath(), err)
 		}
-
+		defer file.Close()
 		r = bufio.NewReader(file)
 	}
-	decoder := xml.NewDecoder(r)
-	decoder.CharsetReader = charset.NewReaderLabel
-	return decoder.Decode(&v)
+	return wsdl.DecodeFrom(r, v, loc)
 
 }
 
 func (ge *goEncoder) cacheTypes(d *wsdl.Definitions) {
-	// operation types are declared as go struct types
-	for _, v := range d.Schema.Elements {
-		if v.Type == "" && v.ComplexType != nil {
-			ct := *v.ComplexType
-			ct.Name = v.Name
-			ge.ctypes[v.Name] = &ct
-		}
-	}
 	// simple types map 1:1 to go basic types
 	for _, v := range d.Schema.SimpleTypes {
-		ge.stypes[v.Name] = v
+		name := ge.cacheTypeName(v.TargetNamespace, v.Name)
+		if name != v.Name {
+			st := *v
+			st.Name = name
+			ge.declareAs(&st, v)
+			v = &st
+		}
+		ge.stypes[name] = v
 	}
 	// complex types are declared as go struct types
 	for _, v := range d.Schema.ComplexTypes {
-		ge.ctypes[v.Name] = v
+		name := ge.cacheTypeName(v.TargetNamespace, v.Name)
+		if name != v.Name {
+			ct := *v
+			ct.Name = name
+			ge.declareAs(&ct, v)
+			v = &ct
+		}
+		ge.ctypes[name] = v
+	}
+	// operation types are declared as go struct types, named after
+	// their elements unless taken by a type, or by the element of the
+	// same name of another namespace: elements and types have names of
+	// their own in the schema
+	spaces := make(map[string]string)
+	for _, v := range d.Schema.Elements {
+		if v.Type == "" && v.ComplexType != nil {
+			q := xml.Name{Space: ge.targetNamespace(v, ""), Local: v.Name}
+			name, renamed := ge.elementTypes[q]
+			if !renamed {
+				name = v.Name
+				if _, exists := ge.typeQNames[name]; exists {
+					name = ge.fixNameConflicts(name+"Element", "Element")
+					ge.elementTypes[q] = name
+				} else if space, exists := spaces[name]; exists && space != q.Space {
+					if prefix := ge.namespacePrefix(q.Space); prefix != "" {
+						name = ge.goSymbol(prefix) + ge.goSymbol(name)
+					}
+					name = ge.fixNameConflicts(name, "Element")
+					ge.elementTypes[q] = name
+				}
+			}
+			spaces[name] = q.Space
+			ct := *v.ComplexType
+			ct.Name = name
+			ge.declareAs(&ct, v)
+			ge.ctypes[name] = &ct
+		}
 	}
 	// cache elements from schema
-	ge.cacheElements(d.Schema.Elements)
+	ge.cacheElements(d.Schema.Elements, "")
 	// cache elements from complex types
 	for _, ct := range ge.ctypes {
-		ge.cacheComplexTypeElements(ct)
+		ge.cacheComplexTypeElements(ct, ge.targetNamespace(ct, ct.TargetNamespace))
 	}
+	ge.hoistAnonymousTypes()
+	ge.typeSymbols = nil
 }
 
-func (ge *goEncoder) cacheChoiceTypeElements(choice *wsdl.Choice) {
+func (ge *goEncoder) cacheChoiceTypeElements(choice *wsdl.Choice, space string) {
 	if choice != nil {
 		for _, cct := range choice.ComplexTypes {
-			ge.cacheComplexTypeElements(cct)
+			ge.cacheComplexTypeElements(cct, space)
 		}
-		ge.cacheElements(choice.Elements)
+		ge.cacheElements(choice.Elements, space)
 	}
 }
 
-func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
+// cacheComplexTypeElements caches the elements of ct, in the namespace
+// space, resolving their references in the scope of ct.
+func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType, space string) {
+	defer ge.inScope(ct)()
 	if ct.AllElements != nil {
-		ge.cacheElements(ct.AllElements)
+		ge.cacheElements(ct.AllElements, space)
 	}
 	if ct.Sequence != nil {
-		ge.cacheElements(ct.Sequence.Elements)
+		ge.cacheElements(ct.Sequence.Elements, space)
+		for _, seq := range ct.Sequence.Sequences {
+			ge.cacheElements(sequenceGroup(seq).elements, space)
+		}
 	}
 	if ct.Choice != nil {
-		ge.cacheElements(ct.Choice.Elements)
+		ge.cacheElements(ct.Choice.Elements, space)
 	}
 
 	cc := ct.ComplexContent
@@ -400,51 +1132,102 @@ func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
 		if cce != nil && cce.Sequence != nil {
 			seq := cce.Sequence
 			for _, cct := range seq.ComplexTypes {
-				ge.cacheComplexTypeElements(cct)
+				ge.cacheComplexTypeElements(cct, space)
 			}
-			ge.cacheElements(seq.Elements)
+			ge.cacheElements(seq.Elements, space)
 
 			//Add in Choice elements
 			for _, choice := range seq.Choices {
-				ge.cacheChoiceTypeElements(choice)
+				ge.cacheChoiceTypeElements(choice, space)
 			}
 		}
 		if cce != nil && cce.Choice != nil {
-			ge.cacheChoiceTypeElements(cce.Choice)
+			ge.cacheChoiceTypeElements(cce.Choice, space)
 		}
 	}
 }
 
-func (ge *goEncoder) cacheElements(ct []*wsdl.Element) {
+// elementType returns the type of the global element the qualified
+// name s refers to, which is s itself unless the type declared by the
+// element was renamed.
+func (ge *goEncoder) elementType(s string) string {
+	if name, ok := ge.elementTypes[ge.qname(s)]; ok {
+		return name
+	}
+	return s
+}
+
+// element returns the element cached that the qualified name s refers
+// to, or else the first one of its local name, e.g. when its prefix
+// isn't declared.
+func (ge *goEncoder) element(s string) (*wsdl.Element, bool) {
+	q := ge.qname(s)
+	if el, ok := ge.elements[q]; ok {
+		return el, true
+	}
+	el, ok := ge.elements[ge.elementNames[q.Local]]
+	return el, ok
+}
+
+// targetNamespace returns the target namespace of the schema that
+// declares v, a type or element, if known, or else space.
+func (ge *goEncoder) targetNamespace(v interface{}, space string) string {
+	if s, ok := ge.schemaOf[v]; ok {
+		return s.TargetNamespace
+	}
+	return space
+}
+
+// cacheElements caches the elements in ct, declared by their schemas,
+// or else in the namespace space. References are cached by the name of
+// the element they refer to.
+func (ge *goEncoder) cacheElements(ct []*wsdl.Element, space string) {
 	for _, el := range ct {
+		q := xml.Name{Space: ge.targetNamespace(el, space), Local: trimns(el.Name)}
+		if el.Ref != "" {
+			q = ge.qname(el.Ref)
+		}
 		if el.Name == "" || el.Type == "" {
 			if el.Ref == "" {
 				continue
 			}
-			el.Name = trimns(el.Ref)
-			el.Type = el.Name
+			el.Name = q.Local
+			el.Type = trimns(ge.elementType(el.Ref))
 		}
-		name := trimns(el.Name)
-		if _, exists := ge.elements[name]; exists {
+		if _, exists := ge.elements[q]; exists {
 			continue
 		}
-		ge.elements[name] = el
-		ct := el.ComplexType
-		if ct != nil {
-			ge.cacheElements(ct.AllElements)
+		ge.elements[q] = el
+		if _, exists := ge.elementNames[q.Local]; !exists {
+			ge.elementNames[q.Local] = q
+		}
+		if ct := el.ComplexType; ct != nil {
+			restore := ge.inScope(el)
+			ge.cacheElements(ct.AllElements, q.Space)
 			if ct.Sequence != nil {
-				ge.cacheElements(ct.Sequence.Elements)
+				ge.cacheElements(ct.Sequence.Elements, q.Space)
 			}
 			if ct.Choice != nil {
-				ge.cacheElements(ct.Choice.Elements)
+				ge.cacheElements(ct.Choice.Elements, q.Space)
 			}
+			restore()
 		}
 	}
 }
 
 func (ge *goEncoder) cacheFuncs(d *wsdl.Definitions) {
-	// operations are declared as boilerplate go functions
-	for _, v := range d.PortType.Operations {
+	// operations are declared as boilerplate go functions, and those
+	// of the same name are told apart by their input
+	count := make(map[string]int)
+	for _, v := range d.DefaultPortType().Operations {
+		count[v.Name]++
+	}
+	for _, v := range d.DefaultPortType().Operations {
+		if count[v.Name] > 1 {
+			v = ge.overload(v, count)
+		} else if promotedNames[ge.goSymbol(v.Name)] {
+			v = ge.rename(v, v.Name+"Operation", count)
+		}
 		ge.funcs[v.Name] = v
 	}
 	ge.funcnames = make([]string, len(ge.funcs))
@@ -463,17 +1246,104 @@ func (ge *goEncoder) cacheMessages(d *wsdl.Definitions) {
 }
 
 func (ge *goEncoder) cacheSOAPOperations(d *wsdl.Definitions) {
-	for _, v := range d.Binding.Operations {
+	// Binding operations of overloaded operations are in the same
+	// order as those of the port type.
+	seen := make(map[string]int)
+	for _, v := range d.DefaultBinding().Operations {
+		if names := ge.overloads[v.Name]; len(names) > 0 {
+			i := seen[v.Name]
+			seen[v.Name]++
+			if i >= len(names) {
+				continue
+			}
+			bo := *v
+			bo.Name = names[i]
+			v = &bo
+		}
+		if ge.funcs[v.Name] == nil {
+			ge.report(SkipEvent, d.DefaultBinding().Pos, "operation %q of binding %q isn't in port type %q", v.Name, d.DefaultBinding().Name, d.DefaultPortType().Name)
+			continue
+		}
 		ge.soapOps[v.Name] = v
 	}
 }
 
+// overload returns a copy of op, one of several operations of the same
+// name, named after the parts of its input, e.g. GetUserByIdAndName, or
+// else its input message. The names of operations in the port type are
+// counted in names, to avoid them.
+func (ge *goEncoder) overload(op *wsdl.Operation, names map[string]int) *wsdl.Operation {
+	var suffix string
+	if op.Input != nil {
+		if m, ok := ge.messages[trimns(op.Input.Message)]; ok && len(m.Parts) > 0 {
+			parts := make([]string, len(m.Parts))
+			for i, part := range m.Parts {
+				parts[i] = ge.goSymbol(part.Name)
+			}
+			suffix = "By" + strings.Join(parts, "And")
+		} else {
+			suffix = ge.goSymbol(op.Input.Message)
+		}
+	}
+	return ge.rename(op, op.Name+suffix, names)
+}
+
+// promotedNames are the names promoted to the generated clients by the
+// soap.Base they embed, which their methods can't have.
+var promotedNames = map[string]bool{"Base": true, "Client": true}
+
+// rename returns a copy of op named name, or else name followed by a
+// number if an operation has that name. Its binding operation and the
+// name sent to the service are still those of the WSDL.
+func (ge *goEncoder) rename(op *wsdl.Operation, name string, names map[string]int) *wsdl.Operation {
+	base := name
+	for i := 2; names[name] > 0 || ge.funcs[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	ge.overloads[op.Name] = append(ge.overloads[op.Name], name)
+	ge.wireNames[name] = op.Name
+	o := *op
+	o.Name = name
+	return &o
+}
+
+// wireName returns the name in the WSDL of the named operation, which
+// differs for overloaded operations.
+func (ge *goEncoder) wireName(name string) string {
+	if n, ok := ge.wireNames[name]; ok {
+		return n
+	}
+	return name
+}
+
 var interfaceTypeT = template.Must(template.New("interfaceType").Parse(`
-// New{{.Name}} creates an initializes a {{.Name}}.
-func New{{.Name}}(cli *soap.Client) {{.Name}} {
-	return &{{.Impl}}{cli}
+// {{.New}} creates an initializes a {{.Name}}.
+func {{.New}}(cli *soap.Client) {{.Name}} {
+	return &{{.Impl}}{soap.Base{Client: cli}}
 }
 
+// {{.New}}WithHeader creates a {{.Name}} that sends header,
+// e.g. of authentication, as the SOAP Header of all its requests,
+// rather than the Header of cli, which is left as is.
+func {{.New}}WithHeader(cli *soap.Client, header soap.Header) {{.Name}} {
+	return {{.New}}(cli.WithHeader(header))
+}
+{{if .Address}}
+// {{.NewImpl}} creates a {{.Name}} that calls the
+// service at the address of its WSDL port:
+//
+//	{{.Address}}
+//
+// Use {{.New}} to configure the client otherwise.
+func {{.NewImpl}}() {{.Name}} {
+	return {{.New}}(&soap.Client{
+		URL: {{printf "%q" .Address}},{{if .Namespace}}
+		Namespace: {{.Namespace}},{{end}}{{if .Namespaces}}
+		Namespaces: {{.Namespaces}},{{end}}{{if .SkipXMLType}}
+		SkipXMLType: true,{{end}}
+	})
+}
+{{end}}
 // {{.Name}} was auto-generated from WSDL
 // and defines interface for the remote service. Useful for testing.
 type {{.Name}} interface {
@@ -496,20 +1366,21 @@ func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error
 		op := ge.funcs[fn]
 		if _, exists := ge.soapOps[op.Name]; !exists {
 			// TODO: probably faulty wsdl?
+			ge.report(SkipEvent, operationPos(op), "operation %q isn't in binding %q", ge.wireName(op.Name), d.DefaultBinding().Name)
 			continue
 		}
 		inParams, err := ge.inputParams(op)
 		if err != nil {
 			return err
 		}
-		outParams, err := ge.outputParams(op)
+		outParams, err := ge.outputParams(d, op)
 		if err != nil {
 			return err
 		}
 		in, out := code(inParams), codeParams(outParams)
-		name := goSymbol(op.Name)
+		name := ge.goSymbol(op.Name)
 		var doc bytes.Buffer
-		ge.writeComments(&doc, name, op.Doc)
+		ge.writeComments(&doc, name, op.Doc.In(ge.docLang))
 		funcs[i] = &interfaceTypeFunc{
 			Doc:    doc.String(),
 			Name:   name,
@@ -518,49 +1389,90 @@ func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error
 		}
 		i++
 	}
-	n := d.PortType.Name
-	return interfaceTypeT.Execute(w, &struct {
-		Name  string
-		Impl  string // private type that implements the interface
-		Funcs []*interfaceTypeFunc
+	iface, impl := ge.portTypeNames(d)
+	namespace, namespaces := "", ""
+	if d.TargetNamespace != "" {
+		namespace = ge.namespaceVarName()
+	}
+	if len(ge.namespacePrefixes()) > 0 {
+		namespaces = ge.namespacesVarName()
+	}
+	return ge.template(interfaceTypeT).Execute(w, &struct {
+		Name       string
+		Impl       string // type that implements the interface
+		New        string // constructor of Name, and of NewWithHeader
+		NewImpl    string // constructor of Impl at the port address
+		Address    string // location of the service port, if any
+		Namespace  string // variable of the target namespace, if any
+		Namespaces string // variable of the namespace prefixes, if any
+		// whether requests aren't walked with reflection, see SetMinimal
+		SkipXMLType bool
+		Funcs       []*interfaceTypeFunc
 	}{
-		goSymbol(n),
-		strings.ToLower(n)[:1] + n[1:],
+		iface,
+		impl,
+		ge.constructorName(iface, "WithHeader"),
+		ge.constructorName(impl),
+		serviceAddress(d),
+		namespace,
+		namespaces,
+		ge.minimal,
 		funcs[:i],
 	})
 }
 
 var portTypeT = template.Must(template.New("portType").Parse(`
 // {{.Name}} implements the {{.Interface}} interface.
+//
+// Methods can be added to it in other files of this package, calling
+// the service through its Client field. Custom clients can embed it
+// to override or add methods:
+//
+//	type MyClient struct {
+//		*{{.Name}}
+//	}
 type {{.Name}} struct {
-	cli *soap.Client
+	soap.Base
 }
 
+// Checks at compile time that {{.Name}} implements {{.Interface}}.
+var _ {{.Interface}} = (*{{.Name}})(nil)
+
 `))
 
 func (ge *goEncoder) writePortType(w io.Writer, d *wsdl.Definitions) error {
 	if len(ge.funcs) == 0 {
 		return nil
 	}
-	n := d.PortType.Name
-	return portTypeT.Execute(w, &struct {
+	iface, impl := ge.portTypeNames(d)
+	return ge.template(portTypeT).Execute(w, &struct {
 		Name      string
 		Interface string
 	}{
-		strings.ToLower(n)[:1] + n[1:],
-		goSymbol(n),
+		impl,
+		iface,
 	})
 }
 
+// errorAt returns the error of format and args, as pos: error if the
+// position is known, where it's found in the WSDL or its schemas.
+func errorAt(pos wsdl.Pos, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if !pos.IsValid() {
+		return err
+	}
+	return fmt.Errorf("%s: %v", pos, err)
+}
+
 // writeGoFuncs writes Go function definitions from WSDL types to w.
 // Functions are written in the same order of the WSDL document.
 func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
-	if d.Binding.Type != "" {
-		a, b := trimns(d.Binding.Type), trimns(d.PortType.Name)
+	if d.DefaultBinding().Type != "" {
+		a, b := trimns(d.DefaultBinding().Type), trimns(d.DefaultPortType().Name)
 		if a != b {
-			return fmt.Errorf(
+			return errorAt(d.DefaultBinding().Pos,
 				"binding %q requires port type %q but it's not defined",
-				d.Binding.Name, d.Binding.Type)
+				d.DefaultBinding().Name, d.DefaultBinding().Type)
 		}
 	}
 	if len(ge.funcs) == 0 {
@@ -568,17 +1480,20 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 	}
 	for _, fn := range ge.funcnames {
 		op := ge.funcs[fn]
-		ge.writeComments(w, op.Name, op.Doc)
+		ge.writeComments(w, op.Name, op.Doc.In(ge.docLang))
 		inParams, err := ge.inputParams(op)
 		if err != nil {
 			return err
 		}
-		outParams, err := ge.outputParams(op)
+		outParams, err := ge.outputParams(d, op)
 		if err != nil {
 			return err
 		}
 
-		ok := ge.writeSOAPFunc(w, d, op, inParams, outParams)
+		ok, err := ge.writeSOAPFunc(w, d, op, inParams, outParams)
+		if err != nil {
+			return err
+		}
 		if !ok {
 			in, out := code(inParams), codeParams(outParams)
 			ret := make([]string, len(out))
@@ -590,7 +1505,7 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 			ge.needsStdPkg["context"] = true
 			in = append([]string{"ctx context.Context"}, in...)
 
-			fn := ge.fixFuncNameConflicts(goSymbol(op.Name))
+			fn := ge.fixFuncNameConflicts(ge.goSymbol(op.Name))
 			fmt.Fprintf(w, "func %s(%s) (%s) {\nreturn %s\n}\n\n",
 				fn,
 				strings.Join(in, ","),
@@ -605,11 +1520,13 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 var soapFuncT = template.Must(template.New("soapFunc").Parse(
 	`func (p *{{.PortType}}) {{.Name}}({{.Input}}) ({{.Output}}) {
 	α := struct {
-		{{if .OpInputDataType}}
-			{{if .RPCStyle}}M{{end}} {{.OpInputDataType}} ` + "`xml:\"{{.OpName}}\"`" + `
+		{{if .Encoded}}soap.Encoding
+		{{end}}{{if .OpInputDataType}}
+			{{if .RPCStyle}}M{{end}} {{.OpInputDataType}} {{if not .InputBare}}` + "`xml:\"{{.OpName}}\"`" + `{{end}}
 		{{end}}
 	}{
-		{{if .OpInputDataType}}{{.OpInputDataType}} {
+		{{if .Encoded}}soap.Encoded(),
+		{{end}}{{if .OpInputDataType}}{{.OpInputDataType}} {
 			{{range $index, $element := .InputNames}}{{$element}},
 			{{end}}
 		},{{end}}
@@ -617,24 +1534,37 @@ var soapFuncT = template.Must(template.New("soapFunc").Parse(
 
 	γ := struct {
 		{{if .OpResponseDataType}}
-			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} ` + "`xml:\"{{.OpResponseName}}\"`" + `
+			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} {{if not .OutputBare}}` + "`xml:\"{{.OpResponseName}}\"`" + `{{end}}
 		{{end}}
 	}{}
-	if err := p.cli.RoundTripWithAction("{{.Name}}", α, &γ); err != nil {
+	{{if .Raw}}ρ := new(soap.Response)
+	{{end}}{{if .MIME}}{{if .OpLabel}}start := time.Now()
+	{{end}}{{if .OutputAttachments}}β{{else}}_{{end}}, err := p.Client.RoundTripWithAttachments("{{.Name}}", α, {{if .Raw}}soap.WithResponse(&γ, ρ){{else}}&γ{{end}}{{range .Attachments}},
+		{{.}}{{end}})
+	{{if .OpLabel}}err = soap.Observe(p.Client, "{{.OpLabel}}", start, err)
+	{{end}}if err != nil {
 		return {{.RetDef}}
 	}
-	return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
+	{{else}}if err := {{if .OpLabel}}soap.Observe(p.Client, "{{.OpLabel}}", time.Now(), {{end}}p.Client.RoundTripWithAction("{{.Name}}", α, {{if .Raw}}soap.WithResponse(&γ, ρ){{else}}&γ{{end}}){{if .OpLabel}}){{end}}; err != nil {
+		return {{.RetDef}}
+	}
+	{{end}}{{if .Unwrapped}}if {{.Unwrapped}} == nil {
+		return {{.NilRet}}
+	}
+	{{end}}return {{range .OpOutputs}}{{.}}, {{end}}nil
 }
 `))
 
 var soapActionFuncT = template.Must(template.New("soapActionFunc").Parse(
 	`func (p *{{.PortType}}) {{.Name}}({{.Input}}) ({{.Output}}) {
 	α := struct {
-		{{if .OpInputDataType}}
-			{{if .RPCStyle}}M{{end}} {{.OpInputDataType}} ` + "`xml:\"{{.OpName}}\"`" + `
+		{{if .Encoded}}soap.Encoding
+		{{end}}{{if .OpInputDataType}}
+			{{if .RPCStyle}}M{{end}} {{.OpInputDataType}} {{if not .InputBare}}` + "`xml:\"{{.OpName}}\"`" + `{{end}}
 		{{end}}
 	}{
-		{{if .OpInputDataType}}{{.OpInputDataType}} {
+		{{if .Encoded}}soap.Encoded(),
+		{{end}}{{if .OpInputDataType}}{{.OpInputDataType}} {
 			{{range $index, $element := .InputNames}}{{$element}},
 			{{end}}
 		},{{end}}
@@ -642,41 +1572,79 @@ var soapActionFuncT = template.Must(template.New("soapActionFunc").Parse(
 
 	γ := struct {
 		{{if .OpResponseDataType}}
-			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} ` + "`xml:\"{{.OpResponseName}}\"`" + `
+			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} {{if not .OutputBare}}` + "`xml:\"{{.OpResponseName}}\"`" + `{{end}}
 		{{end}}
 	}{}
-	if err := p.cli.{{.RoundTripType}}("{{.Action}}", α, &γ); err != nil {
+	{{if .Raw}}ρ := new(soap.Response)
+	{{end}}{{if .MIME}}{{if .OpLabel}}start := time.Now()
+	{{end}}{{if .OutputAttachments}}β{{else}}_{{end}}, err := p.Client.RoundTripWithAttachments("{{.Action}}", α, {{if .Raw}}soap.WithResponse(&γ, ρ){{else}}&γ{{end}}{{range .Attachments}},
+		{{.}}{{end}})
+	{{if .OpLabel}}err = soap.Observe(p.Client, "{{.OpLabel}}", start, err)
+	{{end}}if err != nil {
 		return {{.RetDef}}
 	}
-	return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
+	{{else}}if err := {{if .OpLabel}}soap.Observe(p.Client, "{{.OpLabel}}", time.Now(), {{end}}p.Client.{{.RoundTripType}}("{{.Action}}", α, {{if .Raw}}soap.WithResponse(&γ, ρ){{else}}&γ{{end}}){{if .OpLabel}}){{end}}; err != nil {
+		return {{.RetDef}}
+	}
+	{{end}}{{if .Unwrapped}}if {{.Unwrapped}} == nil {
+		return {{.NilRet}}
+	}
+	{{end}}return {{range .OpOutputs}}{{.}}, {{end}}nil
 }
 `))
 
-func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, in, out []*parameter) bool {
+// isRPC reports whether the binding operation bo has the rpc style,
+// set in the operation or else in the binding. Operations of a binding
+// can have different styles, e.g. rpc/encoded and document/literal.
+func isRPC(d *wsdl.Definitions, bo *wsdl.BindingOperation) bool {
+	if bo != nil {
+		if style := bo.Operation11.Style; style != "" {
+			return style == "rpc"
+		}
+		if style := bo.Operation.Style; style != "" {
+			return style == "rpc"
+		}
+	}
+	return d.DefaultBinding().BindingType != nil && d.DefaultBinding().BindingType.Style == "rpc"
+}
+
+func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, in, out []*parameter) (bool, error) {
 	if _, exists := ge.soapOps[op.Name]; !exists {
 		// TODO: probably faulty wsdl?
-		return false
+		return false, nil
+	}
+	if hop := ge.httpOperation(d, op.Name); hop != nil {
+		return true, ge.writeHTTPFunc(w, d, op, hop, in, out)
 	}
 
 	// Do we need to wrap into a operation element?
-	rpcStyle := false
-
-	if d.Binding.BindingType != nil {
-		rpcStyle = d.Binding.BindingType.Style == "rpc"
-	}
+	rpcStyle := isRPC(d, ge.soapOps[op.Name])
 
 	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
 
-	// inputNames describe the accessors to the input parameter names
-	inputNames := make([]string, len(in))
-	for index, name := range in {
+	// inputNames describe the accessors to the input parameter names,
+	// and attachments the MIME parts sent along with them
+	inputNames := make([]string, 0, len(in))
+	attachments := []string{}
+	for _, name := range in {
 		returnVal := maskKeywordUsage(name.code)
 
+		if name.attachment != nil {
+			attachments = append(attachments, fmt.Sprintf(
+				"soap.Attachment{Name: %q, ContentType: %q, Data: %s}",
+				name.attachment.Part, name.attachment.Type, returnVal))
+			// v1 input wrappers keep a field for attachments, see
+			// genOpStructMessage.
+			if ge.compat != "v1" {
+				continue
+			}
+		}
+
 		if !strings.HasPrefix(name.dataType, "*") {
 			returnVal = "&" + returnVal
 		}
 
-		inputNames[index] = returnVal
+		inputNames = append(inputNames, returnVal)
 	}
 
 	// outputDataTypes describe the data types which are returned by the func
@@ -685,40 +1653,89 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 	// retDefaults describes the default return values in case of an error
 	retDefaults := make([]string, len(out))
 
-	// operationOutputNames describes the fields which are part of the response we unmarshal
+	// operationOutputs describes the fields which are part of the response we unmarshal,
+	// or the attachments received with it
 	// len-1, because the last parameter is error, which is not part of the xml response we unmarshal
-	operationOutputNames := make([]string, len(out)-1)
-	operationOutputPrefixes := make([]string, len(out)-1)
+	operationOutputs := make([]string, len(out)-1)
+	outputAttachments := false
+	unwrapped := ""
 
 	for index, name := range out {
 		outputDataTypes[index] = name.dataType
 
-		// operationOutputNames names will only be computed till len-1
+		// operationOutputs will only be computed till len-1
 		if index == len(out)-1 {
 			continue
 		}
 
-		operationOutputNames[index] = strings.ToUpper(name.code[:1]) + name.code[1:]
-		operationOutputPrefixes[index] = ""
 		retDefaults[index] = "nil"
 
+		if name.attachment != nil {
+			operationOutputs[index] = fmt.Sprintf("β.Data(%q)", name.attachment.Part)
+			outputAttachments = true
+			continue
+		}
+
+		if name.raw {
+			operationOutputs[index], retDefaults[index] = "ρ", "ρ"
+			continue
+		}
+
+		if name.response {
+			operationOutputs[index] = "&γ." + strings.TrimPrefix(name.dataType, "*")
+			if rpcStyle {
+				operationOutputs[index] = "&γ.M"
+			}
+			continue
+		}
+
+		field := "γ."
+		if rpcStyle {
+			field += "M."
+		}
+		field += ge.goSymbol(name.code)
+
+		if name.unwrap != nil {
+			unwrapped = field
+			operationOutputs[index] = field + "." + name.unwrap.Name
+			if !strings.HasPrefix(name.unwrap.Type, "*") && !strings.HasPrefix(name.unwrap.Type, "[]") {
+				operationOutputs[index] = "&" + operationOutputs[index]
+			}
+			continue
+		}
+
 		// If the output is >not< a pointer, we need to return the value of the response
 		if !strings.HasPrefix(name.dataType, "*") {
-			operationOutputPrefixes[index] = "*"
+			field = "*" + field
 
 			// Also - only resolve the default for non-pointer returns (otherwise nil suffices)
 			retDefaults[index] = ge.wsdl2goDefault(name.dataType)
 		}
+		operationOutputs[index] = field
 	}
 	retDefaults[len(retDefaults)-1] = "err"
 
-	// Check if we need to prefix the op with a namespace
+	// Unwrapped outputs are nil when their response element is absent.
+	nilRet := ""
+	if unwrapped != "" {
+		nilRet = strings.Join(retDefaults[:len(retDefaults)-1], ",") + ",nil"
+	}
+
+	// Operations with MIME bindings send and receive their attachments
+	// in multipart/related messages.
+	mime := len(attachments) > 0 || outputAttachments
+
+	// Check if we need to prefix the op with a namespace, which must be
+	// one of Namespaces, or tns, to be declared in the envelope. The
+	// wrapper of rpc operations is in the namespace of their soap:body.
 	mInput := ge.funcs[op.Name].Input
-	namespacedOpName := op.Name
+	namespacedOpName := ge.wireName(op.Name)
 
-	if mInput != nil {
+	if prefix := ge.bodyPrefix(d, ge.soapOps[op.Name]); prefix != "" {
+		namespacedOpName = prefix + ":" + namespacedOpName
+	} else if mInput != nil {
 		nsSplit := strings.Split(mInput.Message, ":")
-		if len(nsSplit) > 1 {
+		if _, ok := ge.usedNamespaces[nsSplit[0]]; (ok || nsSplit[0] == "tns") && len(nsSplit) > 1 {
 			namespacedOpName = nsSplit[0] + ":" + namespacedOpName
 		}
 	}
@@ -726,7 +1743,7 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 	// The response name is always the operation name + "Response" according to specification.
 	// Note, we also omit the namespace, since this does currently not work reliable with golang
 	// (See: https://github.com/golang/go/issues/14407)
-	opResponseName := op.Name + "Response"
+	opResponseName := ge.wireName(op.Name) + "Response"
 
 	// No-input operations can be inlined into an anonymous struct on rpc, and omitted otherwise
 	operationInputDataType := ""
@@ -746,6 +1763,18 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		operationInputDataType = "struct{}"
 	}
 
+	// Document/literal bare operations have their part elements
+	// serialized directly as children of the SOAP body.
+	inputBare, outputBare := ge.bareMessages(d, op)
+
+	_, impl := ge.portTypeNames(d)
+
+	// rpc/encoded operations declare the SOAP encoding in the body.
+	encoded := false
+	if bo := ge.soapOps[op.Name]; bo.Input != nil {
+		encoded = bo.Input.Use == "encoded"
+	}
+
 	soapFunctionName := "RoundTripSoap12"
 	soapAction := ""
 	if bindingOp, exists := ge.soapOps[op.Name]; exists {
@@ -755,8 +1784,17 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 			soapAction = bindingOp.Operation11.Action
 		}
 	}
+	if soapFunctionName == "RoundTripWithAction" && !mime && usesAddressing(d) {
+		soapFunctionName = "RoundTripWithAddressing"
+		soapAction = addressingAction(d, ge.wireName(op.Name), op, soapAction)
+	}
+	opLabel := ""
+	if ge.opLabels {
+		opLabel = operationLabel(d, op)
+		ge.needsStdPkg["time"] = true
+	}
 	if soapAction != "" {
-		soapActionFuncT.Execute(w, &struct {
+		err := ge.template(soapActionFuncT).Execute(w, &struct {
 			RoundTripType      string
 			Action             string
 			PortType           string
@@ -766,32 +1804,50 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 			InputNames         []string
 			OpResponseName     string
 			OpResponseDataType string
-			OpOutputNames      []string
-			OpOutputPrefixes   []string
+			OpOutputs          []string
 			Input              string
 			Output             string
 			RetDef             string
 			RPCStyle           bool
+			InputBare          bool
+			OutputBare         bool
+			Encoded            bool
+			MIME               bool
+			Attachments        []string
+			OutputAttachments  bool
+			OpLabel            string
+			Raw                bool
+			Unwrapped          string
+			NilRet             string
 		}{
 			soapFunctionName,
 			soapAction,
-			strings.ToLower(d.PortType.Name[:1]) + d.PortType.Name[1:],
-			goSymbol(op.Name),
+			impl,
+			ge.goSymbol(op.Name),
 			namespacedOpName,
 			operationInputDataType,
 			inputNames,
 			opResponseName,
 			operationOutputDataType,
-			operationOutputNames,
-			operationOutputPrefixes,
+			operationOutputs,
 			strings.Join(code(in), ","),
 			strings.Join(outputDataTypes, ","),
 			strings.Join(retDefaults, ","),
 			rpcStyle,
+			inputBare,
+			outputBare,
+			encoded,
+			mime,
+			attachments,
+			outputAttachments,
+			opLabel,
+			ge.rawResponse,
+			unwrapped,
+			nilRet,
 		})
-		return true
+		return true, err
 	}
-	soapFuncT.Execute(w, &struct {
+	err := ge.template(soapFuncT).Execute(w, &struct {
 		PortType           string
 		Name               string
 		OpName             string
@@ -799,28 +1855,173 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		InputNames         []string
 		OpResponseName     string
 		OpResponseDataType string
-		OpOutputNames      []string
-		OpOutputPrefixes   []string
+		OpOutputs          []string
 		Input              string
 		Output             string
 		RetDef             string
 		RPCStyle           bool
+		InputBare          bool
+		OutputBare         bool
+		Encoded            bool
+		MIME               bool
+		Attachments        []string
+		OutputAttachments  bool
+		OpLabel            string
+		Raw                bool
+		Unwrapped          string
+		NilRet             string
 	}{
-		strings.ToLower(d.PortType.Name[:1]) + d.PortType.Name[1:],
-		goSymbol(op.Name),
+		impl,
+		ge.goSymbol(op.Name),
 		namespacedOpName,
 		operationInputDataType,
 		inputNames,
 		opResponseName,
 		operationOutputDataType,
-		operationOutputNames,
-		operationOutputPrefixes,
+		operationOutputs,
 		strings.Join(code(in), ","),
 		strings.Join(outputDataTypes, ","),
 		strings.Join(retDefaults, ","),
 		rpcStyle,
+		inputBare,
+		outputBare,
+		encoded,
+		mime,
+		attachments,
+		outputAttachments,
+		opLabel,
+		ge.rawResponse,
+		unwrapped,
+		nilRet,
 	})
-	return true
+	return true, err
+}
+
+// usesAddressing reports whether the binding of d uses WS-Addressing,
+// as declared by wsaw:UsingAddressing or a policy with an addressing
+// assertion.
+func usesAddressing(d *wsdl.Definitions) bool {
+	if d.DefaultBinding().UsingAddressing != nil {
+		return true
+	}
+	for _, p := range d.DefaultBinding().Policies {
+		if p.Addressing {
+			return true
+		}
+	}
+	for _, ref := range d.DefaultBinding().PolicyReferences {
+		if p := d.Policy(ref.URI); p != nil && p.Addressing {
+			return true
+		}
+	}
+	return false
+}
+
+// addressingAction returns the WS-Addressing action of the input of
+// op, named name in the WSDL: the one set in the WSDL, or else the SOAP
+// action of the binding, or else the default action of WS-Addressing
+// Metadata.
+func addressingAction(d *wsdl.Definitions, name string, op *wsdl.Operation, soapAction string) string {
+	if op.Input != nil && op.Input.Action != "" {
+		return op.Input.Action
+	}
+	if soapAction != "" {
+		return soapAction
+	}
+	delim := "/"
+	if strings.HasPrefix(d.TargetNamespace, "urn:") {
+		delim = ":"
+	}
+	return strings.TrimSuffix(d.TargetNamespace, delim) + delim +
+		d.DefaultPortType().Name + delim + name + "Request"
+}
+
+// operationLabel returns the name of op as service.port.operation, for
+// labels of metrics. The port is the one of the service bound to the
+// binding of d, or else the binding itself.
+func operationLabel(d *wsdl.Definitions, op *wsdl.Operation) string {
+	service := d.DefaultService().Name
+	if service == "" {
+		service = d.Name
+	}
+	port := d.DefaultBinding().Name
+	for _, p := range d.DefaultService().Ports {
+		if trimns(p.Binding) == d.DefaultBinding().Name {
+			port = p.Name
+			break
+		}
+	}
+	return labelName(service) + "." + labelName(port) + "." + labelName(op.Name)
+}
+
+// serviceAddress returns the location of the service port bound to the
+// binding of d, or else of the first port that has one.
+func serviceAddress(d *wsdl.Definitions) string {
+	for _, p := range d.DefaultService().Ports {
+		if trimns(p.Binding) == d.DefaultBinding().Name && p.Address.Location != "" {
+			return p.Address.Location
+		}
+	}
+	for _, p := range d.DefaultService().Ports {
+		if p.Address.Location != "" {
+			return p.Address.Location
+		}
+	}
+	return ""
+}
+
+// labelName converts s to snake case, with lower case letters, digits
+// and underscores only, e.g. GetHTTPStatus to get_http_status.
+func labelName(s string) string {
+	var b strings.Builder
+	r := []rune(s)
+	for i, c := range r {
+		switch {
+		case c < unicode.MaxASCII && unicode.IsUpper(c):
+			if i > 0 && (unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1]) ||
+				unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(c))
+		case c < unicode.MaxASCII && (unicode.IsLower(c) || unicode.IsDigit(c)):
+			b.WriteRune(c)
+		default:
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// isBareMessage reports whether m is a document/literal bare message:
+// all of its parts refer to schema elements, and they are not a single
+// wrapper element named after the operation.
+func (ge *goEncoder) isBareMessage(m *wsdl.Message, wrapper string) bool {
+	if m == nil || len(m.Parts) == 0 {
+		return false
+	}
+	for _, part := range m.Parts {
+		if part.Element == "" {
+			return false
+		}
+	}
+	return len(m.Parts) > 1 || trimns(m.Parts[0].Element) != wrapper
+}
+
+// bareMessages reports whether the input and output messages of op
+// are document/literal bare messages, see isBareMessage.
+func (ge *goEncoder) bareMessages(d *wsdl.Definitions, op *wsdl.Operation) (input, output bool) {
+	if isRPC(d, ge.soapOps[op.Name]) {
+		return false, false
+	}
+	if op.Input != nil {
+		input = ge.isBareMessage(ge.messages[trimns(op.Input.Message)], op.Name)
+	}
+	if op.Output != nil {
+		output = ge.isBareMessage(ge.messages[trimns(op.Output.Message)], ge.wireName(op.Name)+"Response")
+	}
+	return input, output
 }
 
 func renameParam(p, name string) string {
@@ -839,16 +2040,22 @@ func (ge *goEncoder) inputParams(op *wsdl.Operation) ([]*parameter, error) {
 	im := trimns(op.Input.Message)
 	req, ok := ge.messages[im]
 	if !ok {
-		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, im)
+		return nil, errorAt(op.Input.Pos, "operation %q wants input message %q but it's not defined", op.Name, im)
 	}
 
 	// TODO: I had to disable this for my use case - do other use cases still work with false?
-	return ge.genParams(req, false), nil
+	params := ge.genParams(req, false)
+	bindAttachments(params, req, ge.attachments(op.Name, false))
+	return params, nil
 }
 
 // returns list of function output parameters plus error.
-func (ge *goEncoder) outputParams(op *wsdl.Operation) ([]*parameter, error) {
+func (ge *goEncoder) outputParams(d *wsdl.Definitions, op *wsdl.Operation) ([]*parameter, error) {
 	out := []*parameter{{code: "err", dataType: "error"}}
+	if ge.rawResponse {
+		ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+		out = append([]*parameter{{code: "raw", dataType: "*soap.Response", raw: true}}, out...)
+	}
 
 	if op.Output == nil {
 		return out, nil
@@ -856,9 +2063,113 @@ func (ge *goEncoder) outputParams(op *wsdl.Operation) ([]*parameter, error) {
 	om := trimns(op.Output.Message)
 	resp, ok := ge.messages[om]
 	if !ok {
-		return nil, fmt.Errorf("operation %q wants output message %q but it's not defined", op.Name, om)
+		return nil, errorAt(op.Output.Pos, "operation %q wants output message %q but it's not defined", op.Name, om)
+	}
+	params := ge.genParams(resp, false)
+	bindAttachments(params, resp, ge.attachments(op.Name, true))
+	// Wrappers are generated for the operations of the binding, others
+	// are generated as stubs, see writePlaceholders.
+	_, bound := ge.soapOps[op.Name]
+	if ge.responseWrapper && len(params) > 0 && bound && ge.httpOperation(d, op.Name) == nil {
+		for _, p := range params {
+			if p.attachment != nil {
+				return append(params, out...), nil
+			}
+		}
+		// The operation returns its output wrapper as is.
+		name := ge.sanitizedOperationsType(resp.Name)
+		params = []*parameter{{code: "resp", dataType: "*" + name, response: true}}
+	} else if ge.unwrap && len(params) == 1 && params[0].attachment == nil &&
+		!isRPC(d, ge.soapOps[op.Name]) && ge.httpOperation(d, op.Name) == nil {
+		if f := ge.unwrapField(resp); f != nil {
+			typ := f.Type
+			if !strings.HasPrefix(typ, "*") && !strings.HasPrefix(typ, "[]") {
+				typ = "*" + typ
+			}
+			params[0] = &parameter{code: params[0].code, dataType: typ, unwrap: f}
+		}
+	}
+	return append(params, out...), nil
+}
+
+// unwrapField returns the field of the single element of the response
+// element of the document/literal message m, if that's all it has.
+func (ge *goEncoder) unwrapField(m *wsdl.Message) *structField {
+	if len(m.Parts) != 1 || m.Parts[0].Element == "" {
+		return nil
+	}
+	name := ge.typeName(ge.elementType(m.Parts[0].Element))
+	if el, ok := ge.element(m.Parts[0].Element); ok {
+		restore := ge.inScope(el)
+		name = ge.typeName(el.Type)
+		restore()
+	}
+	ct, ok := ge.ctypes[name]
+	if !ok || ct.Choice != nil || len(ct.Attributes) > 0 ||
+		ct.ComplexContent != nil || ct.SimpleContent != nil {
+		return nil
+	}
+	elements := ct.AllElements
+	if seq := ct.Sequence; seq != nil {
+		if len(elements) > 0 || len(seq.Any) > 0 || len(seq.Choices) > 0 || len(seq.ComplexTypes) > 0 {
+			return nil
+		}
+		elements = seq.Elements
+	}
+	if len(elements) != 1 {
+		return nil
+	}
+	defer ge.inScope(ct)()
+	inner := elements[0]
+	if inner.Ref != "" {
+		if inner, ok = ge.element(inner.Ref); !ok {
+			return nil
+		}
+		defer ge.inScope(inner)()
+	}
+	// The type of the field is the one of the struct generated from ct.
+	structName, ptrFields := ge.structName, ge.ptrFields
+	defer func() { ge.structName, ge.ptrFields = structName, ptrFields }()
+	ge.structName, ge.ptrFields = ge.goSymbol(ct.Name), false
+	inner, slice, typ, _ := ge.elementField(inner)
+	return &structField{Name: ge.goSymbol(inner.Name), Type: slice + typ}
+}
+
+// attachments returns the message parts of the named operation's input
+// or output that are bound to MIME content, by part name.
+func (ge *goEncoder) attachments(opName string, output bool) map[string]*wsdl.MIMEContent {
+	bo, ok := ge.soapOps[opName]
+	if !ok {
+		return nil
+	}
+	mm := bo.InputMIME
+	if output {
+		mm = bo.OutputMIME
+	}
+	if mm == nil {
+		return nil
+	}
+	contents := make(map[string]*wsdl.MIMEContent)
+	for _, part := range mm.Parts {
+		for _, c := range part.Contents {
+			// Alternative contents of the same part only differ in type.
+			if _, exists := contents[c.Part]; !exists {
+				contents[c.Part] = c
+			}
+		}
+	}
+	return contents
+}
+
+// bindAttachments makes the parameters generated from the parts of m
+// that are bound to MIME content raw attachment data.
+func bindAttachments(params []*parameter, m *wsdl.Message, contents map[string]*wsdl.MIMEContent) {
+	for i, part := range m.Parts {
+		if c, ok := contents[part.Name]; ok {
+			params[i].dataType = "[]byte"
+			params[i].attachment = c
+		}
 	}
-	return append(ge.genParams(resp, false), out[0]), nil
 }
 
 var isGoKeyword = map[string]bool{
@@ -890,9 +2201,13 @@ var isGoKeyword = map[string]bool{
 }
 
 type parameter struct {
-	code     string
-	dataType string
-	xmlToken string
+	code       string
+	dataType   string
+	xmlToken   string
+	attachment *wsdl.MIMEContent // MIME content binding, if any
+	response   bool              // output wrapper, see SetResponseWrapper
+	raw        bool              // raw response, see SetRawResponse
+	unwrap     *structField      // field of the output returned, see SetUnwrap
 }
 
 func code(list []*parameter) []string {
@@ -933,11 +2248,13 @@ func (ge *goEncoder) genParams(m *wsdl.Message, needsTag bool) []*parameter {
 			token = t
 		case param.Element != "":
 			elName = trimns(param.Element)
-			code = goSymbol(param.Element)
-			if el, ok := ge.elements[elName]; ok {
-				t = ge.wsdl2goType(trimns(el.Type))
+			code = ge.goSymbol(param.Element)
+			if el, ok := ge.element(param.Element); ok {
+				restore := ge.inScope(el)
+				t = ge.wsdl2goType(el.Type)
+				restore()
 			} else {
-				t = ge.wsdl2goType(param.Element)
+				t = ge.wsdl2goType(ge.elementType(param.Element))
 			}
 			token = trimns(param.Element)
 		}
@@ -952,17 +2269,155 @@ func (ge *goEncoder) genParams(m *wsdl.Message, needsTag bool) []*parameter {
 
 // Fixes conflicts between function and type names.
 func (ge *goEncoder) fixFuncNameConflicts(name string) string {
+	return ge.fixNameConflicts(name, "Func")
+}
+
+// Fixes conflicts between generated identifiers and type names, by
+// appending suffix to name until it no longer matches a type.
+func (ge *goEncoder) fixNameConflicts(name, suffix string) string {
+	for ge.isTypeName(name) {
+		name += suffix
+	}
+	return name
+}
+
+// builtinTypeName returns the name of the type generated for the XSD
+// type name, such as Date, renamed if the schema declares a type of
+// the same name.
+func (ge *goEncoder) builtinTypeName(name string) string {
+	return ge.fixNameConflicts(name, "Type")
+}
+
+// isTypeName reports whether name is declared as a Go type generated
+// from the schema.
+func (ge *goEncoder) isTypeName(name string) bool {
 	if _, exists := ge.stypes[name]; exists {
-		name += "Func"
-		return ge.fixFuncNameConflicts(name)
+		return true
 	}
 	if _, exists := ge.ctypes[name]; exists {
+		return true
+	}
+	if ge.typeSymbols == nil {
+		ge.typeSymbols = make(map[string]bool, len(ge.stypes)+len(ge.ctypes))
+		for k := range ge.stypes {
+			ge.typeSymbols[ge.goSymbol(k)] = true
+		}
+		for k := range ge.ctypes {
+			ge.typeSymbols[ge.goSymbol(k)] = true
+		}
+	}
+	return ge.typeSymbols[name]
+}
+
+// constructorName returns the name of the generated constructor of
+// typ, NewTyp, renamed if it or its variants, NewTyp followed by any of
+// suffixes, are schema type names.
+func (ge *goEncoder) constructorName(typ string, suffixes ...string) string {
+	taken := func(name string) bool {
+		for _, suffix := range append([]string{""}, suffixes...) {
+			if ge.isTypeName(name + suffix) {
+				return true
+			}
+		}
+		return false
+	}
+	name := "New" + typ
+	for taken(name) {
 		name += "Func"
-		return ge.fixFuncNameConflicts(name)
 	}
 	return name
 }
 
+// portTypeNames returns the names of the generated interface for the
+// port type and of the client type implementing it.
+func (ge *goEncoder) portTypeNames(d *wsdl.Definitions) (iface, impl string) {
+	n := d.DefaultPortType().Name
+	if ge.isTypeName(ge.goSymbol(n)) {
+		n = ge.fixNameConflicts(ge.goSymbol(n)+"PortType", "PortType")
+	}
+	iface = ge.goSymbol(n)
+	return iface, ge.fixNameConflicts(iface+"Client", "Client")
+}
+
+// addSchemaVersion records the version of the schema of namespace ns,
+// unless already recorded.
+func (ge *goEncoder) addSchemaVersion(ns, version string) {
+	for _, v := range ge.schemaVersions {
+		if v.Namespace == ns {
+			return
+		}
+	}
+	ge.schemaVersions = append(ge.schemaVersions, schemaVersion{ns, version})
+}
+
+// writeVersions writes constants with the version attributes of the
+// WSDL document and its schemas. The schema version is SchemaVersion,
+// or if there are several schemas with versions, SchemaVersion followed
+// by the prefix of their namespace.
+func (ge *goEncoder) writeVersions(w io.Writer, d *wsdl.Definitions) {
+	if d.Version != "" {
+		name := ge.fixNameConflicts("WSDLVersion", "Const")
+		ge.writeComments(w, name, name+" is the version of the WSDL document.")
+		fmt.Fprintf(w, "const %s = %q\n\n", name, d.Version)
+	}
+	names := make(map[string]bool)
+	for i, v := range ge.schemaVersions {
+		name := "SchemaVersion"
+		if len(ge.schemaVersions) > 1 {
+			prefix := ge.namespacePrefix(v.Namespace)
+			if prefix == "" {
+				prefix = strconv.Itoa(i + 1)
+			}
+			name += ge.goSymbol(prefix)
+		}
+		name = ge.fixNameConflicts(name, "Const")
+		for names[name] {
+			name += "Const"
+		}
+		names[name] = true
+		comment := name + " is the version of the schema"
+		if v.Namespace != "" {
+			comment += " of namespace " + v.Namespace
+		}
+		ge.writeComments(w, name, comment+".")
+		fmt.Fprintf(w, "const %s = %q\n\n", name, v.Version)
+	}
+}
+
+// writeActions writes constants with the soapAction of each operation,
+// named after the operation, e.g. GetQuoteAction, for custom transports
+// and dispatchers.
+func (ge *goEncoder) writeActions(w io.Writer) {
+	var b bytes.Buffer
+	for _, fn := range ge.funcnames {
+		bo, ok := ge.soapOps[ge.funcs[fn].Name]
+		if !ok {
+			continue
+		}
+		action := bo.Operation.Action
+		if action == "" {
+			action = bo.Operation11.Action
+		}
+		if action == "" {
+			continue
+		}
+		name := ge.fixNameConflicts(ge.goSymbol(bo.Name)+"Action", "Const")
+		fmt.Fprintf(&b, "%s = %q\n", name, action)
+	}
+	if b.Len() == 0 {
+		return
+	}
+	fmt.Fprint(w, "// SOAP actions of the operations, by name.\nconst (\n")
+	b.WriteTo(w)
+	fmt.Fprint(w, ")\n\n")
+}
+
+// namespaceVarName returns the name of the variable holding the target
+// namespace.
+func (ge *goEncoder) namespaceVarName() string {
+	return ge.fixNameConflicts("Namespace", "Var")
+}
+
 // Fixes request and response parameters with the same name, in place.
 // Each string in the slice consists of Go's "name Type", we only
 // compare names. In case of a conflict, we set the response one
@@ -974,7 +2429,7 @@ func (ge *goEncoder) fixParamConflicts(req, resp []string) {
 			y := strings.SplitN(b, " ", 2)
 			if len(y) > 1 {
 				if x == y[0] {
-					n := goSymbol(y[0])
+					n := ge.goSymbol(y[0])
 					resp[j] = "resp" + n + " " + y[1]
 				}
 			}
@@ -987,19 +2442,29 @@ func (ge *goEncoder) fixParamConflicts(req, resp []string) {
 // E.g. - a soap operation gkstServer_getVersion is sanitized
 // to gkstServerGetVersion (remove snake case)
 func (ge *goEncoder) sanitizedOperationsType(opName string) string {
-	return "Operation" + goSymbol(opName)
+	return ge.fixNameConflicts("Operation"+ge.goSymbol(opName), "Op")
 }
 
 // Converts types from wsdl type to Go type.
 func (ge *goEncoder) wsdl2goType(t string) string {
 	// TODO: support other types.
 	v := trimns(t)
-	if _, exists := ge.stypes[v]; exists {
-		return goSymbol(v)
+	if !ge.isXSDType(t) {
+		name := ge.typeName(t)
+		if _, exists := ge.stypes[name]; exists {
+			return ge.goSymbol(name)
+		}
+		if _, exists := ge.ctypes[name]; exists {
+			return "*" + ge.goSymbol(name)
+		}
 	}
 	switch strings.ToLower(v) {
 	case "byte", "unsignedbyte":
 		return "byte"
+	case "short":
+		return "int16"
+	case "unsignedshort":
+		return "uint16"
 	case "int":
 		return "int"
 	case "integer":
@@ -1010,16 +2475,17 @@ func (ge *goEncoder) wsdl2goType(t string) string {
 		return "float64"
 	case "boolean":
 		return "bool"
-	case "hexbinary", "base64binary":
+	case "hexbinary", "base64binary", "base64": // base64 is from SOAP encoding
 		return "[]byte"
-	case "string", "anyuri", "token", "nmtoken", "qname", "language", "id":
+	case "string", "anyuri", "token", "nmtoken", "qname", "language", "id",
+		"name", "ncname", "idref", "entity":
 		return "string"
 	case "date":
 		ge.needsDateType = true
-		return "Date"
+		return ge.builtinTypeName("Date")
 	case "time":
 		ge.needsTimeType = true
-		return "Time"
+		return ge.builtinTypeName("Time")
 	case "nonnegativeinteger":
 		return "uint"
 	case "positiveinteger":
@@ -1028,16 +2494,105 @@ func (ge *goEncoder) wsdl2goType(t string) string {
 		return "string"
 	case "unsignedint":
 		return "uint"
+	case "unsignedlong":
+		return "uint64"
+	case "negativeinteger", "nonpositiveinteger":
+		return "int64"
 	case "datetime":
 		ge.needsDateTimeType = true
-		return "DateTime"
+		return ge.builtinTypeName("DateTime")
 	case "duration":
 		ge.needsDurationType = true
-		return "Duration"
-	case "anysequence", "anytype", "anysimpletype":
+		return ge.builtinTypeName("Duration")
+	case "anysequence":
+		ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+		return "soap.AnyElement"
+	case "anytype", "anysimpletype":
 		return "interface{}"
 	default:
-		return "*" + goSymbol(v)
+		ge.unresolvedType(t)
+		return "*" + ge.goSymbol(v)
+	}
+}
+
+// unresolvedType records the qualified name t of a type that can't be
+// found, to be reported by Encode in strict mode, or generated as a
+// placeholder in lenient mode. Names that only differ in case from a
+// declared type resolve to the same Go type, and are fine.
+func (ge *goEncoder) unresolvedType(t string) {
+	if !ge.strict && !ge.lenient {
+		return
+	}
+	sym := ge.goSymbol(trimns(t))
+	for name := range ge.ctypes {
+		if ge.goSymbol(name) == sym {
+			return
+		}
+	}
+	for name := range ge.stypes {
+		if ge.goSymbol(name) == sym {
+			return
+		}
+	}
+	if ge.unresolved == nil {
+		ge.unresolved = make(map[string]string)
+		ge.unresolvedPos = make(map[string]wsdl.Pos)
+	}
+	name := t
+	if q := ge.qname(t); q.Space != "" {
+		name = "{" + q.Space + "}" + q.Local
+	}
+	if _, ok := ge.unresolved[name]; !ok {
+		ge.unresolvedPos[name] = ge.pos
+	}
+	ge.unresolved[name] = sym
+}
+
+// unresolvedNames returns the sorted qualified names of the types
+// recorded by unresolvedType.
+func (ge *goEncoder) unresolvedNames() []string {
+	names := make([]string, 0, len(ge.unresolved))
+	for name := range ge.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unresolvedErr returns the error of the types recorded by
+// unresolvedType in strict mode, if any.
+func (ge *goEncoder) unresolvedErr() error {
+	if !ge.strict || len(ge.unresolved) == 0 {
+		return nil
+	}
+	names := ge.unresolvedNames()
+	for i, name := range names {
+		if pos := ge.unresolvedPos[name]; pos.IsValid() {
+			names[i] += " (" + pos.String() + ")"
+		}
+	}
+	return fmt.Errorf("unresolved types: %s", strings.Join(names, ", "))
+}
+
+// writePlaceholders writes the placeholders of the types recorded by
+// unresolvedType in lenient mode, and warns about them.
+func (ge *goEncoder) writePlaceholders(w io.Writer) {
+	if !ge.lenient || len(ge.unresolved) == 0 {
+		return
+	}
+	names := ge.unresolvedNames()
+	ge.report(WarningEvent, wsdl.Pos{}, "placeholders generated for types not found: %s", strings.Join(names, ", "))
+	written := make(map[string]bool)
+	for _, name := range names {
+		sym := ge.unresolved[name]
+		if written[sym] {
+			continue
+		}
+		written[sym] = true
+		fmt.Fprintf(w, "// %s is a placeholder for the type %s,\n", sym, name)
+		fmt.Fprintf(w, "// which wasn't found, e.g. because its schema couldn't be imported.\n")
+		fmt.Fprintf(w, "// It keeps the raw XML of its elements.\n")
+		fmt.Fprintf(w, "type %s struct {\nRaw []byte `xml:\",innerxml\"`\n}\n\n", sym)
 	}
 }
 
@@ -1052,7 +2607,7 @@ func (ge *goEncoder) wsdl2goDefault(t string) string {
 		return `errors.New("not implemented")`
 	case "bool":
 		return "false"
-	case "uint", "int", "int64", "float64":
+	case "int16", "uint16", "uint", "int", "uint64", "int64", "float64":
 		return "0"
 	case "string":
 		return `""`
@@ -1077,6 +2632,7 @@ func (ge *goEncoder) renameType(old, name string) {
 	ct.Name = name
 	delete(ge.ctypes, old)
 	ge.ctypes[name] = ct
+	ge.typeSymbols = nil
 }
 
 // writeGoTypes writes Go types from WSDL types to w.
@@ -1085,41 +2641,32 @@ func (ge *goEncoder) renameType(old, name string) {
 // generate, simple types, then complex types.
 func (ge *goEncoder) writeGoTypes(w io.Writer, d *wsdl.Definitions) error {
 	var b bytes.Buffer
+	var err error
 	for _, name := range ge.sortedSimpleTypes() {
-		st := ge.stypes[name]
-		stname := goSymbol(st.Name)
-		if st.Restriction != nil {
-			ge.writeComments(&b, stname, "")
-			fmt.Fprintf(&b, "type %s %s\n\n", stname, ge.wsdl2goType(st.Restriction.Base))
-			ge.genValidator(&b, stname, st.Restriction)
-		} else if st.Union != nil {
-			types := strings.Split(st.Union.MemberTypes, " ")
-			ntypes := make([]string, len(types))
-			for i, t := range types {
-				t = strings.TrimSpace(t)
-				if t == "" {
-					continue
-				}
-				ntypes[i] = ge.wsdl2goType(t)
-			}
-			doc := stname + " is a union of: " + strings.Join(ntypes, ", ")
-			ge.writeComments(&b, stname, doc)
-			fmt.Fprintf(&b, "type %s interface{}\n\n", stname)
+		if err = ge.genSimpleType(&b, ge.stypes[name]); err != nil {
+			return err
 		}
 	}
-	var err error
 	for _, name := range ge.sortedComplexTypes() {
 		ct := ge.ctypes[name]
 		err = ge.genGoStruct(&b, d, ct)
 		if err != nil {
 			return err
 		}
-		ge.genGoXMLTypeFunction(&b, ct)
+		ge.registerXMLType(ct)
+	}
+	err = ge.genTypeRegistry(&b)
+	if err != nil {
+		return err
 	}
 
 	// Operation wrappers - mainly used for rpc, not exclusively
 	for _, name := range ge.sortedOperations() {
 		ct := ge.soapOps[name]
+		if ge.httpOperation(d, name) != nil {
+			// HTTP operations send their parts unwrapped
+			continue
+		}
 
 		err = ge.genGoOpStruct(&b, d, ct)
 		if err != nil {
@@ -1132,6 +2679,37 @@ func (ge *goEncoder) writeGoTypes(w io.Writer, d *wsdl.Definitions) error {
 	return err
 }
 
+// genSimpleType writes the Go type of the simple type st to w.
+func (ge *goEncoder) genSimpleType(w io.Writer, st *wsdl.SimpleType) error {
+	defer ge.inScope(st)()
+	defer func(pos wsdl.Pos) { ge.pos = pos }(ge.pos)
+	ge.pos = st.Pos
+	stname := ge.goSymbol(st.Name)
+	if st.Restriction != nil {
+		ge.writeComments(w, stname, "")
+		fmt.Fprintf(w, "type %s %s\n\n", stname, ge.wsdl2goType(st.Restriction.Base))
+		if err := ge.genValidator(w, stname, st.Restriction); err != nil {
+			return err
+		}
+		return ge.genEnumString(w, stname, st.Restriction)
+	}
+	if st.Union != nil {
+		types := strings.Split(st.Union.MemberTypes, " ")
+		ntypes := make([]string, len(types))
+		for i, t := range types {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			ntypes[i] = ge.wsdl2goType(t)
+		}
+		doc := stname + " is a union of: " + strings.Join(ntypes, ", ")
+		ge.writeComments(w, stname, doc)
+		fmt.Fprintf(w, "type %s interface{}\n\n", stname)
+	}
+	return nil
+}
+
 func (ge *goEncoder) sortedSimpleTypes() []string {
 	keys := make([]string, len(ge.stypes))
 	i := 0
@@ -1173,23 +2751,22 @@ func (ge *goEncoder) genDateTypes(w io.Writer) {
 	}{
 		{
 			needs: ge.needsDateType,
-			name:  "Date",
-			code:  "type Date string\n\n",
+			name:  ge.builtinTypeName("Date"),
+			code:  dateJSON,
 		},
 		{
 			needs: ge.needsTimeType,
-			name:  "Time",
-			code:  "type Time string\n\n",
+			name:  ge.builtinTypeName("Time"),
+			code:  timeJSON,
 		},
 		{
 			needs: ge.needsDateTimeType,
-			name:  "DateTime",
-			code:  "type DateTime string\n\n",
+			name:  ge.builtinTypeName("DateTime"),
 		},
 		{
 			needs: ge.needsDurationType,
-			name:  "Duration",
-			code:  "type Duration string\n\n",
+			name:  ge.builtinTypeName("Duration"),
+			code:  durationJSON,
 		},
 	}
 	for _, c := range cases {
@@ -1197,14 +2774,77 @@ func (ge *goEncoder) genDateTypes(w io.Writer) {
 			continue
 		}
 		ge.writeComments(w, c.name, c.name+" in WSDL format.")
-		io.WriteString(w, c.code)
+		fmt.Fprintf(w, "type %s string\n\n", c.name)
+		if c.code == "" {
+			continue
+		}
+		ge.needsStdPkg["encoding/json"] = true
+		ge.needsStdPkg["strconv"] = true
+		ge.needsStdPkg["time"] = true
+		fmt.Fprintf(w, c.code, c.name)
+	}
+}
+
+// JSON methods of the date types, formatted with their names. They're
+// only decoded, from the WSDL format and from the formats usual in JSON
+// APIs, and encoded as is, in the WSDL format. DateTime has none, as its
+// WSDL format is already RFC 3339, but for the time zone, which is
+// optional and can't be made up.
+const (
+	dateJSON = `// UnmarshalJSON decodes v from a WSDL date, or the date of an
+// RFC 3339 date and time.
+func (v *%[1]s) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		s = t.Format("2006-01-02Z07:00")
+	}
+	*v = %[1]s(s)
+	return nil
+}
+
+`
+	timeJSON = `// UnmarshalJSON decodes v from a WSDL time, or the time of an
+// RFC 3339 date and time.
+func (v *%[1]s) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		s = t.Format("15:04:05.999999999Z07:00")
+	}
+	*v = %[1]s(s)
+	return nil
+}
+
+`
+	durationJSON = `// UnmarshalJSON decodes v from a WSDL duration, or a Go duration
+// such as "1h30m".
+func (v *%[1]s) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		s = "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+		if d < 0 {
+			s = "-PT" + strconv.FormatFloat(-d.Seconds(), 'f', -1, 64) + "S"
+		}
 	}
+	*v = %[1]s(s)
+	return nil
 }
 
+`
+)
+
 var validatorT = template.Must(template.New("validator").Parse(`
 // Validate validates {{.TypeName}}.
 func (v {{.TypeName}}) Validate() bool {
-	for _, vv := range []{{.Type}} {
+	for _, vv := range []{{.TypeName}} {
 		{{range .Args}}{{.}},{{"\n"}}{{end}}
 	}{
 		if reflect.DeepEqual(v, vv) {
@@ -1215,21 +2855,58 @@ func (v {{.TypeName}}) Validate() bool {
 }
 `))
 
-func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restriction) {
+var switchValidatorT = template.Must(template.New("switchValidator").Parse(`
+// Validate validates {{.TypeName}}.
+func (v {{.TypeName}}) Validate() bool {
+	switch v {
+	case {{range $i, $v := .Args}}{{if $i}},{{"\n"}}{{end}}{{$v}}{{end}}:
+		return true
+	}
+	return false
+}
+`))
+
+var enumTableT = template.Must(template.New("enumTable").Parse(`
+// {{.Table}} is the set of valid values of {{.TypeName}}.
+var {{.Table}} = map[{{.TypeName}}]struct{}{
+	{{range .Args}}{{.}}: {},{{"\n"}}{{end}}
+}
+`))
+
+var enumValidatorT = template.Must(template.New("enumValidator").Parse(`
+// Validate validates {{.TypeName}}.
+func (v {{.TypeName}}) Validate() bool {
+	_, ok := {{.Table}}[v]
+	return ok
+}
+`))
+
+// largeEnum is the number of enumerations from which a simple type is
+// validated with a map lookup instead of comparing each value, as the
+// slice literal and reflect.DeepEqual calls are slow to compile.
+const largeEnum = 256
+
+func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restriction) error {
 	if len(r.Enum) == 0 {
-		return
+		return nil
 	}
-	args := make([]string, len(r.Enum))
+	args := make([]string, 0, len(r.Enum))
+	seen := make(map[string]bool, len(r.Enum))
 	t := ge.wsdl2goType(r.Base)
-	for i, v := range r.Enum {
+	for _, v := range r.Enum {
+		arg := v.Value
 		if t == "string" {
-			args[i] = strconv.Quote(v.Value)
-		} else {
-			args[i] = v.Value
+			arg = strconv.Quote(v.Value)
+		}
+		if !seen[arg] {
+			seen[arg] = true
+			args = append(args, arg)
 		}
 	}
-	ge.needsStdPkg["reflect"] = true
-	validatorT.Execute(w, &struct {
+	if len(args) >= largeEnum {
+		return ge.genEnumTable(w, typeName, args)
+	}
+	data := &struct {
 		TypeName string
 		Type     string
 		Args     []string
@@ -1237,29 +2914,206 @@ func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restrict
 		typeName,
 		t,
 		args,
+	}
+	if ge.minimal {
+		return ge.template(switchValidatorT).Execute(w, data)
+	}
+	ge.needsStdPkg["reflect"] = true
+	return ge.template(validatorT).Execute(w, data)
+}
+
+// genEnumTable writes the Validate method of a type with a large
+// enumeration to w, and its table of values to the enumeration file
+// set with SetEnumWriter, or to w if there's none.
+func (ge *goEncoder) genEnumTable(w io.Writer, typeName string, args []string) error {
+	data := &struct {
+		TypeName string
+		Table    string
+		Args     []string
+	}{
+		typeName,
+		"valid" + typeName,
+		args,
+	}
+	if err := ge.template(enumValidatorT).Execute(w, data); err != nil {
+		return err
+	}
+	if ge.enumw != nil {
+		w = &ge.enums
+	}
+	return ge.template(enumTableT).Execute(w, data)
+}
+
+var enumStringT = template.Must(template.New("enumString").Parse(`
+// String returns v as a string.
+func (v {{.TypeName}}) String() string {
+	return {{.Format}}
+}
+{{if .Parse}}
+// Parse{{.TypeName}} parses s as a {{.TypeName}}, and returns an error if
+// it's not one of the valid values.
+func Parse{{.TypeName}}(s string) ({{.TypeName}}, error) {
+	{{.Parse}}
+	if !v.Validate() {
+		return {{.Zero}}, fmt.Errorf("invalid {{.TypeName}}: %q", s)
+	}
+	return v, nil
+}
+{{end}}
+`))
+
+// genEnumString writes the String method of the simple type typeName
+// with enumerations, and its Parse function unless the name is taken.
+func (ge *goEncoder) genEnumString(w io.Writer, typeName string, r *wsdl.Restriction) error {
+	if len(r.Enum) == 0 {
+		return nil
+	}
+	var format, parse, zero string
+	switch t := ge.wsdl2goType(r.Base); t {
+	case "string", ge.builtinTypeName("Date"), ge.builtinTypeName("Time"),
+		ge.builtinTypeName("DateTime"), ge.builtinTypeName("Duration"):
+		format = "string(v)"
+		parse = "v := " + typeName + "(s)"
+		zero = `""`
+	case "bool":
+		format = "strconv.FormatBool(bool(v))"
+		parse = "b, err := strconv.ParseBool(s)\nif err != nil {\nreturn false, err\n}\nv := " + typeName + "(b)"
+		zero = "false"
+	case "int", "int16", "int64":
+		format = "strconv.FormatInt(int64(v), 10)"
+		parse = "n, err := strconv.ParseInt(s, 10, " + bitSize(t) + ")\nif err != nil {\nreturn 0, err\n}\nv := " + typeName + "(n)"
+		zero = "0"
+	case "uint", "uint16", "uint64":
+		format = "strconv.FormatUint(uint64(v), 10)"
+		parse = "n, err := strconv.ParseUint(s, 10, " + bitSize(t) + ")\nif err != nil {\nreturn 0, err\n}\nv := " + typeName + "(n)"
+		zero = "0"
+	case "float64":
+		format = "strconv.FormatFloat(float64(v), 'g', -1, 64)"
+		parse = "f, err := strconv.ParseFloat(s, 64)\nif err != nil {\nreturn 0, err\n}\nv := " + typeName + "(f)"
+		zero = "0"
+	default:
+		return nil
+	}
+	if ge.isTypeName("Parse" + typeName) {
+		parse = ""
+	}
+	ge.needsStdPkg["fmt"] = true
+	ge.needsStdPkg["strconv"] = true
+	return ge.template(enumStringT).Execute(w, &struct {
+		TypeName, Format, Parse, Zero string
+	}{
+		typeName,
+		format,
+		parse,
+		zero,
 	})
 }
 
-func (ge *goEncoder) genGoXMLTypeFunction(w io.Writer, ct *wsdl.ComplexType) {
+// bitSize returns the bit size argument of strconv to parse an integer
+// of type t.
+func bitSize(t string) string {
+	switch t {
+	case "int16", "uint16":
+		return "16"
+	case "int64", "uint64":
+		return "64"
+	}
+	return "0"
+}
+
+func (ge *goEncoder) registerXMLType(ct *wsdl.ComplexType) {
 	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil || ct.TargetNamespace == "" {
 		return
 	}
+	if ct.ComplexContent.Extension.Base != "" && !ct.Abstract {
+		ge.xsiTypes = append(ge.xsiTypes, ct)
+	}
+}
 
-	ext := ct.ComplexContent.Extension
-	if ext.Base != "" && !ct.Abstract {
-		ge.writeComments(w, "SetXMLType", "")
-		fmt.Fprintf(w, "func (t *%s) SetXMLType() {\n", goSymbol(ct.Name))
-		fmt.Fprintf(w, "if t.OverrideTypeAttrXSI != nil {\n")
-		fmt.Fprintf(w, "    t.TypeAttrXSI = *t.OverrideTypeAttrXSI\n")
-		fmt.Fprintf(w, "} else {\n")
-		fmt.Fprintf(w, "    t.TypeAttrXSI = \"objtype:%s\"\n", ct.Name)
-		fmt.Fprintf(w, "}\n")
-		fmt.Fprintf(w, "if t.OverrideTypeNamespace != nil {\n")
-		fmt.Fprintf(w, "    t.TypeNamespace = *t.OverrideTypeNamespace\n")
-		fmt.Fprintf(w, "} else {\n")
-		fmt.Fprintf(w, "    t.TypeNamespace = \"%s\"\n", ct.TargetNamespace)
-		fmt.Fprintf(w, "}\n}\n\n")
+var typeRegistryT = template.Must(template.New("typeRegistry").Parse(`
+// init registers the XML schema types of extension types, which are
+// marshaled with xsi:type when assigned to abstract fields.
+func init() {
+{{- range .}}
+	soap.RegisterType((*{{.Type}})(nil), xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}})
+{{- end}}
+}
+{{range .}}
+// SetXMLType sets the xsi:type of t to the type registered for it.
+//
+// Deprecated: Extension types are registered with soap.RegisterType,
+// and their xsi:type is set when they're sent.
+func (t *{{.Type}}) SetXMLType() {
+	soap.SetRegisteredType(t)
+}
+{{end}}
+`))
+
+func (ge *goEncoder) genTypeRegistry(w io.Writer) error {
+	if len(ge.xsiTypes) == 0 || ge.minimal {
+		return nil
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	types := make([]struct{ Type, Space, Local string }, len(ge.xsiTypes))
+	for i, ct := range ge.xsiTypes {
+		types[i].Type = ge.goSymbol(ct.Name)
+		types[i].Space = ct.TargetNamespace
+		types[i].Local = ct.Name
+		if q, ok := ge.typeQNames[ct.Name]; ok {
+			types[i].Local = q.Local
+		}
+	}
+	return ge.template(typeRegistryT).Execute(w, types)
+}
+
+var arrayTypeT = template.Must(template.New("arrayType").Parse(`
+// SetXMLType was auto-generated from WSDL.
+func (t *{{.Name}}) SetXMLType() {
+	t.TypeAttrXSI = "SOAP-ENC:Array"
+	t.ArrayType = "{{.ItemType}}[" + strconv.Itoa(len(t.Items)) + "]"
+}
+
+var _ soap.XMLTyper = (*{{.Name}})(nil)
+
+`))
+
+// usesEncoding reports whether the input or output of an operation of
+// the binding uses the SOAP encoding, rather than being literal.
+func (ge *goEncoder) usesEncoding() bool {
+	for _, bo := range ge.soapOps {
+		for _, body := range []*wsdl.BindingIO{bo.Input, bo.Output} {
+			if body != nil && body.Use == "encoded" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// genArrayTypeFunction writes the SetXMLType method of SOAP encoded
+// arrays, declaring their type and length as SOAP-ENC:arrayType. The
+// item type, the qualified name typ, is prefixed with xsd for built-in
+// types, which the envelope declares, and else with a prefix of its
+// namespace written to the generated Namespaces, or tns if it has none.
+func (ge *goEncoder) genArrayTypeFunction(w io.Writer, name, typ string) error {
+	prefix := "xsd:"
+	if !ge.isXSDType(typ) {
+		prefix = "tns:"
+		if p := ge.declaredPrefix(ge.qname(typ).Space); p != "" {
+			prefix = p + ":"
+		}
 	}
+	typ = trimns(typ)
+	ge.needsStdPkg["strconv"] = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	return ge.template(arrayTypeT).Execute(w, &struct {
+		Name     string
+		ItemType string
+	}{
+		name,
+		prefix + typ,
+	})
 }
 
 // helper function to print out the XMLName
@@ -1275,15 +3129,15 @@ func (ge *goEncoder) genXMLName(w io.Writer, targetNamespace string, name string
 	}
 }
 
-var invalidGoSymbol = regexp.MustCompile(`[0-9_]*[^0-9a-zA-Z_]+`)
+var invalidGoSymbol = regexp.MustCompile(`[0-9_]*[^0-9\pL_]+`)
 
-func goSymbol(s string) string {
-	v := invalidGoSymbol.ReplaceAllString(trimns(s), " ")
-	var name string
+func (ge *goEncoder) goSymbol(s string) string {
+	v := invalidGoSymbol.ReplaceAllString(transliterate(trimns(s)), " ")
+	var name strings.Builder
 	for _, part := range strings.Split(v, " ") {
-		name += strings.Title(part)
+		name.WriteString(strings.Title(part))
 	}
-	return name
+	return exportedName(ge.goInitialisms(name.String()))
 }
 
 func trimns(s string) string {
@@ -1305,38 +3159,37 @@ func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.Comp
 	if ct.Sequence == nil && ct.Choice == nil {
 		c++
 	} else if ct.Sequence != nil &&
-		(len(ct.Sequence.ComplexTypes) == 0 && len(ct.Sequence.Elements) == 0 && len(ct.Sequence.Choices) == 0) {
+		(len(ct.Sequence.ComplexTypes) == 0 && len(ct.Sequence.Elements) == 0 && len(ct.Sequence.Choices) == 0 && len(ct.Sequence.Any) == 0) {
 		c++
-	} else if ct.Choice != nil && (len(ct.Choice.ComplexTypes) == 0 && len(ct.Choice.Elements) == 0) {
+	} else if ct.Choice != nil && (len(ct.Choice.ComplexTypes) == 0 && len(ct.Choice.Elements) == 0 && len(ct.Choice.Any) == 0) {
 		c++
 	}
 
-	name := goSymbol(ct.Name)
-	ge.writeComments(w, name, ct.Doc)
+	name := ge.goSymbol(ct.Name)
+	defer ge.inScope(ct)()
+	defer func(pos wsdl.Pos) { ge.pos = pos }(ge.pos)
+	ge.pos = ct.Pos
+	ge.writeComments(w, name, ct.Doc.In(ge.docLang))
 	if ct.Abstract {
 		fmt.Fprintf(w, "type %s interface{}\n\n", name)
 		return nil
 	}
-	if ct.Sequence != nil && ct.Sequence.Any != nil {
-		if len(ct.Sequence.Elements) == 0 {
-			fmt.Fprintf(w, "type %s []interface{}\n\n", name)
-			return nil
-		}
-	}
-	if ct.Choice != nil && ct.Choice.Any != nil {
-		if len(ct.Choice.Elements) == 0 {
-			fmt.Fprintf(w, "type %s []interface{}\n\n", name)
-			return nil
-		}
-	}
+	ge.structs = append(ge.structs, name)
 	if ct.ComplexContent != nil {
 		restr := ct.ComplexContent.Restriction
 		if restr != nil && len(restr.Attributes) == 1 && restr.Attributes[0].ArrayType != "" {
 			fmt.Fprintf(w, "type %s struct {\n", name)
-			typ := strings.SplitN(trimns(restr.Attributes[0].ArrayType), "[", 2)[0]
+			typ := strings.SplitN(restr.Attributes[0].ArrayType, "[", 2)[0]
 			fmt.Fprintf(w, "Items []%s `xml:\"item,omitempty\" json:\"item,omitempty\" yaml:\"item,omitempty\"`\n", ge.wsdl2goType(typ))
+			// only encoded messages declare the type of arrays
+			if !ge.usesEncoding() {
+				fmt.Fprintf(w, "}\n\n")
+				return nil
+			}
+			fmt.Fprint(w, "ArrayType   string `xml:\"SOAP-ENC:arrayType,attr,omitempty\" json:\"-\" yaml:\"-\"`\n")
+			fmt.Fprint(w, "TypeAttrXSI string `xml:\"xsi:type,attr,omitempty\" json:\"-\" yaml:\"-\"`\n")
 			fmt.Fprintf(w, "}\n\n")
-			return nil
+			return ge.genArrayTypeFunction(w, name, typ)
 		}
 	}
 
@@ -1349,6 +3202,9 @@ func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.Comp
 	fmt.Fprintf(w, "type %s struct {\n", name)
 	ge.genXMLName(w, d.TargetNamespace, name)
 
+	ge.fields = nil
+	ge.structName, ge.ptrFields = name, false
+	ge.choiceField, ge.anyField = false, false
 	err := ge.genStructFields(w, d, ct)
 
 	if ct.ComplexContent != nil && ct.ComplexContent.Extension != nil {
@@ -1362,15 +3218,18 @@ func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.Comp
 		return err
 	}
 	fmt.Fprintf(w, "}\n\n")
+	ge.choiceDecls.WriteTo(w)
+	if ge.constructors {
+		return ge.genConstructor(w, name, ge.fields)
+	}
 	return nil
 }
 
 func (ge *goEncoder) genGoOpStruct(w io.Writer, d *wsdl.Definitions, bo *wsdl.BindingOperation) error {
-	name := goSymbol(bo.Name)
-	function := ge.funcs[name]
+	function := ge.funcs[bo.Name]
 
 	if function.Input == nil {
-		log.Printf("function input is nil! %v is %v", name, function)
+		ge.report(SkipEvent, wsdl.Pos{}, "operation %q has no input, so no input wrapper is generated", bo.Name)
 	} else {
 		message := trimns(function.Input.Message)
 		inputMessage := ge.messages[message]
@@ -1378,21 +3237,24 @@ func (ge *goEncoder) genGoOpStruct(w io.Writer, d *wsdl.Definitions, bo *wsdl.Bi
 		// No-Op on operations which don't take arguments
 		// (These can be inlined, and don't need to pollute the file)
 		if len(inputMessage.Parts) > 0 {
-			ge.genOpStructMessage(w, d, name, inputMessage)
+			ge.genOpStructMessage(w, d, bo, inputMessage, false)
 		}
 	}
 
 	if function.Output == nil {
-		log.Printf("function output is nil! %v is %v", name, function)
+		ge.report(SkipEvent, wsdl.Pos{}, "operation %q has no output, so no output wrapper is generated", bo.Name)
 	} else {
 		// Output messages are always required
-		ge.genOpStructMessage(w, d, name, ge.messages[trimns(ge.funcs[bo.Name].Output.Message)])
+		ge.genOpStructMessage(w, d, bo, ge.messages[trimns(ge.funcs[bo.Name].Output.Message)], true)
 	}
 
 	return nil
 }
 
+// genStructFields generates the fields of ct, which may be the base of
+// the type being generated, declared by another schema.
 func (ge *goEncoder) genStructFields(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+	defer ge.inScope(ct)()
 	err := ge.genComplexContent(w, d, ct)
 	if err != nil {
 		return err
@@ -1406,43 +3268,85 @@ func (ge *goEncoder) genStructFields(w io.Writer, d *wsdl.Definitions, ct *wsdl.
 	return ge.genElements(w, ct)
 }
 
-func (ge *goEncoder) genOpStructMessage(w io.Writer, d *wsdl.Definitions, name string, message *wsdl.Message) {
+// genOpStructMessage generates the operation wrapper for message, the
+// input or output of bo. Parts in attachments are sent as MIME parts
+// and left out of the wrapper. The parts of bare messages are the
+// children of the SOAP body, so their fields are named after their
+// elements.
+func (ge *goEncoder) genOpStructMessage(w io.Writer, d *wsdl.Definitions, bo *wsdl.BindingOperation, message *wsdl.Message, output bool) {
+	name := ge.goSymbol(bo.Name)
+	attachments := ge.attachments(bo.Name, output)
+	bare, outputBare := ge.bareMessages(d, ge.funcs[bo.Name])
+	if output {
+		bare = outputBare
+	}
 	sanitizedMessageName := ge.sanitizedOperationsType(message.Name)
+	if ge.opStructs[sanitizedMessageName] {
+		// shared by another operation
+		return
+	}
+	ge.opStructs[sanitizedMessageName] = true
 
 	ge.writeComments(w, sanitizedMessageName, "Operation wrapper for "+name+".")
 	ge.writeComments(w, sanitizedMessageName, "")
 	fmt.Fprintf(w, "type %s struct {\n", sanitizedMessageName)
+	ge.structName, ge.ptrFields = sanitizedMessageName, true
 	if elName, ok := ge.needsTag[sanitizedMessageName]; ok {
 		fmt.Fprintf(w, "XMLName xml.Name `xml:\"%s %s\" json:\"-\" yaml:\"-\"`\n",
 			d.TargetNamespace, elName)
 	}
 
 	for _, part := range message.Parts {
+		if _, ok := attachments[part.Name]; ok {
+			// v1 declared fields for attachments, which are now sent
+			// as MIME parts instead.
+			if ge.compat == "v1" {
+				fmt.Fprintf(w, "%s *[]byte `xml:\"-\" json:\"%s,omitempty\" yaml:\"%s,omitempty\"`\n",
+					ge.goSymbol(part.Name), part.Name, part.Name)
+			}
+			continue
+		}
 		wsdlType := part.Type
 
 		// Probably soap12
 		if wsdlType == "" {
-			wsdlType = part.Element
+			wsdlType = ge.elementType(part.Element)
 		}
 
-		partName := part.Name
+		partName, fieldName := part.Name, ""
+		var decl *wsdl.Element
 		if part.Element != "" {
 			elName := trimns(part.Element)
-			if el, ok := ge.elements[elName]; ok {
+			if el, ok := ge.element(part.Element); ok {
 				partName = trimns(el.Name)
+				// elements of named types are of those types,
+				// declared in the scope of their schema
+				if el.Type != "" && el.ComplexType == nil && el.SimpleType == nil {
+					wsdlType, decl = el.Type, el
+				}
 			} else if el, ok := ge.ctypes[elName]; ok {
 				partName = trimns(el.Name)
 			} else if el, ok := ge.stypes[elName]; ok {
 				partName = trimns(el.Name)
+			} else if bare {
+				partName = elName
+				// v1 named the field after the part, which is
+				// kept for inputs: code generated with such
+				// outputs didn't compile.
+				if ge.compat == "v1" && !output {
+					fieldName = part.Name
+				}
 			}
 		}
 
-		ge.genElementField(w, &wsdl.Element{
+		restore := ge.inScope(decl)
+		ge.genNamedElementField(w, &wsdl.Element{
 			XMLName: part.XMLName,
 			Name:    partName,
 			Type:    wsdlType,
 			// TODO: Maybe one could make guesses about nillable?
-		})
+		}, fieldName)
+		restore()
 	}
 
 	fmt.Fprintf(w, "}\n\n")
@@ -1454,7 +3358,7 @@ func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsd
 	}
 	ext := ct.ComplexContent.Extension
 	if ext.Base != "" {
-		base, exists := ge.ctypes[trimns(ext.Base)]
+		base, exists := ge.ctypes[ge.typeName(ext.Base)]
 		if exists {
 			err := ge.genStructFields(w, d, base)
 			if err != nil {
@@ -1485,7 +3389,12 @@ func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsd
 			Any:          ext.Choice.Any}
 		sequences = append(sequences, tmpSeq)
 	}
-	for _, seq := range sequences {
+	for i, seq := range sequences {
+		// all but the first, unless it's a choice
+		choice := ext.Sequence == nil || i > 0
+		if choice {
+			ge.choices++
+		}
 		for _, v := range seq.ComplexTypes {
 			err := ge.genElements(w, v)
 			if err != nil {
@@ -1495,30 +3404,36 @@ func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsd
 		for _, v := range seq.Elements {
 			ge.genElementField(w, v)
 		}
-
+		if choice {
+			ge.choices--
+		}
+		if len(seq.Any) > 0 {
+			ge.genAnyField(w)
+		}
 	}
 	return nil
 }
 
 func (ge *goEncoder) genSimpleContent(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
-	if ct.SimpleContent == nil || ct.SimpleContent.Extension == nil {
+	if ct.SimpleContent == nil {
+		return nil
+	}
+	if ct.SimpleContent.Extension == nil {
+		ge.report(SkipEvent, ct.Pos, "restriction of simpleContent of %q is not supported", ct.Name)
 		return nil
 	}
 
 	ext := ct.SimpleContent.Extension
 	if ext.Base != "" {
-		baseComplex, exists := ge.ctypes[trimns(ext.Base)]
+		baseComplex, exists := ge.ctypes[ge.typeName(ext.Base)]
 		if exists {
 			err := ge.genStructFields(w, d, baseComplex)
 			if err != nil {
 				return err
 			}
 		} else {
-			// otherwise it's a simple type
-			ge.genElementField(w, &wsdl.Element{
-				Type: trimns(ext.Base),
-				Name: "Content",
-			})
+			// otherwise it's a simple type, the text of the element
+			ge.genCharDataField(w, ext.Base, ext.Attributes)
 		}
 	}
 
@@ -1527,28 +3442,115 @@ func (ge *goEncoder) genSimpleContent(w io.Writer, d *wsdl.Definitions, ct *wsdl
 	}
 
 	// sequence, choice, etc. are not supported in simpleContent tags.
+	if ext.Sequence != nil || ext.Choice != nil {
+		ge.report(SkipEvent, ct.Pos, "elements of simpleContent of %q are not supported", ct.Name)
+	}
 	return nil
 }
 
+// genAnyField generates the Any field of the elements of xsd:any, unless
+// the struct being generated already has a field of other elements.
+func (ge *goEncoder) genAnyField(w io.Writer) {
+	if ge.choiceField || ge.anyField {
+		return
+	}
+	ge.anyField = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	fmt.Fprint(w, "Any []soap.AnyElement `xml:\",any\" json:\"Any,omitempty\" yaml:\"Any,omitempty\"`\n")
+}
+
+// genCharDataField generates the Value field of the text of an element
+// of the simple type base, or CharData if one of attrs is named value.
+func (ge *goEncoder) genCharDataField(w io.Writer, base string, attrs []*wsdl.Attribute) {
+	name := "Value"
+	for _, attr := range attrs {
+		if ge.goSymbol(attr.Name) == name {
+			name = "CharData"
+		}
+	}
+	typ := ge.wsdl2goType(base)
+	extra := ge.fieldTags.lookup(taggedField{
+		Struct:   ge.structName,
+		Field:    name,
+		Type:     trimns(base),
+		Required: true,
+	})
+	fmt.Fprintf(w, "%s %s `xml:\",chardata\" json:\"%s\" yaml:\"%s\"%s`\n",
+		name, typ, name, name, extra)
+	ge.fields = append(ge.fields, structField{
+		Name:     name,
+		Type:     typ,
+		Required: true,
+	})
+}
+
 func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
 	for _, el := range ct.AllElements {
 		ge.genElementField(w, el)
 	}
-	if ct.Sequence != nil {
-		for _, el := range ct.Sequence.Elements {
+	group := ge.choiceGroup(ct)
+	hasAny := ct.Sequence != nil && len(ct.Sequence.Any) > 0 || ct.Choice != nil && len(ct.Choice.Any) > 0
+	var groups []*repeatedGroup
+	if ct.Sequence != nil && repeated(ct.Sequence.Max) {
+		// the whole content of ct is repeated
+		groups = append(groups, sequenceGroup(ct.Sequence))
+	} else if ct.Sequence != nil {
+		// repeated sequences are generated in place, among elements
+		nested := ct.Sequence.Sequences
+		for i, el := range ct.Sequence.Elements {
+			for ; len(nested) > 0 && nested[0].Position <= i; nested = nested[1:] {
+				if err := ge.genGroupField(w, ct, sequenceGroup(nested[0]), hasAny); err != nil {
+					return err
+				}
+			}
 			ge.genElementField(w, el)
 		}
+		for _, seq := range nested {
+			if err := ge.genGroupField(w, ct, sequenceGroup(seq), hasAny); err != nil {
+				return err
+			}
+		}
+		ge.choices++
 		for _, choice := range ct.Sequence.Choices {
+			if repeated(choice.Max) {
+				groups = append(groups, choiceGroupOf(choice))
+				continue
+			}
+			if choice == group {
+				if err := ge.genChoiceField(w, ct, choice); err != nil {
+					return err
+				}
+				continue
+			}
 			for _, el := range choice.Elements {
 				ge.genElementField(w, el)
 			}
 		}
+		ge.choices--
 	}
-	if ct.Choice != nil {
-		for _, el := range ct.Choice.Elements {
-			ge.genElementField(w, el)
+	if ct.Choice != nil && repeated(ct.Choice.Max) {
+		groups = append(groups, choiceGroupOf(ct.Choice))
+	} else if ct.Choice != nil {
+		if ct.Choice == group {
+			if err := ge.genChoiceField(w, ct, ct.Choice); err != nil {
+				return err
+			}
+		} else {
+			ge.choices++
+			for _, el := range ct.Choice.Elements {
+				ge.genElementField(w, el)
+			}
+			ge.choices--
+		}
+	}
+	for _, g := range groups {
+		if err := ge.genGroupField(w, ct, g, hasAny); err != nil {
+			return err
 		}
 	}
+	if hasAny {
+		ge.genAnyField(w)
+	}
 	for _, attr := range ct.Attributes {
 		ge.genAttributeField(w, attr)
 	}
@@ -1556,24 +3558,61 @@ func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
 }
 
 func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) {
+	ge.genNamedElementField(w, el, "")
+}
+
+// genNamedElementField generates the field of el, named after
+// fieldName if not empty, or else after el.
+func (ge *goEncoder) genNamedElementField(w io.Writer, el *wsdl.Element, fieldName string) {
 	if el.Ref != "" {
-		ref := trimns(el.Ref)
-		nel, ok := ge.elements[ref]
+		nel, ok := ge.element(el.Ref)
 		if !ok {
 			return
 		}
+		// the type of the element is resolved in the scope of its schema
+		defer ge.inScope(nel)()
 		el = nel
 	}
-	var slicetype string
+	ge.writeFieldComments(w, el.Doc)
+	el, slice, typ, tag := ge.elementField(el)
+	et := el.Type
+	if et == "" {
+		et = "string"
+	}
+	if fieldName == "" {
+		fieldName = el.Name
+	}
+	required := el.Min > 0 && ge.choices == 0
+	extra := ge.fieldTags.lookup(taggedField{
+		Struct:   ge.structName,
+		Field:    ge.goSymbol(fieldName),
+		Name:     el.Name,
+		Type:     trimns(et),
+		Required: required,
+	})
+	fmt.Fprintf(w, "%s %s%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"%s`\n",
+		ge.goSymbol(fieldName), slice, typ, tag, tag, tag, extra)
+	ge.fields = append(ge.fields, structField{
+		Name:     ge.goSymbol(fieldName),
+		Type:     slice + typ,
+		Required: required,
+		Default:  el.Default,
+	})
+}
+
+// elementField returns the element of the field of el in the struct
+// being generated, which is the single element of its anonymous
+// sequence, if so, named after el. It also returns the slice prefix of
+// its Go type, if repeated, the type and the name of its XML tag.
+func (ge *goEncoder) elementField(el *wsdl.Element) (*wsdl.Element, string, string, string) {
+	var slicetype, slice string
+	if base := anonymousSimpleTypeBase(el.SimpleType); el.Type == "" && base != "" {
+		typed := *el
+		typed.Type = base
+		el = &typed
+	}
 	if el.Type == "" && el.ComplexType != nil {
-		seq := el.ComplexType.Sequence
-		if seq == nil && el.ComplexType.Choice != nil {
-			seq = &wsdl.Sequence{
-				ComplexTypes: el.ComplexType.Choice.ComplexTypes,
-				Elements:     el.ComplexType.Choice.Elements,
-				Any:          el.ComplexType.Choice.Any}
-		}
-		if seq != nil {
+		if seq := anonymousSequence(el.ComplexType); seq != nil {
 			if len(seq.Elements) == 1 {
 				n := el.Name
 				seqel := seq.Elements[0]
@@ -1581,65 +3620,124 @@ func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) {
 				*el = *seqel
 				slicetype = seqel.Name
 				el.Name = n
-			} else if len(seq.Any) == 1 {
+			} else if len(seq.Any) == 1 && len(seq.Elements) == 0 {
+				// the element is kept whole, with its content
 				el = &wsdl.Element{
-					Name: el.Name,
-					Type: "anysequence",
-					Min:  seq.Any[0].Min,
-					Max:  seq.Any[0].Max,
+					Name:     el.Name,
+					Type:     "anysequence",
+					Min:      el.Min,
+					Max:      el.Max,
+					Nillable: el.Nillable,
 				}
-				slicetype = el.Name
 			}
 		}
 	}
 	et := el.Type
+	if name, ok := ge.anonTypes[el.ComplexType]; ok && et == "" {
+		et = name
+	}
 	if et == "" {
 		et = "string"
 	}
 	tag := el.Name
-	fmt.Fprintf(w, "%s ", goSymbol(el.Name))
 	if el.Max != "" && el.Max != "1" {
-		fmt.Fprintf(w, "[]")
+		slice = "[]"
 		if slicetype != "" {
 			tag = el.Name + ">" + slicetype
 		}
 	}
 	typ := ge.wsdl2goType(et)
 	if el.Nillable || el.Min == 0 {
-		tag += ",omitempty"
+		omit := ge.structOmitsEmpty()
+		if omit {
+			tag += ",omitempty"
+		}
 		//since we add omitempty tag, we should add pointer to type.
 		//thus xmlencoder can differ not-initialized fields from zero-initialized values
-		if !strings.HasPrefix(typ, "*") {
+		//without omitempty, only structs remain pointers, as they may be recursive
+		_, isStruct := ge.ctypes[ge.typeName(et)]
+		pointer := omit || isStruct && slice == ""
+		if ge.valueScalars && !ge.ptrFields && !isStruct && !el.Nillable {
+			pointer = false
+		}
+		if pointer && !strings.HasPrefix(typ, "*") {
 			typ = "*" + typ
 		}
 	}
-	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"`\n",
-		typ, tag, tag, tag)
+	return el, slice, typ, tag
 }
 
 func (ge *goEncoder) genAttributeField(w io.Writer, attr *wsdl.Attribute) {
+	if attr.Use == "prohibited" {
+		return
+	}
 	if attr.Name == "" && attr.Ref != "" {
 		attr.Name = trimns(attr.Ref)
 	}
+	if attr.Type == "" {
+		attr.Type = anonymousSimpleTypeBase(attr.SimpleType)
+	}
 	if attr.Type == "" {
 		attr.Type = "string"
 	}
 
 	tag := fmt.Sprintf("%s,attr", attr.Name)
-	fmt.Fprintf(w, "%s ", goSymbol(attr.Name))
+	ge.writeFieldComments(w, attr.Doc)
+	fmt.Fprintf(w, "%s ", ge.goSymbol(attr.Name))
 	typ := ge.wsdl2goType(attr.Type)
-	if attr.Nillable || attr.Min == 0 {
+	if (attr.Nillable || attr.Min == 0) && ge.structOmitsEmpty() {
 		tag += ",omitempty"
 	}
-	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"`\n",
-		typ, tag, tag, tag)
+	required := attr.Use == "required" || attr.Min > 0
+	extra := ge.fieldTags.lookup(taggedField{
+		Struct:   ge.structName,
+		Field:    ge.goSymbol(attr.Name),
+		Name:     attr.Name,
+		Type:     trimns(attr.Type),
+		Required: required,
+		Attr:     true,
+	})
+	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"%s`\n",
+		typ, tag, tag, tag, extra)
+	value := attr.Default
+	if value == "" {
+		value = attr.Fixed
+	}
+	ge.fields = append(ge.fields, structField{
+		Name:     ge.goSymbol(attr.Name),
+		Type:     typ,
+		Required: required,
+		Default:  value,
+	})
+}
+
+// structOmitsEmpty returns whether the optional fields of the struct
+// being generated are pointers tagged with omitempty. Fields of
+// operation wrappers always are, as operations set them from their
+// parameters, and so are those of choices, to tell the one that is set.
+func (ge *goEncoder) structOmitsEmpty() bool {
+	if ge.ptrFields {
+		return true
+	}
+	if enabled, ok := ge.structOmitEmpty[ge.structName]; ok {
+		return enabled
+	}
+	return ge.omitEmpty
+}
+
+// writeFieldComments writes the documentation of a struct field to w,
+// if any.
+func (ge *goEncoder) writeFieldComments(w io.Writer, doc wsdl.Documentation) {
+	if text := strings.Join(strings.Fields(doc.In(ge.docLang)), " "); text != "" {
+		ge.writeComments(w, "", text)
+	}
 }
 
 // writeComments writes comments to w, capped at ~80 columns.
 func (ge *goEncoder) writeComments(w io.Writer, typeName, comment string) {
 	comment = strings.Trim(strings.Replace(comment, "\n", " ", -1), " ")
 	if comment == "" {
-		comment = goSymbol(typeName) + " was auto-generated from WSDL."
+		comment = ge.goSymbol(typeName) + " was auto-generated from WSDL."
 	}
 	count, line := 0, ""
 	words := strings.Split(comment, " ")
@@ -1667,3 +3765,146 @@ func (ge *goEncoder) writeComments(w io.Writer, typeName, comment string) {
 func (ge *goEncoder) SetLocalNamespace(s string) {
 	ge.localNamespace = s
 }
+
+// SetASTHook sets the function that post-processes the generated code
+func (ge *goEncoder) SetASTHook(hook ASTHook) {
+	ge.astHook = hook
+}
+
+// SetTestWriter sets where to write tests for the generated code
+func (ge *goEncoder) SetTestWriter(w io.Writer) {
+	ge.testw = w
+}
+
+// SetEnumWriter sets where to write the tables of large enumerations
+func (ge *goEncoder) SetEnumWriter(w io.Writer) {
+	ge.enumw = w
+}
+
+// SetDocLang sets the language of the documentation comments
+func (ge *goEncoder) SetDocLang(lang string) {
+	ge.docLang = lang
+}
+
+// SetBaseURL sets the location of the WSDL document
+func (ge *goEncoder) SetBaseURL(loc string) {
+	ge.baseURL = loc
+}
+
+// SetCatalog sets the catalog of local files of imported documents
+func (ge *goEncoder) SetCatalog(c Catalog) {
+	ge.catalog = c
+}
+
+// SetCache sets where to keep downloaded documents
+func (ge *goEncoder) SetCache(c *Cache) {
+	ge.cache = c
+}
+
+// SetOperationLabels sets whether operations call the Observe hook
+func (ge *goEncoder) SetOperationLabels(enabled bool) {
+	ge.opLabels = enabled
+}
+
+// SetGetters sets whether to generate getters of pointer fields
+func (ge *goEncoder) SetGetters(enabled bool) {
+	ge.getters = enabled
+}
+
+// SetEqualCopy sets whether to generate Equal and DeepCopy methods
+func (ge *goEncoder) SetEqualCopy(enabled bool) {
+	ge.equalCopy = enabled
+}
+
+// SetConstructors sets whether to generate constructors of structs
+func (ge *goEncoder) SetConstructors(enabled bool) {
+	ge.constructors = enabled
+}
+
+// SetFieldTags sets the struct tags added to the fields of structs
+func (ge *goEncoder) SetFieldTags(t FieldTags) {
+	ge.fieldTags = t
+}
+
+// SetOmitEmpty sets whether optional fields are tagged with omitempty
+func (ge *goEncoder) SetOmitEmpty(enabled bool) {
+	ge.omitEmpty = enabled
+}
+
+// SetStructOmitEmpty overrides SetOmitEmpty for the fields of a struct
+func (ge *goEncoder) SetStructOmitEmpty(name string, enabled bool) {
+	ge.structOmitEmpty[name] = enabled
+}
+
+// SetValueScalars sets whether optional fields of simple types are values
+func (ge *goEncoder) SetValueScalars(enabled bool) {
+	ge.valueScalars = enabled
+}
+
+// SetChoiceStructs sets whether to generate choices as structs
+func (ge *goEncoder) SetChoiceStructs(enabled bool) {
+	ge.choiceStructs = enabled
+}
+
+// SetResponseWrapper sets whether operations return their output wrapper
+func (ge *goEncoder) SetResponseWrapper(enabled bool) {
+	ge.responseWrapper = enabled
+}
+
+// SetRawResponse sets whether operations return their raw response
+func (ge *goEncoder) SetRawResponse(enabled bool) {
+	ge.rawResponse = enabled
+}
+
+// SetUnwrap sets whether operations return the single element of their response
+func (ge *goEncoder) SetUnwrap(enabled bool) {
+	ge.unwrap = enabled
+}
+
+// SetStrict sets whether to fail on unresolved types
+func (ge *goEncoder) SetStrict(enabled bool) {
+	ge.strict = enabled
+}
+
+// SetLenient sets whether to generate placeholders for unresolved types
+func (ge *goEncoder) SetLenient(enabled bool) {
+	ge.lenient = enabled
+}
+
+// SetReporter sets the function called with the events of Encode
+func (ge *goEncoder) SetReporter(r func(Event)) {
+	ge.reporter = r
+}
+
+// SetMinimal sets whether to generate code without reflection
+func (ge *goEncoder) SetMinimal(enabled bool) {
+	ge.minimal = enabled
+}
+
+// SetCompat sets the version of generated type names to keep
+func (ge *goEncoder) SetCompat(version string) error {
+	switch version {
+	case "", "v1":
+		ge.compat = version
+		return nil
+	}
+	return fmt.Errorf("unsupported compat version %q", version)
+}
+
+// SetNaming sets how the words of generated identifiers are cased
+func (ge *goEncoder) SetNaming(n Naming) error {
+	switch n {
+	case "", GoNaming, TitleNaming:
+		ge.naming = n
+		return nil
+	}
+	return fmt.Errorf("unsupported naming %q", n)
+}
+
+// SetInitialisms sets the initialisms written in upper case
+func (ge *goEncoder) SetInitialisms(words []string) {
+	ge.initialisms = make(map[string]bool, len(words))
+	for _, w := range words {
+		ge.initialisms[strings.ToUpper(w)] = true
+	}
+}
@@ -2,17 +2,27 @@ package wsdlgo
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
 	"io/ioutil"
+	"log"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/fiorix/wsdl2go/wsdl"
 )
@@ -55,7 +65,28 @@ var EncoderCases = []struct {
 	{F: "localimport.wsdl", G: "localimport.golden", E: nil},
 	{F: "localimport-url.wsdl", G: "localimport.golden", E: nil},
 	{F: "localimport_choice.wsdl", G: "localimport_choice.golden", E: nil},
+	{F: "redefine.wsdl", G: "redefine.golden", E: nil},
+	{F: "nested.wsdl", G: "nested.golden", E: nil},
+	{F: "groups.wsdl", G: "groups.golden", E: nil},
 	{F: "arrayexample.wsdl", G: "arrayexample.golden", E: nil},
+	{F: "arraynamespace.wsdl", G: "arraynamespace.golden", E: nil},
+	{F: "bare.wsdl", G: "bare.golden", E: nil},
+	{F: "doclang.wsdl", G: "doclang.golden", E: nil},
+	{F: "promoted.wsdl", G: "promoted.golden", E: nil},
+	{F: "conflicts.wsdl", G: "conflicts.golden", E: nil},
+	{F: "mime.wsdl", G: "mime.golden", E: nil},
+	{F: "addressing.wsdl", G: "addressing.golden", E: nil},
+	{F: "namespaces.wsdl", G: "namespaces.golden", E: nil},
+	{F: "prefixes.wsdl", G: "prefixes.golden", E: nil},
+	{F: "elementtype.wsdl", G: "elementtype.golden", E: nil},
+	{F: "enums.wsdl", G: "enums.golden", E: nil},
+	{F: "datetypes.wsdl", G: "datetypes.golden", E: nil},
+	{F: "mixedstyle.wsdl", G: "mixedstyle.golden", E: nil},
+	{F: "simplecontent.wsdl", G: "simplecontent.golden", E: nil},
+	{F: "any.wsdl", G: "any.golden", E: nil},
+	{F: "httpbinding.wsdl", G: "httpbinding.golden", E: nil},
+	{F: "overloaded.wsdl", G: "overloaded.golden", E: nil},
+	{F: "multischema.wsdl", G: "multischema.golden", E: nil},
 }
 
 func NewTestServer(t *testing.T) *httptest.Server {
@@ -99,6 +130,983 @@ func TestEncoder(t *testing.T) {
 	}
 }
 
+func TestEncoderChunks(t *testing.T) {
+	s := NewTestServer(t)
+	defer s.Close()
+	defer func(n, m int) { chunkSize, spillSize = n, m }(chunkSize, spillSize)
+	chunkSize, spillSize = 1, 1
+	for i, tc := range EncoderCases {
+		if tc.G == "" {
+			continue
+		}
+		d := LoadDefinition(t, tc.F, tc.E)
+		var have bytes.Buffer
+		if err := NewEncoder(&have).Encode(d); err != nil {
+			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", tc.G))
+		if err != nil {
+			t.Errorf("test %d: missing golden file %q: %v", i, tc.G, err)
+		}
+		if !bytes.Equal(have.Bytes(), want) {
+			t.Errorf("test %d, %q formatted in chunks != %q\ngenerated:\n%s\n",
+				i, tc.F, tc.G, have.Bytes())
+		}
+	}
+}
+
+func TestImportSchemaParallel(t *testing.T) {
+	var mu sync.Mutex
+	var inflight, maxInflight int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+		name := strings.Trim(r.URL.Path, "/")
+		fmt.Fprintf(w, `<schema targetNamespace="urn:%s"><complexType name="%s"/></schema>`, name, name)
+	}))
+	defer s.Close()
+
+	d := &wsdl.Definitions{}
+	var want []string
+	for i := 0; i < 3*maxParallelImports; i++ {
+		name := fmt.Sprintf("T%d", i)
+		d.Schema.Imports = append(d.Schema.Imports, &wsdl.ImportSchema{Location: s.URL + "/" + name})
+		want = append(want, name)
+	}
+	d.Schema.Imports = append(d.Schema.Imports, &wsdl.ImportSchema{Location: s.URL + "/T0"})
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	if err := ge.importSchema(d); err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, ct := range d.Schema.ComplexTypes {
+		have = append(have, ct.Name)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("unexpected types: want %v, have %v", want, have)
+	}
+	if maxInflight < 2 || maxInflight > maxParallelImports {
+		t.Errorf("unexpected concurrent downloads: %d", maxInflight)
+	}
+}
+
+func TestImportSchemaDepth(t *testing.T) {
+	const depth = 6
+	var mu sync.Mutex
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		var i int
+		fmt.Sscanf(r.URL.Path, "/xsd/s%d.xsd", &i)
+		// Each schema includes the next one, relative to itself, and
+		// the last one includes the first.
+		fmt.Fprintf(w, `<schema><include schemaLocation="s%d.xsd"/><complexType name="T%d"/></schema>`,
+			(i+1)%depth, i)
+	}))
+	defer s.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	d := &wsdl.Definitions{}
+	d.Schema.Imports = []*wsdl.ImportSchema{{Location: s.URL + "/xsd/s0.xsd"}}
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	if err := ge.importSchema(d); err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, ct := range d.Schema.ComplexTypes {
+		have = append(have, ct.Name)
+	}
+	want := []string{"T0", "T1", "T2", "T3", "T4", "T5"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("unexpected types: want %v, have %v", want, have)
+	}
+	if requests != depth {
+		t.Errorf("unexpected number of requests: want %d, have %d", depth, requests)
+	}
+	var cycle []string
+	for i := 0; i <= depth; i++ {
+		cycle = append(cycle, fmt.Sprintf("%s/xsd/s%d.xsd", s.URL, i%depth))
+	}
+	if want := "schema import cycle: " + strings.Join(cycle, " -> ") + "\n"; !strings.HasSuffix(logs.String(), want) {
+		t.Errorf("unexpected warning: want %q, have %q", want, logs.String())
+	}
+}
+
+func TestImportSchemaBaseURL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wsdl/types/a.xsd":
+			fmt.Fprint(w, `<schema><include schemaLocation="b.xsd"/><complexType name="A"/></schema>`)
+		case "/wsdl/types/b.xsd":
+			fmt.Fprint(w, `<schema><complexType name="B"/></schema>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	d := &wsdl.Definitions{}
+	d.Schema.Imports = []*wsdl.ImportSchema{{Location: "types/a.xsd"}}
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	ge.SetBaseURL(s.URL + "/wsdl/service.wsdl")
+	if err := ge.importSchema(d); err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, ct := range d.Schema.ComplexTypes {
+		have = append(have, ct.Name)
+	}
+	if want := []string{"A", "B"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("unexpected types: want %v, have %v", want, have)
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	cases := []struct {
+		Base, Loc, Want string
+	}{
+		{"http://example.com/a/b.xsd", "c.xsd", "http://example.com/a/c.xsd"},
+		{"http://example.com/a/b.xsd", "../c.xsd", "http://example.com/c.xsd"},
+		{"http://example.com/a/b.xsd", "https://example.org/c.xsd", "https://example.org/c.xsd"},
+		{"/tmp/a/b.xsd", "c/d.xsd", "/tmp/a/c/d.xsd"},
+		{"/tmp/a/b.xsd", "/tmp/c.xsd", "/tmp/c.xsd"},
+		{"b.xsd", "c.xsd", "c.xsd"},
+		{"", "c.xsd", "c.xsd"},
+		// local files relative to the document, or else to the working
+		// directory
+		{"testdata/localimport.wsdl", "localimport.xsd", "testdata/localimport.xsd"},
+		{"testdata/localimport.wsdl", "testdata/localimport.xsd", "testdata/localimport.xsd"},
+	}
+	for _, tc := range cases {
+		if have := resolveLocation(tc.Base, tc.Loc); have != tc.Want {
+			t.Errorf("%q in %q: want %q, have %q", tc.Loc, tc.Base, tc.Want, have)
+		}
+	}
+}
+
+func TestEncoderASTHook(t *testing.T) {
+	d := LoadDefinition(t, "w3example2.wsdl", nil)
+	var have bytes.Buffer
+	enc := NewEncoder(&have)
+	enc.SetASTHook(func(fset *token.FileSet, f *ast.File) error {
+		f.Decls = append(f.Decls, &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent("Hooked")},
+				Values: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: "1"}},
+			}},
+		})
+		return nil
+	})
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(have.String(), "var Hooked = 1\n") {
+		t.Fatalf("hook not applied:\n%s", have.Bytes())
+	}
+
+	d = LoadDefinition(t, "w3example2.wsdl", nil)
+	have.Reset()
+	enc = NewEncoder(&have)
+	enc.SetASTHook(func(fset *token.FileSet, f *ast.File) error {
+		return errors.New("rejected")
+	})
+	if err := enc.Encode(d); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if have.Len() != 0 {
+		t.Fatalf("unexpected output on hook error:\n%s", have.Bytes())
+	}
+}
+
+func TestEncoderImports(t *testing.T) {
+	s := NewTestServer(t)
+	defer s.Close()
+	// the whole file is parsed with an AST hook, rather than formatted
+	// in chunks, and its imports are fixed the same way
+	noop := WithASTHook(func(*token.FileSet, *ast.File) error { return nil })
+	var unknown []string
+	reporter := WithReporter(func(e Event) {
+		if strings.HasPrefix(e.Message, "packages not imported") {
+			unknown = append(unknown, e.Message)
+		}
+	})
+	for i, tc := range EncoderCases {
+		if tc.G == "" {
+			continue
+		}
+		d := LoadDefinition(t, tc.F, tc.E)
+		var have bytes.Buffer
+		if err := NewEncoder(&have, noop, reporter).Encode(d); err != nil {
+			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
+			continue
+		}
+		// in chunks, names declared by other chunks aren't packages
+		if err := NewEncoder(ioutil.Discard, reporter).Encode(d); err != nil {
+			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
+		}
+		if len(unknown) > 0 {
+			t.Errorf("test %d, %q: %v", i, tc.F, unknown)
+			unknown = nil
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", tc.G))
+		if err != nil {
+			t.Errorf("test %d: missing golden file %q: %v", i, tc.G, err)
+		}
+		if !bytes.Equal(have.Bytes(), want) {
+			t.Errorf("test %d, %q parsed whole != %q\ngenerated:\n%s\n",
+				i, tc.F, tc.G, have.Bytes())
+		}
+	}
+
+	// packages imported by the hook are kept, and others aren't looked
+	// up, even if they exist, but reported
+	d := LoadDefinition(t, "w3example2.wsdl", nil)
+	var have bytes.Buffer
+	hook := WithASTHook(func(fset *token.FileSet, f *ast.File) error {
+		src := `package p
+
+import "os"
+
+var Env = os.Getenv("ENV")
+
+var Upper = strings.ToUpper("x")
+
+var NoRows = sql.ErrNoRows
+`
+		decls, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			return err
+		}
+		f.Decls = append(decls.Decls[:1], append(f.Decls, decls.Decls[1:]...)...)
+		return nil
+	})
+	if err := NewEncoder(&have, hook, reporter).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\t\"os\"\n", "\t\"strings\"\n"} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing import %s in:\n%s", want, have.Bytes())
+		}
+	}
+	if strings.Contains(have.String(), `"database/sql"`) {
+		t.Errorf("unexpected import of database/sql in:\n%s", have.Bytes())
+	}
+	want := []string{"packages not imported, as their paths aren't known: sql"}
+	if !reflect.DeepEqual(unknown, want) {
+		t.Errorf("want %q, have %q", want, unknown)
+	}
+
+	// the same for code formatted in chunks, as by templates
+	unknown = nil
+	portType := template.Must(DefaultTemplate("portType").New("hosts").Parse(`{{template "portType" .}}
+// Hosts returns the hosts of the service, sorted.
+func (p *{{.Name}}) Hosts() []string {
+	hosts := []string{p.Client.URL}
+	sort.Strings(hosts)
+	return hosts
+}
+`))
+	have.Reset()
+	if err := NewEncoder(&have, WithTemplate("portType", portType), reporter).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(have.String(), `"sort"`) {
+		t.Errorf("unexpected import of sort in:\n%s", have.Bytes())
+	}
+	want = []string{"packages not imported, as their paths aren't known: sort"}
+	if !reflect.DeepEqual(unknown, want) {
+		t.Errorf("want %q, have %q", want, unknown)
+	}
+}
+
+func TestEncoderDocLang(t *testing.T) {
+	cases := []struct {
+		Lang string
+		Want []string
+	}{
+		{"", []string{
+			"// EchoMessage carries the text to echo.\n",
+			"// Echo returns the text it was given.\n",
+			"// Text to echo.\n\tText ",
+			"// Language of the text.\n\tLang ",
+		}},
+		{"pt", []string{
+			"// EchoMessage carrega o texto a ecoar.\n",
+			"// Echo retorna o texto recebido.\n",
+			"// Texto a ecoar.\n\tText ",
+			"// Language of the text.\n\tLang ",
+		}},
+		{"en-GB", []string{
+			"// EchoMessage carries the text to echo.\n",
+		}},
+	}
+	src := make(map[string][]byte)
+	for _, tc := range cases {
+		d := LoadDefinition(t, "doclang.wsdl", nil)
+		var have bytes.Buffer
+		enc := NewEncoder(&have)
+		enc.SetDocLang(tc.Lang)
+		if err := enc.Encode(d); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.Want {
+			if !strings.Contains(have.String(), want) {
+				t.Errorf("lang %q: missing %q in:\n%s", tc.Lang, want, have.Bytes())
+			}
+		}
+		src["doclang_"+tc.Lang] = have.Bytes()
+	}
+	checkCompile(t, src)
+}
+
+func TestEncoderCompat(t *testing.T) {
+	cases := []struct {
+		F    string
+		Want []string
+	}{
+		{"bare.wsdl", []string{
+			"Text *string `xml:\"string,omitempty\"",
+		}},
+		{"mime.wsdl", []string{
+			"File *[]byte `xml:\"-\" json:\"file,omitempty\"",
+			"soap.Attachment{Name: \"file\", ContentType: \"application/pdf\", Data: file}",
+		}},
+	}
+	for _, tc := range cases {
+		d := LoadDefinition(t, tc.F, nil)
+		var have bytes.Buffer
+		enc := NewEncoder(&have)
+		if err := enc.SetCompat("v1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(d); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.Want {
+			if !strings.Contains(have.String(), want) {
+				t.Errorf("%s: missing %q in:\n%s", tc.F, want, have.Bytes())
+			}
+		}
+	}
+	if err := NewEncoder(ioutil.Discard).SetCompat("v0"); err == nil {
+		t.Error("unexpected success with unsupported version")
+	}
+}
+
+func TestNewEncoderOptions(t *testing.T) {
+	d := LoadDefinition(t, "memcache.wsdl", nil)
+	var have bytes.Buffer
+	enc := NewEncoder(&have, WithPackageName(PackageName("cache")), WithOperationLabels(true))
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package cache\n", "soap.Observe(p.Client, "} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+	enc = NewEncoder(ioutil.Discard, WithCompat("v0"))
+	if err := enc.Encode(d); err == nil {
+		t.Error("unexpected success with unsupported compat version")
+	}
+}
+
+func TestEncoderLargeEnum(t *testing.T) {
+	var enum bytes.Buffer
+	for i := 0; i <= largeEnum; i++ {
+		fmt.Fprintf(&enum, `<xs:enumeration value="C%d"/>`, i)
+	}
+	enum.WriteString(`<xs:enumeration value="C0"/>`)
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+<types><xs:schema><xs:simpleType name="Code"><xs:restriction base="xs:string">` +
+		enum.String() + `</xs:restriction></xs:simpleType></xs:schema></types></definitions>`
+	for _, sep := range []bool{false, true} {
+		d, err := wsdl.Unmarshal(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var code, enums bytes.Buffer
+		enc := NewEncoder(&code, WithPackageName(PackageName("codes")))
+		if sep {
+			enc.SetEnumWriter(&enums)
+		}
+		if err = enc.Encode(d); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(code.String(), "_, ok := validCode[v]") || strings.Contains(code.String(), "reflect") {
+			t.Errorf("unexpected validator in:\n%s", code.Bytes())
+		}
+		table := code.String()
+		if sep {
+			table = enums.String()
+			if !strings.HasPrefix(table, fileHeader+"\n\npackage codes\n") {
+				t.Errorf("unexpected enumerations file:\n%s", table)
+			}
+		}
+		if n := strings.Count(table, `"C0":`); n != 1 {
+			t.Errorf("want 1 C0 value, have %d in:\n%s", n, table)
+		}
+		if n := strings.Count(table, "{},"); n != largeEnum+1 {
+			t.Errorf("want %d values, have %d", largeEnum+1, n)
+		}
+	}
+}
+
+func TestEncoderTransport(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">
+<binding name="JMS"><soap:binding style="document" transport="http://www.w3.org/2010/soapjms/"/></binding>
+</definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewEncoder(ioutil.Discard).Encode(d)
+	want := `binding "JMS" has unsupported transport "http://www.w3.org/2010/soapjms/"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("want error %q, have %v", want, err)
+	}
+}
+
+func TestEncoderMinimal(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+<types><xs:schema><xs:simpleType name="Color"><xs:restriction base="xs:string">
+<xs:enumeration value="red"/><xs:enumeration value="green"/><xs:enumeration value="red"/>
+</xs:restriction></xs:simpleType></xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have, WithMinimal(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(have.String(), "switch v {\n\tcase \"red\",\n\t\t\"green\":\n\t\treturn true") {
+		t.Errorf("missing switch validator in:\n%s", have.Bytes())
+	}
+
+	have.Reset()
+	d = LoadDefinition(t, "data.wsdl", nil)
+	if err = NewEncoder(&have, WithMinimal(true), WithEqualCopy(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"reflect", "soap.RegisterType"} {
+		if strings.Contains(have.String(), code) {
+			t.Errorf("unexpected %s in:\n%s", code, have.Bytes())
+		}
+	}
+	for _, want := range []string{"func (t *BaseReq) Equal(", "SkipXMLType: true,"} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+
+	src = `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+<types><xs:schema><xs:complexType name="Note"><xs:sequence>
+<xs:element name="Value" type="xs:anyType"/>
+</xs:sequence></xs:complexType></xs:schema></types></definitions>`
+	if d, err = wsdl.Unmarshal(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	err = NewEncoder(ioutil.Discard, WithMinimal(true), WithEqualCopy(true)).Encode(d)
+	if err == nil || !strings.Contains(err.Error(), "Note.Value: ") {
+		t.Errorf("Equal of interface{} without reflection: unexpected error %v", err)
+	}
+}
+
+func TestEncoderInlineSimpleTypes(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+<types><xs:schema><xs:complexType name="Order"><xs:sequence>
+<xs:element name="Quantity"><xs:simpleType><xs:restriction base="xs:int"><xs:minInclusive value="1"/></xs:restriction></xs:simpleType></xs:element>
+</xs:sequence>
+<xs:attribute name="priority"><xs:simpleType><xs:restriction base="xs:short"/></xs:simpleType></xs:attribute>
+</xs:complexType></xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Quantity *int  `xml:\"Quantity,omitempty\"",
+		"Priority int16 `xml:\"priority,attr,omitempty\"",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderGetters(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:test">
+<types><xs:schema targetNamespace="urn:test">
+<xs:complexType name="Person"><xs:sequence>
+<xs:element name="Name" type="xs:string" minOccurs="0"/>
+<xs:element name="Address" type="tns:Address" minOccurs="0"/>
+</xs:sequence></xs:complexType>
+<xs:complexType name="Address"><xs:sequence><xs:element name="City" type="xs:string"/></xs:sequence></xs:complexType>
+</xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have, WithGetters(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func (t *Person) GetName() (v string) {\n\tif t != nil && t.Name != nil {\n\t\tv = *t.Name\n\t}\n\treturn v\n}",
+		"func (t *Person) GetAddress() *Address {\n\tif t == nil {\n\t\treturn nil\n\t}\n\treturn t.Address\n}",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderEqualCopy(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:test">
+<types><xs:schema targetNamespace="urn:test">
+<xs:complexType name="Person"><xs:sequence>
+<xs:element name="Name" type="xs:string" minOccurs="0"/>
+<xs:element name="Address" type="tns:Address" minOccurs="0"/>
+<xs:element name="Phone" type="xs:string" maxOccurs="unbounded"/>
+</xs:sequence></xs:complexType>
+<xs:complexType name="Address"><xs:sequence><xs:element name="City" type="xs:string"/></xs:sequence></xs:complexType>
+</xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have, WithEqualCopy(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func (t *Person) Equal(o *Person) bool {\n\tif t == nil || o == nil {\n\t\treturn t == o\n\t}\n" +
+			"\tif (t.Name == nil) != (o.Name == nil) {\n\t\treturn false\n\t}\n" +
+			"\tif t.Name != nil {\n\t\tif *t.Name != *o.Name {\n\t\t\treturn false\n\t\t}\n\t}\n" +
+			"\tif !t.Address.Equal(o.Address) {\n\t\treturn false\n\t}\n" +
+			"\tif len(t.Phone) != len(o.Phone) {\n\t\treturn false\n\t}\n",
+		"func (t *Person) DeepCopy() *Person {\n\tif t == nil {\n\t\treturn nil\n\t}\n\tc := *t\n" +
+			"\tif t.Name != nil {\n\t\tv := *t.Name\n\t\tc.Name = &v\n\t}\n" +
+			"\tc.Address = t.Address.DeepCopy()\n",
+		"func (t *Address) Equal(o *Address) bool {",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderNaming(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:test">
+<types><xs:schema targetNamespace="urn:test">
+<xs:complexType name="XMLHttpRequest"><xs:sequence>
+<xs:element name="customerId" type="xs:string"/>
+<xs:element name="home_url" type="xs:string"/>
+<xs:element name="Id2" type="xs:string"/>
+<xs:element name="ssnNumber" type="xs:string"/>
+</xs:sequence></xs:complexType>
+</xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		Opts []Option
+		Want []string
+	}{
+		{
+			Want: []string{"type XMLHTTPRequest struct", "CustomerID ", "Home_URL ", "ID2 ", "SsnNumber "},
+		},
+		{
+			Opts: []Option{WithNaming(TitleNaming)},
+			Want: []string{"type XMLHttpRequest struct", "CustomerId ", "Home_url ", "Id2 "},
+		},
+		{
+			Opts: []Option{WithCompat("v1")},
+			Want: []string{"type XMLHttpRequest struct", "CustomerId "},
+		},
+		{
+			Opts: []Option{WithCompat("v1"), WithNaming(GoNaming)},
+			Want: []string{"type XMLHTTPRequest struct", "CustomerID "},
+		},
+		{
+			Opts: []Option{WithInitialisms([]string{"ssn", "ID"})},
+			Want: []string{"type XMLHttpRequest struct", "CustomerID ", "SSNNumber "},
+		},
+	} {
+		var have bytes.Buffer
+		if err = NewEncoder(&have, tc.Opts...).Encode(d); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		for _, want := range tc.Want {
+			if !strings.Contains(have.String(), want) {
+				t.Errorf("test %d: missing %q in:\n%s", i, want, have.Bytes())
+			}
+		}
+	}
+	if err = NewEncoder(ioutil.Discard, WithNaming("camel")).Encode(d); err == nil {
+		t.Error("unsupported naming: no error")
+	}
+}
+
+func TestEncoderTransliterate(t *testing.T) {
+	src := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:test">
+<types><xs:schema targetNamespace="urn:test">
+<xs:complexType name="Dirección"><xs:sequence>
+<xs:element name="número" type="xs:int"/>
+<xs:element name="Straße" type="xs:string"/>
+<xs:element name="ÉtatCivil" type="xs:string"/>
+<xs:element name="Имя" type="xs:string"/>
+<xs:element name="Όνομα" type="xs:string"/>
+<xs:element name="名前" type="xs:string"/>
+</xs:sequence></xs:complexType>
+</xs:schema></types></definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(have.String(), "type Direccion struct") {
+		t.Errorf("missing type Direccion in:\n%s", have.Bytes())
+	}
+	for name, tag := range map[string]string{
+		"Numero":    "número",
+		"Strasse":   "Straße",
+		"EtatCivil": "ÉtatCivil",
+		"Imya":      "Имя",
+		"Onoma":     "Όνομα",
+		"X名前":       "名前",
+	} {
+		field := regexp.MustCompile("\n\t" + name + " +\\S+ +`xml:\"" + tag + ",")
+		if !field.Match(have.Bytes()) {
+			t.Errorf("missing field %s of %s in:\n%s", name, tag, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderConstructors(t *testing.T) {
+	d := LoadDefinition(t, "constructors.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithConstructors(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func NewOrder(id string, items []string, _type string, version string) *Order {",
+		"currency := CurrencyCode(\"USD\")\n\tquantity := 1\n\tprice := float64(2)\n\tgift := false\n",
+		"Currency: &currency,",
+		"Lang:     \"en\",",
+		"Rev:      \"2\",",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+	for _, code := range []string{"func NewNote", "email string", "phone string", "Old "} {
+		if strings.Contains(have.String(), code) {
+			t.Errorf("unexpected %q in:\n%s", code, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderOmitEmpty(t *testing.T) {
+	d := LoadDefinition(t, "constructors.wsdl", nil)
+	var have bytes.Buffer
+	err := NewEncoder(&have, WithOmitEmpty(false), WithStructOmitEmpty("Note", true)).Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Currency CurrencyCode `xml:\"Currency\" json:\"Currency\" yaml:\"Currency\"`",
+		"Lang     string       `xml:\"lang,attr\" json:\"lang,attr\" yaml:\"lang,attr\"`",
+		"Text *string `xml:\"Text,omitempty\" json:\"Text,omitempty\" yaml:\"Text,omitempty\"`",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderValueScalars(t *testing.T) {
+	d := LoadDefinition(t, "constructors.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithValueScalars(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Currency CurrencyCode `xml:\"Currency,omitempty\" json:\"Currency,omitempty\" yaml:\"Currency,omitempty\"`",
+		"Quantity int          `xml:\"Quantity,omitempty\" json:\"Quantity,omitempty\" yaml:\"Quantity,omitempty\"`",
+		"Comment  *string      `xml:\"Comment,omitempty\" json:\"Comment,omitempty\" yaml:\"Comment,omitempty\"`",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderChoiceStructs(t *testing.T) {
+	d := LoadDefinition(t, "constructors.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithChoiceStructs(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Choice   *OrderChoice  `xml:\",any\" json:\"Choice,omitempty\" yaml:\"Choice,omitempty\"`",
+		"Email *string `xml:\"Email,omitempty\" json:\"Email,omitempty\" yaml:\"Email,omitempty\"`",
+		"func (c OrderChoice) Which() string {",
+		"func (c OrderChoice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {",
+		"return errors.New(\"Order: more than one element of the choice is set\")",
+		"func (c *OrderChoice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+	if strings.Contains(have.String(), "Email    string") {
+		t.Errorf("unexpected Email field of Order in:\n%s", have.Bytes())
+	}
+}
+
+func TestEncoderResponseWrapper(t *testing.T) {
+	d := LoadDefinition(t, "mixedstyle.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithResponseWrapper(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GetQuote(GetQuote *GetQuote) (*OperationGetQuoteOutput, error)",
+		"return &γ.OperationGetQuoteOutput, nil",
+		"GetTradePrices(tickerSymbol string) (*OperationGetTradePricesOutput, error)",
+		"return &γ.M, nil",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderArrayType(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("testdata", "arrayexample.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []byte(`<input>
+               <soap:body use="encoded"`)
+	cases := []struct {
+		Name    string
+		Src     []byte
+		Encoded bool
+	}{
+		{"literal", bytes.Replace(src, []byte(`use="encoded"`), []byte(`use="literal"`), -1), false},
+		{"encoded output", bytes.Replace(src, input, bytes.Replace(input, []byte("encoded"), []byte("literal"), 1), 1), true},
+	}
+	for _, tc := range cases {
+		d, err := wsdl.Unmarshal(bytes.NewReader(tc.Src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have bytes.Buffer
+		if err = NewEncoder(&have).Encode(d); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"ArrayType   string", "func (t *ArrayOfFloat) SetXMLType()"} {
+			if strings.Contains(have.String(), want) != tc.Encoded {
+				t.Errorf("%s: want %q %v in:\n%s", tc.Name, want, tc.Encoded, have.Bytes())
+			}
+		}
+		if strings.Contains(have.String(), "soap.Encoded()") {
+			t.Errorf("%s: unexpected encoded request in:\n%s", tc.Name, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderRawResponse(t *testing.T) {
+	d := LoadDefinition(t, "mixedstyle.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithRawResponse(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GetTradePrices(tickerSymbol string) (*ArrayOfFloat, *soap.Response, error)",
+		"ρ := new(soap.Response)",
+		"α, soap.WithResponse(&γ, ρ)); err != nil {",
+		"return nil, ρ, err",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderUnwrap(t *testing.T) {
+	d := LoadDefinition(t, "w3example1.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithUnwrap(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"GetEndorsingBoarder(GetEndorsingBoarder *GetEndorsingBoarder) (*string, error)",
+		"if γ.GetEndorsingBoarderResponse == nil {\n\t\treturn nil, nil\n\t}",
+		"return γ.GetEndorsingBoarderResponse.EndorsingBoarder, nil",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderStrict(t *testing.T) {
+	cases := []struct {
+		F string
+		E string
+	}{
+		{"data.wsdl", "unresolved types: {http://host.com/xsd}ClientIdentification (22:13), {http://host.com/xsd}ErrorDetails (28:13)"},
+		{"memcache.wsdl", ""},
+		{"w3example1.wsdl", ""},
+	}
+	for _, tc := range cases {
+		d := LoadDefinition(t, tc.F, nil)
+		have := ""
+		if err := NewEncoder(ioutil.Discard, WithStrict(true)).Encode(d); err != nil {
+			have = err.Error()
+		}
+		if have != tc.E {
+			t.Errorf("%s: want error %q, have %q", tc.F, tc.E, have)
+		}
+	}
+}
+
+func TestEncoderErrorPositions(t *testing.T) {
+	doc := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:tns="urn:test" targetNamespace="urn:test">
+<portType name="Orders">
+  <operation name="Get">
+    <input message="tns:GetIn"/>
+  </operation>
+</portType>
+<binding name="OrdersBinding" type="tns:Orders"/>
+</definitions>`
+	d, err := wsdl.UnmarshalFrom(strings.NewReader(doc), "orders.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `orders.wsdl:4:5: operation "Get" wants input message "GetIn" but it's not defined`
+	if err = NewEncoder(ioutil.Discard).Encode(d); err == nil || err.Error() != want {
+		t.Errorf("want error %q, have %v", want, err)
+	}
+}
+
+func TestEncoderLenient(t *testing.T) {
+	d := LoadDefinition(t, "lenient.wsdl", nil)
+	if err := NewEncoder(ioutil.Discard).Encode(d); err == nil {
+		t.Fatal("want error of unreachable import, have nil")
+	}
+	d = LoadDefinition(t, "lenient.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithLenient(true)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Address  []*Address `xml:\"address,omitempty\"",
+		"type Customer struct {\n\tRaw []byte `xml:\",innerxml\"`\n}",
+		"type Address struct {\n\tRaw []byte `xml:\",innerxml\"`\n}",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
+
+func TestEncoderReporter(t *testing.T) {
+	doc := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:test" targetNamespace="urn:test">
+<types><xs:schema targetNamespace="urn:test">
+<xs:complexType name="Price"><xs:simpleContent><xs:restriction base="xs:decimal"/></xs:simpleContent></xs:complexType>
+</xs:schema></types>
+<message name="GetIn"/>
+<message name="GetOut"/>
+<portType name="Orders">
+<operation name="Get"><input message="tns:GetIn"/><output message="tns:GetOut"/></operation>
+<operation name="Put"><input message="tns:GetIn"/></operation>
+</portType>
+<binding name="OrdersBinding" type="tns:Orders">
+<soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+<operation name="Get"><soap:operation soapAction="urn:Get"/></operation>
+</binding>
+</definitions>`
+	d, err := wsdl.UnmarshalFrom(strings.NewReader(doc), "orders.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	reporter := func(e Event) { have = append(have, e.String()) }
+	if err = NewEncoder(ioutil.Discard, WithReporter(reporter)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`orders.wsdl:9:23: skip: operation "Put" isn't in binding "OrdersBinding"`,
+		`orders.wsdl:3:1: skip: restriction of simpleContent of "Price" is not supported`,
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("want events\n%s\nhave\n%s", strings.Join(want, "\n"), strings.Join(have, "\n"))
+	}
+
+	have = nil
+	d = LoadDefinition(t, "localimport-url.wsdl", nil)
+	if err = NewEncoder(ioutil.Discard, WithReporter(reporter)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 1 || !strings.HasPrefix(have[0], "import: imported file://") {
+		t.Errorf("want import event, have %q", have)
+	}
+	have = nil
+	d = LoadDefinition(t, "lenient.wsdl", nil)
+	if err = NewEncoder(ioutil.Discard, WithLenient(true), WithReporter(reporter)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 || !strings.HasPrefix(have[0], "warning: skipped import of ") ||
+		!strings.HasPrefix(have[1], "warning: placeholders generated for types not found: ") {
+		t.Errorf("want warnings of import and placeholders, have %q", have)
+	}
+}
+
+func TestEncoderTestWriter(t *testing.T) {
+	d := LoadDefinition(t, "memcache.wsdl", nil)
+	var code, have bytes.Buffer
+	enc := NewEncoder(&code)
+	enc.SetTestWriter(&have)
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "memcache_test.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have.Bytes(), want) {
+		err := Diff("_diff", "go", want, have.Bytes())
+		t.Fatalf("memcache_test.golden mismatch: %v\ngenerated:\n%s\n", err, have.Bytes())
+	}
+}
+
 func Diff(prefix, ext string, a, b []byte) error {
 	diff, err := exec.LookPath("diff")
 	if err != nil {
@@ -130,3 +1138,212 @@ func Diff(prefix, ext string, a, b []byte) error {
 	}
 	return nil
 }
+
+// checkCompile builds the generated packages in src, by name, with the
+// go tool, and reports the compiler errors. Each package is built in
+// its own directory within the package of the tests, so that they
+// import the soap package of this tree. It's skipped in short mode, or
+// if the go tool isn't installed.
+func checkCompile(t *testing.T, src map[string][]byte) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping build of generated code in short mode")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("skipping build of generated code: %v", err)
+	}
+	dir, err := ioutil.TempDir(".", "_build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, b := range src {
+		pkg := filepath.Join(dir, strings.NewReplacer(".", "_", "-", "_").Replace(name))
+		if err = os.Mkdir(pkg, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(pkg, "generated.go"), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gotool, "build", "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("generated code doesn't compile: %v\n%s", err, out)
+	}
+}
+
+// uncompilable are the fixtures whose generated code doesn't compile,
+// as they refer to types or elements they don't define.
+var uncompilable = map[string]bool{
+	"data.wsdl":             true,
+	"data_withkeyword.wsdl": true,
+}
+
+func TestEncoderCompile(t *testing.T) {
+	s := NewTestServer(t)
+	defer s.Close()
+	src := make(map[string][]byte)
+	for _, tc := range EncoderCases {
+		if tc.G == "" || uncompilable[tc.F] {
+			continue
+		}
+		for variant, opt := range map[string]Option{
+			"":                 WithCompat(""),
+			"_v1":              WithCompat("v1"),
+			"_responsewrapper": WithResponseWrapper(true),
+			"_oplabels":        WithOperationLabels(true),
+		} {
+			d := LoadDefinition(t, tc.F, tc.E)
+			var have bytes.Buffer
+			if err := NewEncoder(&have, opt).Encode(d); err != nil {
+				t.Errorf("encoding %q%s: %v", tc.F, variant, err)
+				continue
+			}
+			src[tc.F+variant] = have.Bytes()
+		}
+	}
+	checkCompile(t, src)
+}
+
+// largeWSDL returns a document/literal WSDL with n operations, each
+// with its own request and response elements, complex types that
+// reference each other, and enumerated simple types. It stands in for
+// huge real world WSDLs, which are too big to keep in testdata.
+func largeWSDL(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<definitions name="Large" targetNamespace="urn:large"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:large"
+  xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+<types><xsd:schema targetNamespace="urn:large" elementFormDefault="qualified">
+`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<xsd:simpleType name="Status%d"><xsd:restriction base="xsd:string">
+  <xsd:enumeration value="Active"/><xsd:enumeration value="Inactive"/>
+</xsd:restriction></xsd:simpleType>
+<xsd:complexType name="Record%d"><xsd:sequence>
+  <xsd:element name="Id" type="xsd:string"/>
+  <xsd:element name="Name" type="xsd:string" minOccurs="0"/>
+  <xsd:element name="Amount" type="xsd:decimal" minOccurs="0"/>
+  <xsd:element name="Created" type="xsd:dateTime" minOccurs="0"/>
+  <xsd:element name="Status" type="tns:Status%d" minOccurs="0"/>
+  <xsd:element name="Parent" type="tns:Record%d" minOccurs="0"/>
+  <xsd:element name="Items" type="tns:Record%d" minOccurs="0" maxOccurs="unbounded"/>
+</xsd:sequence><xsd:attribute name="version" type="xsd:int"/></xsd:complexType>
+<xsd:element name="Get%d"><xsd:complexType><xsd:sequence>
+  <xsd:element name="Id" type="xsd:string"/>
+</xsd:sequence></xsd:complexType></xsd:element>
+<xsd:element name="Get%dResponse"><xsd:complexType><xsd:sequence>
+  <xsd:element name="Result" type="tns:Record%d"/>
+</xsd:sequence></xsd:complexType></xsd:element>
+`, i, i, i, (i+1)%n, (i+2)%n, i, i, i)
+	}
+	b.WriteString("</xsd:schema></types>\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<message name="Get%dRequest"><part name="parameters" element="tns:Get%d"/></message>
+<message name="Get%dResponse"><part name="parameters" element="tns:Get%dResponse"/></message>
+`, i, i, i, i)
+	}
+	b.WriteString(`<portType name="LargePortType">`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<operation name="Get%d"><input message="tns:Get%dRequest"/><output message="tns:Get%dResponse"/></operation>
+`, i, i, i)
+	}
+	b.WriteString(`</portType>
+<binding name="LargeBinding" type="tns:LargePortType">
+<soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<operation name="Get%d"><soap:operation soapAction="urn:Get%d"/>
+<input><soap:body use="literal"/></input><output><soap:body use="literal"/></output></operation>
+`, i, i)
+	}
+	b.WriteString(`</binding>
+<service name="LargeService"><port name="LargePort" binding="tns:LargeBinding">
+<soap:address location="http://localhost/large"/></port></service>
+</definitions>
+`)
+	return b.Bytes()
+}
+
+func benchmarkEncoder(b *testing.B, n int) {
+	src := largeWSDL(n)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d, err := wsdl.Unmarshal(bytes.NewReader(src))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = NewEncoder(ioutil.Discard).Encode(d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder100(b *testing.B)  { benchmarkEncoder(b, 100) }
+func BenchmarkEncoder1000(b *testing.B) { benchmarkEncoder(b, 1000) }
+
+func TestEncoderOperationLabels(t *testing.T) {
+	cases := []struct {
+		F    string
+		Want []string
+	}{
+		{"memcache.wsdl", []string{
+			`if err := soap.Observe(p.Client, "memory_service.memory_service.get", time.Now(), p.Client.RoundTripWithAction("Get", α, &γ)); err != nil {`,
+		}},
+		{"mime.wsdl", []string{
+			"start := time.Now()\n",
+			`err = soap.Observe(p.Client, "`,
+		}},
+	}
+	for _, tc := range cases {
+		d := LoadDefinition(t, tc.F, nil)
+		var have bytes.Buffer
+		enc := NewEncoder(&have)
+		enc.SetOperationLabels(true)
+		if err := enc.Encode(d); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.Want {
+			if !strings.Contains(have.String(), want) {
+				t.Errorf("%s: missing %q in:\n%s", tc.F, want, have.Bytes())
+			}
+		}
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	cases := []struct{ In, Want string }{
+		{"GetOrder", "get_order"},
+		{"getOrder", "get_order"},
+		{"GetHTTPStatus", "get_http_status"},
+		{"Order2Cash", "order2_cash"},
+		{"gkstServer_getVersion", "gkst_server_get_version"},
+		{"Stock Quote-Service", "stock_quote_service"},
+		{"memcache", "memcache"},
+	}
+	for _, tc := range cases {
+		if have := labelName(tc.In); have != tc.Want {
+			t.Errorf("labelName(%q): want %q, have %q", tc.In, tc.Want, have)
+		}
+	}
+}
+
+func TestParamName(t *testing.T) {
+	cases := []struct{ In, Want string }{
+		{"Name", "name"},
+		{"ID", "id"},
+		{"URLPath", "urlPath"},
+		{"userID", "userID"},
+		{"X", "x"},
+	}
+	for _, tc := range cases {
+		if have := paramName(tc.In); have != tc.Want {
+			t.Errorf("paramName(%q): want %q, have %q", tc.In, tc.Want, have)
+		}
+	}
+}
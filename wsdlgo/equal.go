@@ -0,0 +1,270 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// equalCopy writes the Equal and DeepCopy methods of generated structs.
+// Fields are compared and copied according to their types: values that
+// are comparable are compared with == and assigned, pointers and slices
+// are followed, structs with those methods call them, and the others,
+// such as abstract types, are compared with reflect.DeepEqual and
+// assigned as is. Without reflection, comparing those is an error.
+type equalCopy struct {
+	types   map[string]ast.Expr // types of the generated code, by name
+	methods map[string]bool     // types with Equal and DeepCopy methods
+	depth   int                 // of the nested blocks being written
+	minimal bool                // whether reflection is unavailable
+	err     error               // of the field that can't be compared
+}
+
+// newEqualCopy returns an equalCopy for the generated code in f, which
+// writes the methods of the given structs, except for those with fields
+// named Equal or DeepCopy.
+func newEqualCopy(f *ast.File, structs map[string]bool) *equalCopy {
+	ec := &equalCopy{
+		types: make(map[string]ast.Expr),
+		// also declared by the soap package
+		methods: map[string]bool{"soap.AnyElement": true},
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			ec.types[ts.Name.Name] = ts.Type
+		}
+	}
+	for name := range structs {
+		st, ok := ec.types[name].(*ast.StructType)
+		if !ok {
+			continue
+		}
+		ec.methods[name] = true
+		for _, field := range st.Fields.List {
+			for _, id := range field.Names {
+				if id.Name == "Equal" || id.Name == "DeepCopy" {
+					delete(ec.methods, name)
+				}
+			}
+		}
+	}
+	return ec
+}
+
+// write writes the Equal and DeepCopy methods of struct st to w.
+func (ec *equalCopy) write(w *bytes.Buffer, name string, st *ast.StructType) error {
+	var eq, cp bytes.Buffer
+	for _, field := range st.Fields.List {
+		for _, f := range fieldNames(field) {
+			if f == "_" || f == "XMLName" {
+				continue
+			}
+			ec.equal(&eq, "t."+f, "o."+f, field.Type)
+			if ec.err != nil {
+				return fmt.Errorf("%s.%s: %v", name, f, ec.err)
+			}
+			// fields are assigned by copying the struct
+			var b bytes.Buffer
+			ec.copy(&b, "c."+f, "t."+f, field.Type)
+			if b.String() != "c."+f+" = t."+f+"\n" {
+				b.WriteTo(&cp)
+			}
+		}
+	}
+	fmt.Fprintf(w, "// Equal reports whether t and o have the same values, or are both nil.\n")
+	fmt.Fprintf(w, "func (t *%s) Equal(o *%s) bool {\n", name, name)
+	fmt.Fprintf(w, "if t == nil || o == nil {\nreturn t == o\n}\n%sreturn true\n}\n\n", eq.Bytes())
+	fmt.Fprintf(w, "// DeepCopy returns a copy of t that shares no pointers or slices\n")
+	fmt.Fprintf(w, "// with it, or nil if t is nil.\n")
+	fmt.Fprintf(w, "func (t *%s) DeepCopy() *%s {\n", name, name)
+	fmt.Fprintf(w, "if t == nil {\nreturn nil\n}\nc := *t\n%sreturn &c\n}\n\n", cp.Bytes())
+	return nil
+}
+
+// equal writes the statements returning false if a and b, of type typ,
+// aren't equal, to w.
+func (ec *equalCopy) equal(w *bytes.Buffer, a, b string, typ ast.Expr) {
+	if ec.methods[typeKey(typ)] {
+		fmt.Fprintf(w, "if !%s.Equal(&%s) {\nreturn false\n}\n", paren(a), b)
+		return
+	}
+	switch t := ec.underlying(typ).(type) {
+	case *ast.StarExpr:
+		if ec.methods[typeKey(t.X)] {
+			fmt.Fprintf(w, "if !%s.Equal(%s) {\nreturn false\n}\n", paren(a), b)
+			return
+		}
+		fmt.Fprintf(w, "if (%s == nil) != (%s == nil) {\nreturn false\n}\n", a, b)
+		fmt.Fprintf(w, "if %s != nil {\n", a)
+		ec.depth++
+		ec.equal(w, "*"+a, "*"+b, t.X)
+		ec.depth--
+		fmt.Fprintf(w, "}\n")
+		return
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		if typeKey(t.Elt) == "byte" {
+			fmt.Fprintf(w, "if !bytes.Equal(%s, %s) {\nreturn false\n}\n", a, b)
+			return
+		}
+		i := ec.newVar("i")
+		fmt.Fprintf(w, "if len(%s) != len(%s) {\nreturn false\n}\n", a, b)
+		fmt.Fprintf(w, "for %s := range %s {\n", i, a)
+		ec.depth++
+		ec.equal(w, paren(a)+"["+i+"]", paren(b)+"["+i+"]", t.Elt)
+		ec.depth--
+		fmt.Fprintf(w, "}\n")
+		return
+	}
+	if ec.comparable(typ) {
+		fmt.Fprintf(w, "if %s != %s {\nreturn false\n}\n", a, b)
+		return
+	}
+	if ec.minimal && ec.err == nil {
+		ec.err = fmt.Errorf("values of type %s can't be compared without reflection", exprString(typ))
+	}
+	fmt.Fprintf(w, "if !reflect.DeepEqual(%s, %s) {\nreturn false\n}\n", a, b)
+}
+
+// copy writes the statements assigning a copy of src, of type typ,
+// to dst, to w.
+func (ec *equalCopy) copy(w *bytes.Buffer, dst, src string, typ ast.Expr) {
+	if ec.methods[typeKey(typ)] {
+		fmt.Fprintf(w, "%s = *%s.DeepCopy()\n", dst, paren(src))
+		return
+	}
+	switch t := ec.underlying(typ).(type) {
+	case *ast.StarExpr:
+		if ec.methods[typeKey(t.X)] {
+			fmt.Fprintf(w, "%s = %s.DeepCopy()\n", dst, paren(src))
+			return
+		}
+		v := ec.newVar("v")
+		if ec.comparable(t.X) {
+			fmt.Fprintf(w, "if %s != nil {\n%s := *%s\n%s = &%s\n}\n", src, v, src, dst, v)
+			return
+		}
+		fmt.Fprintf(w, "if %s != nil {\n%s := new(%s)\n", src, v, exprString(t.X))
+		ec.depth++
+		ec.copy(w, "*"+v, "*"+src, t.X)
+		ec.depth--
+		fmt.Fprintf(w, "%s = %s\n}\n", dst, v)
+		return
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		fmt.Fprintf(w, "if %s != nil {\n%s = make(%s, len(%s))\n", src, dst, exprString(typ), src)
+		if ec.comparable(t.Elt) {
+			fmt.Fprintf(w, "copy(%s, %s)\n}\n", dst, src)
+			return
+		}
+		i := ec.newVar("i")
+		fmt.Fprintf(w, "for %s := range %s {\n", i, src)
+		ec.depth++
+		ec.copy(w, paren(dst)+"["+i+"]", paren(src)+"["+i+"]", t.Elt)
+		ec.depth--
+		fmt.Fprintf(w, "}\n}\n")
+		return
+	}
+	fmt.Fprintf(w, "%s = %s\n", dst, src)
+}
+
+// underlying returns the type of the generated code that typ is named
+// after, if any, or typ.
+func (ec *equalCopy) underlying(typ ast.Expr) ast.Expr {
+	for i := 0; i < len(ec.types); i++ {
+		id, ok := typ.(*ast.Ident)
+		if !ok || ec.types[id.Name] == nil {
+			break
+		}
+		typ = ec.types[id.Name]
+	}
+	return typ
+}
+
+// comparable reports whether the values of typ can be compared with ==
+// and copied by assignment.
+func (ec *equalCopy) comparable(typ ast.Expr) bool {
+	if ec.methods[typeKey(typ)] {
+		return false
+	}
+	switch t := ec.underlying(typ).(type) {
+	case *ast.Ident:
+		// predeclared types, as others are resolved
+		return true
+	case *ast.SelectorExpr:
+		switch typeKey(t) {
+		case "xml.Name", "xml.Attr":
+			return true
+		}
+	}
+	return false
+}
+
+// newVar returns the name of a variable declared in the block being
+// written, which doesn't shadow those of the blocks it's nested in.
+func (ec *equalCopy) newVar(prefix string) string {
+	if ec.depth == 0 {
+		return prefix
+	}
+	return prefix + strconv.Itoa(ec.depth+1)
+}
+
+// fieldNames returns the names of field, or the name of its type if
+// it's embedded.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		key := typeKey(field.Type)
+		if star, ok := field.Type.(*ast.StarExpr); ok {
+			key = typeKey(star.X)
+		}
+		return []string{key[strings.LastIndex(key, ".")+1:]}
+	}
+	names := make([]string, len(field.Names))
+	for i, id := range field.Names {
+		names[i] = id.Name
+	}
+	return names
+}
+
+// typeKey returns the name of typ, such as Order or soap.AnyElement, or
+// "" if it's not a named type.
+func typeKey(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			return pkg.Name + "." + t.Sel.Name
+		}
+	}
+	return ""
+}
+
+// exprString returns the source code of the expression e.
+func exprString(e ast.Expr) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, token.NewFileSet(), e)
+	return b.String()
+}
+
+// paren returns the expression s in parentheses if it's dereferenced,
+// to be indexed or called.
+func paren(s string) string {
+	if strings.HasPrefix(s, "*") {
+		return "(" + s + ")"
+	}
+	return s
+}
@@ -0,0 +1,360 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chunkSize is the size of the generated code formatted at once. The
+// code of large WSDLs is formatted in chunks of top-level declarations,
+// as parsing it whole takes several times its size in memory.
+var chunkSize = 256 << 10
+
+// spillSize is the size of the formatted code kept in memory until its
+// imports are known, beyond which it's written to a temporary file.
+var spillSize = 4 << 20
+
+// chunkFormatter formats the generated code written to it in chunks, as
+// gofmt would, and records the packages it uses to import them.
+type chunkFormatter struct {
+	out   spill
+	code  []byte          // not formatted yet
+	scan  int             // length of code at which to look for a chunk
+	pkgs  map[string]bool // names of the packages used by the code
+	decls map[string]bool // names declared by the code, see packages
+	tmp   bytes.Buffer
+}
+
+func newChunkFormatter() *chunkFormatter {
+	return &chunkFormatter{
+		scan:  chunkSize,
+		pkgs:  make(map[string]bool),
+		decls: make(map[string]bool),
+	}
+}
+
+// packages returns the names of the packages used by the code formatted
+// so far. Names selected from in a chunk but declared in another one,
+// which can't be resolved in the chunk, aren't packages.
+func (f *chunkFormatter) packages() map[string]bool {
+	pkgs := make(map[string]bool, len(f.pkgs))
+	for name := range f.pkgs {
+		if !f.decls[name] {
+			pkgs[name] = true
+		}
+	}
+	return pkgs
+}
+
+// Write buffers p, and formats the declarations written so far once
+// they're larger than chunkSize.
+func (f *chunkFormatter) Write(p []byte) (int, error) {
+	f.code = append(f.code, p...)
+	if len(f.code) < f.scan {
+		return len(p), nil
+	}
+	n := declBoundary(f.code)
+	if n == 0 {
+		// a single declaration larger than chunkSize
+		f.scan = len(f.code) + chunkSize
+		return len(p), nil
+	}
+	if err := f.format(f.code[:n]); err != nil {
+		return 0, err
+	}
+	f.code = append(f.code[:0], f.code[n:]...)
+	f.scan = chunkSize
+	return len(p), nil
+}
+
+// Flush formats the rest of the code.
+func (f *chunkFormatter) Flush() error {
+	if len(bytes.TrimSpace(f.code)) > 0 {
+		if err := f.format(f.code); err != nil {
+			return err
+		}
+	}
+	f.code = nil
+	return nil
+}
+
+// format formats the declarations in code to out.
+func (f *chunkFormatter) format(code []byte) error {
+	const pkg = "package p\n\n"
+	src := append([]byte(pkg), code...)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return badCode(err, src)
+	}
+	usedPackages(file, f.pkgs)
+	for name := range file.Scope.Objects {
+		f.decls[name] = true
+	}
+	f.tmp.Reset()
+	if err = format.Node(&f.tmp, fset, file); err != nil {
+		return err
+	}
+	if f.out.Len() > 0 {
+		if _, err = f.out.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+	_, err = f.out.Write(bytes.TrimPrefix(f.tmp.Bytes(), []byte(pkg)))
+	return err
+}
+
+// WriteTo writes the code formatted by f to w.
+func (f *chunkFormatter) WriteTo(w io.Writer) (int64, error) {
+	return f.out.WriteTo(w)
+}
+
+// Close removes the temporary file of the code formatted by f, if any.
+func (f *chunkFormatter) Close() error {
+	return f.out.Close()
+}
+
+// spill is a buffer that moves its contents to a temporary file once
+// they're larger than spillSize.
+type spill struct {
+	buf  bytes.Buffer
+	file *os.File
+	n    int
+}
+
+func (s *spill) Write(p []byte) (int, error) {
+	if s.file == nil && s.n+len(p) > spillSize {
+		f, err := ioutil.TempFile("", "wsdl2go")
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+		if _, err = s.buf.WriteTo(f); err != nil {
+			return 0, err
+		}
+	}
+	s.n += len(p)
+	if s.file != nil {
+		return s.file.Write(p)
+	}
+	return s.buf.Write(p)
+}
+
+// Len returns the size of the contents of s.
+func (s *spill) Len() int {
+	return s.n
+}
+
+// WriteTo writes the contents of s to w.
+func (s *spill) WriteTo(w io.Writer) (int64, error) {
+	if s.file == nil {
+		return s.buf.WriteTo(w)
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(w, s.file)
+}
+
+// Close removes the temporary file of s, if any.
+func (s *spill) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if rmErr := os.Remove(s.file.Name()); err == nil {
+		err = rmErr
+	}
+	s.file = nil
+	return err
+}
+
+// declBoundary returns the offset of the start of the last top-level
+// declaration in code preceded by a blank line, with its comments, or 0
+// if there's none.
+func declBoundary(code []byte) int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(code))
+	var s scanner.Scanner
+	s.Init(file, code, nil, 0)
+	boundary, end, depth := 0, 0, 0
+	for {
+		pos, tok, lit := s.Scan()
+		off := file.Offset(pos)
+		switch tok {
+		case token.EOF:
+			return boundary
+		case token.LBRACE, token.LPAREN, token.LBRACK:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACK:
+			depth--
+		case token.FUNC, token.TYPE, token.VAR, token.CONST:
+			// declarations at the end of code may be incomplete
+			if depth == 0 && off+len(lit) < len(code) {
+				if i := bytes.LastIndex(code[end:off], []byte("\n\n")); i >= 0 {
+					boundary = end + i + 2
+				}
+			}
+		}
+		if lit == "" {
+			lit = tok.String()
+		}
+		end = off + len(lit)
+	}
+}
+
+// usedPackages records in pkgs the names of the packages used by file:
+// the identifiers that names are selected from, which aren't declared
+// in it.
+func usedPackages(file *ast.File, pkgs map[string]bool) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				pkgs[id.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// knownPackages are the packages the generated code may use without
+// recording them in needsStdPkg or needsExtPkg. Imports are resolved
+// from this list rather than by looking packages up in GOPATH, as
+// golang.org/x/tools/imports does, so the output doesn't depend on the
+// packages installed where wsdl2go runs, and no Go toolchain is needed.
+var knownPackages = []string{
+	"bytes",
+	"context",
+	"encoding/json",
+	"encoding/xml",
+	"errors",
+	"fmt",
+	"io",
+	"net/http",
+	"net/url",
+	"reflect",
+	"strconv",
+	"strings",
+	"time",
+}
+
+// importPaths returns the paths of the packages the generated code may
+// import, by name: knownPackages, and those recorded in needsStdPkg and
+// needsExtPkg. No other packages are looked up.
+func (ge *goEncoder) importPaths() map[string]string {
+	paths := make(map[string]string)
+	for _, p := range knownPackages {
+		paths[path.Base(p)] = p
+	}
+	for p := range ge.needsStdPkg {
+		paths[path.Base(p)] = p
+	}
+	for p := range ge.needsExtPkg {
+		paths[path.Base(p)] = p
+	}
+	return paths
+}
+
+// writeImports writes the import declaration of the packages of pkgs,
+// by name, whose paths are known, standard ones first. Packages whose
+// names aren't the base of their paths are imported with their names.
+// It returns the names of the packages whose paths aren't known, sorted.
+func writeImports(w io.Writer, pkgs map[string]bool, paths map[string]string) (unknown []string) {
+	var std, ext []string
+	for name := range pkgs {
+		p, ok := paths[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		spec := strconv.Quote(p)
+		if path.Base(p) != name {
+			spec = name + " " + spec
+		}
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			ext = append(ext, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Strings(unknown)
+	if len(std)+len(ext) == 0 {
+		return unknown
+	}
+	sort.Slice(std, func(i, j int) bool { return importPath(std[i]) < importPath(std[j]) })
+	sort.Slice(ext, func(i, j int) bool { return importPath(ext[i]) < importPath(ext[j]) })
+	fmt.Fprintf(w, "import (\n")
+	for _, spec := range std {
+		fmt.Fprintf(w, "\t%s\n", spec)
+	}
+	if len(std) > 0 && len(ext) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+	for _, spec := range ext {
+		fmt.Fprintf(w, "\t%s\n", spec)
+	}
+	fmt.Fprintf(w, ")\n\n")
+	return unknown
+}
+
+// importPath returns the path of the import spec written by
+// writeImports.
+func importPath(spec string) string {
+	return spec[strings.Index(spec, `"`):]
+}
+
+// fixImports formats file, replacing its imports with those of the
+// packages it uses: the ones it imports, or else those of paths. It
+// returns the names of the packages used whose paths aren't known.
+func fixImports(w io.Writer, fset *token.FileSet, file *ast.File, paths map[string]string) ([]string, error) {
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		paths[name] = p
+	}
+	pkgs := make(map[string]bool)
+	usedPackages(file, pkgs)
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, decl)
+		}
+	}
+	file.Decls, file.Imports = decls, nil
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, file); err != nil {
+		return nil, err
+	}
+	// the imports go after the package clause
+	src := b.Bytes()
+	clause := []byte("package " + file.Name.Name + "\n")
+	i := bytes.Index(src, clause) + len(clause)
+	if _, err := w.Write(src[:i]); err != nil {
+		return nil, err
+	}
+	if i < len(src) && src[i] == '\n' {
+		i++
+	}
+	fmt.Fprintf(w, "\n")
+	unknown := writeImports(w, pkgs, paths)
+	_, err := w.Write(src[i:])
+	return unknown, err
+}
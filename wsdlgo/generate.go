@@ -0,0 +1,108 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// Options configures Generate.
+type Options struct {
+	// PackageName is the name of the generated package. If empty,
+	// it's derived from the WSDL binding.
+	PackageName string
+
+	// Client downloads the documents imported by the WSDL. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the location of the WSDL document, used to resolve
+	// relative imports.
+	BaseURL string
+
+	// Tests also generates XML round-trip tests of the generated types.
+	Tests bool
+
+	// Encoder has more options of the Encoder, applied after the ones
+	// above.
+	Encoder []Option
+}
+
+// Generate generates Go code from the WSDL document read from src. It
+// returns the generated files by name: the code in PackageName.go, or
+// client.go without a package name, and optionally the tables of large
+// enumerations in *_enums.go and the tests in *_test.go. The files are
+// formatted, and ready to be written to a package directory.
+//
+// Documents imported by the WSDL are downloaded with ctx.
+func Generate(ctx context.Context, src io.Reader, opts Options) (map[string][]byte, error) {
+	d, err := wsdl.UnmarshalFrom(src, opts.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	name := "client.go"
+	if opts.PackageName != "" {
+		name = opts.PackageName + ".go"
+	}
+	cli := opts.Client
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	var code, enums, tests bytes.Buffer
+	encOpts := []Option{
+		WithClient(contextClient(ctx, cli)),
+		WithEnumWriter(&enums),
+	}
+	if opts.PackageName != "" {
+		encOpts = append(encOpts, WithPackageName(PackageName(opts.PackageName)))
+	}
+	if opts.BaseURL != "" {
+		encOpts = append(encOpts, WithBaseURL(opts.BaseURL))
+	}
+	if opts.Tests {
+		encOpts = append(encOpts, WithTestWriter(&tests))
+	}
+	encOpts = append(encOpts, opts.Encoder...)
+	if err = NewEncoder(&code, encOpts...).Encode(d); err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{name: code.Bytes()}
+	base := strings.TrimSuffix(name, ".go")
+	if enums.Len() > 0 {
+		files[base+"_enums.go"] = enums.Bytes()
+	}
+	if tests.Len() > 0 {
+		files[base+"_test.go"] = tests.Bytes()
+	}
+	return files, nil
+}
+
+// contextClient returns a copy of cli that makes its requests with ctx.
+func contextClient(ctx context.Context, cli *http.Client) *http.Client {
+	c := *cli
+	c.Transport = &contextTransport{ctx: ctx, base: cli.Transport}
+	return &c
+}
+
+// contextTransport is an http.RoundTripper that makes requests with ctx.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r.WithContext(t.ctx))
+}
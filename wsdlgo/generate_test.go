@@ -0,0 +1,65 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "memcache.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	files, err := Generate(context.Background(), f, Options{PackageName: "memoryservice", Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("want 2 files, have %d", len(files))
+	}
+	for name, golden := range map[string]string{
+		"memoryservice.go":      "memcache.golden",
+		"memoryservice_test.go": "memcache_test.golden",
+	} {
+		want, err := ioutil.ReadFile(filepath.Join("testdata", golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(files[name], want) {
+			t.Errorf("%s mismatch with %s:\n%s", name, golden, files[name])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Generate(ctx, strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`), Options{})
+	if err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+}
+
+func TestContextClient(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	cli := contextClient(context.Background(), http.DefaultClient)
+	resp, err := cli.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = contextClient(ctx, http.DefaultClient).Get(s.URL); err == nil {
+		t.Error("unexpected success with canceled context")
+	}
+}
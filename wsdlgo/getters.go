@@ -0,0 +1,108 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+)
+
+// addMethods returns the generated code in src with methods added after
+// each generated struct: getters, see writeGetters, and Equal and
+// DeepCopy methods, see equalCopy, as enabled.
+func (ge *goEncoder) addMethods(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, badCode(err, src)
+	}
+	structs := make(map[string]bool, len(ge.structs))
+	for _, name := range ge.structs {
+		structs[name] = true
+	}
+	var ec *equalCopy
+	if ge.equalCopy {
+		ec = newEqualCopy(f, structs)
+		ec.minimal = ge.minimal
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		var methods bytes.Buffer
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !structs[ts.Name.Name] {
+				continue
+			}
+			if ge.getters {
+				if err = writeGetters(&methods, ts.Name.Name, st, structs); err != nil {
+					return nil, err
+				}
+			}
+			if ec != nil && ec.methods[ts.Name.Name] {
+				if err = ec.write(&methods, ts.Name.Name, st); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if methods.Len() == 0 {
+			continue
+		}
+		end := fset.Position(gd.End()).Offset
+		b.Write(src[last:end])
+		b.WriteString("\n")
+		methods.WriteTo(&b)
+		last = end
+	}
+	b.Write(src[last:])
+	return b.Bytes(), nil
+}
+
+// writeGetters writes the getters of the pointer fields of struct st.
+func writeGetters(w *bytes.Buffer, name string, st *ast.StructType, structs map[string]bool) error {
+	fields := make(map[string]bool)
+	for _, field := range st.Fields.List {
+		for _, id := range field.Names {
+			fields[id.Name] = true
+		}
+	}
+	var names []string
+	types := make(map[string]ast.Expr)
+	for _, field := range st.Fields.List {
+		star, ok := field.Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		for _, id := range field.Names {
+			if id.IsExported() && !fields["Get"+id.Name] {
+				names = append(names, id.Name)
+				types[id.Name] = star.X
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		var typ bytes.Buffer
+		if err := printer.Fprint(&typ, token.NewFileSet(), types[field]); err != nil {
+			return err
+		}
+		if id, ok := types[field].(*ast.Ident); ok && structs[id.Name] {
+			fmt.Fprintf(w, "// Get%s returns %s, or nil if the receiver is nil.\n", field, field)
+			fmt.Fprintf(w, "func (t *%s) Get%s() *%s {\n", name, field, typ.Bytes())
+			fmt.Fprintf(w, "if t == nil {\nreturn nil\n}\nreturn t.%s\n}\n\n", field)
+			continue
+		}
+		fmt.Fprintf(w, "// Get%s returns the value of %s, or its zero value if it's nil.\n", field, field)
+		fmt.Fprintf(w, "func (t *%s) Get%s() (v %s) {\n", name, field, typ.Bytes())
+		fmt.Fprintf(w, "if t != nil && t.%s != nil {\nv = *t.%s\n}\nreturn v\n}\n\n", field, field)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+var groupT = template.Must(template.New("group").Parse(`
+// {{.Item}} is an occurrence of the repeated {{.Kind}} of elements of
+// {{.Parent}}.
+type {{.Item}} struct {
+{{.Fields}}}
+
+// {{.Name}} are the occurrences of the repeated {{.Kind}} of elements of
+// {{.Parent}}, encoded one after another.
+type {{.Name}} []*{{.Item}}
+
+// MarshalXML encodes the elements of each occurrence that are set.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	for _, item := range v {
+{{- range .Members}}
+		if err := e.EncodeElement(item.{{.Field}}, xml.StartElement{Name: xml.Name{Local: {{printf "%q" .Name}}}}); err != nil {
+			return err
+		}
+{{- end}}
+	}
+	return nil
+}
+
+// UnmarshalXML decodes the element in start into the last occurrence,
+// or into a new one if it can't be part of the last.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var item *{{.Item}}
+	if n := len(*v); n > 0 {
+		item = (*v)[n-1]
+	}
+	switch start.Name.Local {
+{{- range .Members}}
+	case {{printf "%q" .Name}}:
+		if item == nil{{range .Next}} || {{.}}{{end}} {
+			item = new({{$.Item}})
+			*v = append(*v, item)
+		}
+		return d.DecodeElement(&item.{{.Field}}, &start)
+{{- end}}
+	}
+	return d.Skip()
+}
+
+`))
+
+type groupMember struct {
+	Field, Name string
+	Next        []string // conditions of a new occurrence
+}
+
+// repeatedGroup is a sequence or choice of elements with maxOccurs
+// greater than one, generated as a slice of structs of its elements.
+type repeatedGroup struct {
+	kind     string
+	elements []*wsdl.Element
+}
+
+// repeated reports whether max, the maxOccurs of an element or
+// compositor, allows more than one occurrence.
+func repeated(max string) bool {
+	return max != "" && max != "0" && max != "1"
+}
+
+// sequenceGroup returns the group of the repeated sequence seq, with
+// the elements of its choices and sequences.
+func sequenceGroup(seq *wsdl.Sequence) *repeatedGroup {
+	g := &repeatedGroup{kind: "sequence"}
+	var add func(seq *wsdl.Sequence)
+	add = func(seq *wsdl.Sequence) {
+		g.elements = append(g.elements, seq.Elements...)
+		for _, c := range seq.Choices {
+			g.elements = append(g.elements, c.Elements...)
+		}
+		for _, s := range seq.Sequences {
+			add(s)
+		}
+	}
+	add(seq)
+	return g
+}
+
+// choiceGroupOf returns the group of the repeated choice c.
+func choiceGroupOf(c *wsdl.Choice) *repeatedGroup {
+	return &repeatedGroup{kind: "choice", elements: c.Elements}
+}
+
+// genGroupField writes the field of the repeated group g of ct to w.
+// The first group of a struct is generated as a slice of structs, in
+// its Items field, decoded from the elements that match no other field.
+// As there can only be one such field, the elements of other groups,
+// whose occurrences can't be told apart, are generated as slices.
+func (ge *goEncoder) genGroupField(w io.Writer, ct *wsdl.ComplexType, g *repeatedGroup, hasAny bool) error {
+	if hasAny || ge.choiceField || ge.anyField || ct.Name == "" {
+		ge.choices++
+		for _, el := range g.elements {
+			member := *el
+			member.Max = "unbounded"
+			ge.genElementField(w, &member)
+		}
+		ge.choices--
+		return nil
+	}
+	return ge.genGroupStruct(w, ct, g)
+}
+
+// genGroupStruct writes the Items field of the repeated group g of ct
+// to w, and generates its types, unless they already were for another
+// struct extending ct.
+func (ge *goEncoder) genGroupStruct(w io.Writer, ct *wsdl.ComplexType, g *repeatedGroup) error {
+	parent := ge.goSymbol(ct.Name)
+	name, item := parent+"Items", parent+"Item"
+	ge.choiceField = true
+	fmt.Fprintf(w, "Items %s `xml:\",any\" json:\"Items,omitempty\" yaml:\"Items,omitempty\"`\n", name)
+	if ge.groupTypes[name] {
+		return nil
+	}
+	ge.groupTypes[name] = true
+	ge.structs = append(ge.structs, item)
+
+	// elements are generated as the fields of another struct, and must
+	// be pointers or slices to tell the ones that are set
+	fields, structName, ptrFields := ge.fields, ge.structName, ge.ptrFields
+	defer func() { ge.fields, ge.structName, ge.ptrFields = fields, structName, ptrFields }()
+	ge.fields, ge.structName, ge.ptrFields = nil, item, true
+	var elements []*wsdl.Element
+	for _, el := range g.elements {
+		if el.Ref != "" {
+			if el, _ = ge.element(el.Ref); el == nil {
+				continue
+			}
+		}
+		elements = append(elements, el)
+	}
+	var b bytes.Buffer
+	for _, el := range elements {
+		member := *el
+		member.Min = 0
+		restore := ge.inScope(el)
+		ge.genElementField(&b, &member)
+		restore()
+	}
+
+	isSet := make([]string, len(ge.fields))
+	for i, f := range ge.fields {
+		isSet[i] = "item." + f.Name + " != nil"
+		if strings.HasPrefix(f.Type, "[]") {
+			isSet[i] = "len(item." + f.Name + ") > 0"
+		}
+	}
+	data := &struct {
+		Name, Item, Parent, Kind string
+		Fields                   string
+		Members                  []groupMember
+	}{Name: name, Item: item, Parent: parent, Kind: g.kind, Fields: b.String()}
+	for i, f := range ge.fields {
+		// an element starts a new occurrence when it's already set,
+		// unless repeated, or when an element that comes after it in
+		// a sequence, or any other of a choice, is
+		m := groupMember{Field: f.Name, Name: elements[i].Name}
+		for j := range ge.fields {
+			switch {
+			case j == i && strings.HasPrefix(f.Type, "[]"):
+			case j == i, j > i, g.kind == "choice":
+				m.Next = append(m.Next, isSet[j])
+			}
+		}
+		data.Members = append(data.Members, m)
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	return ge.template(groupT).Execute(&ge.choiceDecls, data)
+}
@@ -0,0 +1,137 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+var httpFuncT = template.Must(template.New("httpFunc").Parse(
+	`func (p *{{.PortType}}) {{.Name}}({{.Input}}) ({{.Output}}) {
+	{{if .Unsupported}}return {{.RetDef}}
+}
+{{else}}α := url.Values{}
+	{{range .Params}}α.Set({{printf "%q" .Name}}, {{.Value}})
+	{{end}}{{if .OutputType}}{{if .OutputPtr}}γ := new({{.OutputType}})
+	{{else}}var γ {{.OutputType}}
+	{{end}}{{end}}{{if .Raw}}ρ := new(soap.Response)
+	{{end}}if err := {{if .OpLabel}}soap.Observe(p.Client, "{{.OpLabel}}", time.Now(), {{end}}p.Client.CallHTTP({{printf "%q" .Verb}}, {{printf "%q" .Location}}, α, {{.Replace}}, {{if .Raw}}soap.WithResponse({{end}}{{if not .OutputType}}nil{{else if .OutputPtr}}γ{{else}}&γ{{end}}{{if .Raw}}, ρ){{end}}){{if .OpLabel}}){{end}}; err != nil {
+		return {{.RetDef}}
+	}
+	return {{if .OutputType}}γ, {{end}}{{if .Raw}}ρ, {{end}}nil
+}
+{{end}}`))
+
+type httpFuncParam struct{ Name, Value string }
+
+// httpOperation returns the HTTP operation bound to the named operation,
+// or nil if the binding of d is not an HTTP binding.
+func (ge *goEncoder) httpOperation(d *wsdl.Definitions, name string) *wsdl.HTTPOperation {
+	if d.DefaultBinding().BindingType == nil || d.DefaultBinding().BindingType.Verb == "" {
+		return nil
+	}
+	if bo, ok := ge.soapOps[name]; ok {
+		return bo.HTTPOperation
+	}
+	return nil
+}
+
+// writeHTTPFunc writes the function of op, bound to the HTTP operation
+// hop. Its input parameters are sent as strings, URL encoded or in the
+// location, and its single output, if any, is the XML response.
+// Operations with parameters of complex types or more than one output
+// return an error.
+func (ge *goEncoder) writeHTTPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, hop *wsdl.HTTPOperation, in, out []*parameter) error {
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+
+	// outputs of the operation, but the raw response and the error
+	results := out[:len(out)-1]
+	if ge.rawResponse {
+		results = results[:len(results)-1]
+	}
+
+	unsupported := len(results) > 1
+	params := make([]httpFuncParam, 0, len(in))
+	for _, p := range in {
+		name := maskKeywordUsage(p.code)
+		value := name
+		switch {
+		case strings.HasPrefix(p.dataType, "*"), strings.HasPrefix(p.dataType, "[]"):
+			unsupported = true
+		case p.dataType != "string":
+			ge.needsStdPkg["fmt"] = true
+			value = "fmt.Sprint(" + name + ")"
+		}
+		params = append(params, httpFuncParam{p.code, value})
+	}
+
+	outputs := make([]string, len(out))
+	retDefaults := make([]string, len(out))
+	for i, p := range out {
+		outputs[i] = p.dataType
+		retDefaults[i] = ge.wsdl2goDefault(p.dataType)
+		switch {
+		case p.raw && !unsupported:
+			retDefaults[i] = "ρ"
+		case p.raw, i < len(out)-1 && strings.HasPrefix(p.dataType, "*"):
+			retDefaults[i] = "nil"
+		}
+	}
+	if unsupported {
+		ge.report(SkipEvent, operationPos(op), "operation %q only returns an error, as HTTP bindings only support simple parameters and a single output", op.Name)
+		ge.needsStdPkg["errors"] = true
+		retDefaults[len(out)-1] = fmt.Sprintf("errors.New(%q)",
+			op.Name+": only simple parameters and a single output are supported by HTTP bindings")
+	} else {
+		ge.needsStdPkg["net/url"] = true
+		retDefaults[len(out)-1] = "err"
+	}
+
+	outputType, outputPtr := "", false
+	if len(results) == 1 {
+		outputType = strings.TrimPrefix(results[0].dataType, "*")
+		outputPtr = strings.HasPrefix(results[0].dataType, "*")
+	}
+
+	opLabel := ""
+	if ge.opLabels && !unsupported {
+		opLabel = operationLabel(d, op)
+		ge.needsStdPkg["time"] = true
+	}
+
+	_, impl := ge.portTypeNames(d)
+	return ge.template(httpFuncT).Execute(w, &struct {
+		PortType    string
+		Name        string
+		Input       string
+		Output      string
+		RetDef      string
+		Unsupported bool
+		Params      []httpFuncParam
+		OutputType  string
+		OutputPtr   bool
+		Verb        string
+		Location    string
+		Replace     bool
+		OpLabel     string
+		Raw         bool
+	}{
+		impl,
+		ge.goSymbol(op.Name),
+		strings.Join(code(in), ","),
+		strings.Join(outputs, ","),
+		strings.Join(retDefaults, ","),
+		unsupported,
+		params,
+		outputType,
+		outputPtr,
+		strings.ToUpper(d.DefaultBinding().BindingType.Verb),
+		hop.Location,
+		ge.soapOps[op.Name].URLReplacement != nil,
+		opLabel,
+		ge.rawResponse,
+	})
+}
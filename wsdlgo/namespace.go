@@ -0,0 +1,250 @@
+package wsdlgo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// xsdNamespaces are the namespaces of the XML Schema built-in types,
+// which map to Go basic types.
+var xsdNamespaces = map[string]bool{
+	"http://www.w3.org/2001/XMLSchema":          true,
+	"http://www.w3.org/2000/10/XMLSchema":       true,
+	"http://www.w3.org/1999/XMLSchema":          true,
+	"http://schemas.xmlsoap.org/soap/encoding/": true,
+}
+
+// qname resolves the qualified name s to its namespace and local name,
+// through the namespace declarations of the schema of the type being
+// generated, see inScope, and else those in scope of the WSDL document
+// and its schemas. The namespace is empty when the prefix is not
+// declared.
+func (ge *goEncoder) qname(s string) xml.Name {
+	prefix, local := "", s
+	if n := strings.SplitN(s, ":", 2); len(n) == 2 {
+		prefix, local = n[0], n[1]
+	}
+	if space, ok := ge.scope[prefix]; ok {
+		return xml.Name{Space: space, Local: local}
+	}
+	return xml.Name{Space: ge.usedNamespaces[prefix], Local: local}
+}
+
+// inScope makes qname resolve through the namespace declarations of the
+// schema that declares v, a type or element, if known, as a prefix may
+// be bound to different namespaces by different schemas. It returns the
+// function that restores the previous scope.
+func (ge *goEncoder) inScope(v interface{}) func() {
+	prev := ge.scope
+	if s, ok := ge.schemaOf[v]; ok {
+		ge.scope = s.Namespaces
+	}
+	return func() { ge.scope = prev }
+}
+
+// declare records that the types and elements of s are declared by it,
+// with those of its redefinitions, see inScope.
+func (ge *goEncoder) declare(s *wsdl.Schema) {
+	for _, ct := range s.ComplexTypes {
+		ge.schemaOf[ct] = s
+	}
+	for _, st := range s.SimpleTypes {
+		ge.schemaOf[st] = s
+	}
+	for _, el := range s.Elements {
+		ge.schemaOf[el] = s
+	}
+	for _, r := range s.Redefines {
+		for _, ct := range r.ComplexTypes {
+			ge.schemaOf[ct] = s
+		}
+		for _, st := range r.SimpleTypes {
+			ge.schemaOf[st] = s
+		}
+	}
+}
+
+// declareAs records that v, a copy of the type or element orig, is
+// declared by the schema of orig, unless its schema is already known.
+func (ge *goEncoder) declareAs(v, orig interface{}) {
+	if _, known := ge.schemaOf[v]; known {
+		return
+	}
+	if s, ok := ge.schemaOf[orig]; ok {
+		ge.schemaOf[v] = s
+	}
+}
+
+// isXSDType reports whether the qualified name s refers to a type in
+// an XML Schema namespace. Names whose prefix can't be resolved are
+// assumed to be built-in when not declared in the schema.
+func (ge *goEncoder) isXSDType(s string) bool {
+	q := ge.qname(s)
+	if q.Space != "" {
+		return xsdNamespaces[q.Space]
+	}
+	_, isSimple := ge.stypes[q.Local]
+	_, isComplex := ge.ctypes[q.Local]
+	return !isSimple && !isComplex
+}
+
+// typeName resolves the qualified name s to the key of the type it
+// refers to in the types cache. Names that don't resolve to a declared
+// type are looked up by their local name.
+func (ge *goEncoder) typeName(s string) string {
+	q := ge.qname(s)
+	if name, ok := ge.typeKeys[q]; ok {
+		return name
+	}
+	return q.Local
+}
+
+// cacheTypeName returns the key of the type declared as local in the
+// namespace space for the types cache. Types keep their local names,
+// unless already taken by a type from another namespace: then they're
+// prefixed with the namespace prefix, or numbered if there's none.
+func (ge *goEncoder) cacheTypeName(space, local string) string {
+	q := xml.Name{Space: space, Local: local}
+	if name, ok := ge.typeKeys[q]; ok {
+		return name
+	}
+	name := local
+	if _, taken := ge.typeQNames[name]; taken {
+		base := local
+		if prefix := ge.namespacePrefix(space); prefix != "" {
+			base = ge.goSymbol(prefix) + ge.goSymbol(local)
+		}
+		name = base
+		for i := 2; ; i++ {
+			if _, taken := ge.typeQNames[name]; !taken {
+				break
+			}
+			name = base + strconv.Itoa(i)
+		}
+	}
+	ge.typeKeys[q] = name
+	ge.typeQNames[name] = q
+	return name
+}
+
+// namespacePrefix returns the first prefix, in alphabetical order,
+// declared for the namespace space, or "" if there's none.
+func (ge *goEncoder) namespacePrefix(space string) string {
+	var prefixes []string
+	for prefix, ns := range ge.usedNamespaces {
+		if ns == space && prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return ""
+	}
+	sort.Strings(prefixes)
+	return prefixes[0]
+}
+
+// declaredPrefix returns the first prefix, in alphabetical order,
+// declared for the namespace space by the generated Namespaces, other
+// than those the envelope declares, or "" if there's none.
+func (ge *goEncoder) declaredPrefix(space string) string {
+	if space == "" {
+		return ""
+	}
+	for _, p := range ge.namespacePrefixes() {
+		if ge.usedNamespaces[p] == space && !envelopePrefixes[p] {
+			return p
+		}
+	}
+	return ""
+}
+
+// namespacePrefixes returns the prefixes declared by the WSDL document
+// and its schemas, sorted, except for the default namespace and xml.
+func (ge *goEncoder) namespacePrefixes() []string {
+	var prefixes []string
+	for prefix := range ge.usedNamespaces {
+		if prefix != "" && prefix != "xml" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// namespacesVarName returns the name of the variable holding the
+// namespace prefixes.
+func (ge *goEncoder) namespacesVarName() string {
+	return ge.fixNameConflicts("Namespaces", "Var")
+}
+
+// writeNamespaces writes the map of the namespace prefixes declared
+// by the WSDL document and its schemas, if any, to w.
+func (ge *goEncoder) writeNamespaces(w io.Writer) {
+	prefixes := ge.namespacePrefixes()
+	if len(prefixes) == 0 {
+		return
+	}
+	name := ge.namespacesVarName()
+	fmt.Fprintf(w, "// %s are the namespaces declared by the WSDL and its schemas,\n", name)
+	fmt.Fprint(w, "// by prefix, declared in the envelopes of requests by the Namespaces\n")
+	fmt.Fprint(w, "// of a soap.Client, so the prefixes of elements resolve.\n")
+	fmt.Fprintf(w, "var %s = map[string]string{\n", name)
+	for _, prefix := range prefixes {
+		fmt.Fprintf(w, "%q: %q,\n", prefix, ge.usedNamespaces[prefix])
+	}
+	fmt.Fprint(w, "}\n\n")
+}
+
+// envelopePrefixes are the prefixes declared by soap.Envelope for the
+// fields of the soap.Client other than its Namespaces, except for tns,
+// which defaults to the one of its Namespaces.
+var envelopePrefixes = map[string]bool{
+	"SOAP-ENV": true, "ns": true, "urn": true, "xsi": true,
+}
+
+// cacheBodyPrefixes picks the prefixes of the soap:body namespaces of
+// the rpc operations of d. Namespaces without a prefix declared by the
+// WSDL document and its schemas get one, ns1, ns2 and so on, added to
+// the namespaces used, so they're declared in the envelope by the
+// generated Namespaces.
+func (ge *goEncoder) cacheBodyPrefixes(d *wsdl.Definitions) {
+	for _, bo := range d.DefaultBinding().Operations {
+		if !isRPC(d, bo) || bo.Input == nil || bo.Input.Namespace == "" {
+			continue
+		}
+		space := bo.Input.Namespace
+		if _, ok := ge.bodyPrefixes[space]; ok {
+			continue
+		}
+		prefix := ge.declaredPrefix(space)
+		if prefix == "" {
+			used := make(map[string]string, len(ge.usedNamespaces)+1)
+			for p, ns := range ge.usedNamespaces {
+				used[p] = ns
+			}
+			for i := 1; prefix == ""; i++ {
+				if p := "ns" + strconv.Itoa(i); used[p] == "" {
+					prefix = p
+				}
+			}
+			used[prefix] = space
+			ge.usedNamespaces = used
+		}
+		ge.bodyPrefixes[space] = prefix
+	}
+}
+
+// bodyPrefix returns the prefix of the soap:body namespace of the rpc
+// binding operation bo, or "" if there's none.
+func (ge *goEncoder) bodyPrefix(d *wsdl.Definitions, bo *wsdl.BindingOperation) string {
+	if bo == nil || bo.Input == nil || !isRPC(d, bo) {
+		return ""
+	}
+	return ge.bodyPrefixes[bo.Input.Namespace]
+}
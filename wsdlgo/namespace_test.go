@@ -0,0 +1,124 @@
+package wsdlgo
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+func TestQName(t *testing.T) {
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	ge.usedNamespaces = map[string]string{
+		"":    "http://schemas.xmlsoap.org/wsdl/",
+		"xsd": "http://www.w3.org/2001/XMLSchema",
+		"tns": "http://example.com/types",
+	}
+	ge.ctypes["date"] = &wsdl.ComplexType{Name: "date"}
+
+	cases := []struct {
+		QName string
+		Name  xml.Name
+		Type  string
+	}{
+		// renamed, as the schema declares a date type
+		{"xsd:date", xml.Name{Space: "http://www.w3.org/2001/XMLSchema", Local: "date"}, "DateType"},
+		{"tns:date", xml.Name{Space: "http://example.com/types", Local: "date"}, "*Date"},
+		{"date", xml.Name{Space: "http://schemas.xmlsoap.org/wsdl/", Local: "date"}, "*Date"},
+		{"xsd:string", xml.Name{Space: "http://www.w3.org/2001/XMLSchema", Local: "string"}, "string"},
+		{"foo:string", xml.Name{Local: "string"}, "string"},
+	}
+	for i, tc := range cases {
+		if have := ge.qname(tc.QName); have != tc.Name {
+			t.Errorf("test %d: qname(%q): want %v, have %v", i, tc.QName, tc.Name, have)
+		}
+		if have := ge.wsdl2goType(tc.QName); have != tc.Type {
+			t.Errorf("test %d: wsdl2goType(%q): want %q, have %q", i, tc.QName, tc.Type, have)
+		}
+	}
+}
+
+func TestCacheTypeName(t *testing.T) {
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	ge.usedNamespaces = map[string]string{
+		"tns":  "http://example.com/orders",
+		"bill": "http://example.com/billing",
+	}
+	cases := []struct {
+		Space, Local, Name string
+	}{
+		{"http://example.com/orders", "Address", "Address"},
+		{"http://example.com/billing", "Address", "BillAddress"},
+		{"http://example.com/other", "Address", "Address2"},
+		{"http://example.com/unknown", "Address", "Address3"},
+		{"http://example.com/billing", "Address", "BillAddress"},
+		{"http://example.com/billing", "Invoice", "Invoice"},
+	}
+	for i, tc := range cases {
+		if have := ge.cacheTypeName(tc.Space, tc.Local); have != tc.Name {
+			t.Errorf("test %d: cacheTypeName(%q, %q): want %q, have %q", i, tc.Space, tc.Local, tc.Name, have)
+		}
+	}
+	for qname, want := range map[string]string{
+		"tns:Address":  "Address",
+		"bill:Address": "BillAddress",
+		"foo:Address":  "Address",
+	} {
+		if have := ge.typeName(qname); have != want {
+			t.Errorf("typeName(%q): want %q, have %q", qname, want, have)
+		}
+	}
+
+	// tns is bound to the billing namespace by the schema of Invoice
+	invoice := &wsdl.ComplexType{Name: "Invoice"}
+	ge.schemaOf[invoice] = &wsdl.Schema{Namespaces: map[string]string{
+		"tns": "http://example.com/billing",
+	}}
+	restore := ge.inScope(invoice)
+	for qname, want := range map[string]string{
+		"tns:Address":  "BillAddress",
+		"bill:Address": "BillAddress",
+	} {
+		if have := ge.typeName(qname); have != want {
+			t.Errorf("typeName(%q) in scope of Invoice: want %q, have %q", qname, want, have)
+		}
+	}
+	restore()
+	if have := ge.typeName("tns:Address"); have != "Address" {
+		t.Errorf("typeName(%q) out of scope: want %q, have %q", "tns:Address", "Address", have)
+	}
+}
+
+func TestSOAPEncodingTypes(t *testing.T) {
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	ge.usedNamespaces = map[string]string{
+		"xsd":     "http://www.w3.org/2001/XMLSchema",
+		"soapenc": "http://schemas.xmlsoap.org/soap/encoding/",
+	}
+	cases := []struct {
+		QName, Type string
+	}{
+		{"soapenc:string", "string"},
+		{"soapenc:int", "int"},
+		{"soapenc:long", "int64"},
+		{"soapenc:short", "int16"},
+		{"soapenc:unsignedShort", "uint16"},
+		{"soapenc:unsignedLong", "uint64"},
+		{"soapenc:double", "float64"},
+		{"soapenc:boolean", "bool"},
+		{"soapenc:base64", "[]byte"},
+		{"soapenc:base64Binary", "[]byte"},
+		{"soapenc:dateTime", "DateTime"},
+		{"soapenc:decimal", "float64"},
+		{"soapenc:anyURI", "string"},
+		{"soapenc:Name", "string"},
+		{"soapenc:NCName", "string"},
+		{"xsd:short", "int16"},
+	}
+	for i, tc := range cases {
+		if have := ge.wsdl2goType(tc.QName); have != tc.Type {
+			t.Errorf("test %d: wsdl2goType(%q): want %q, have %q", i, tc.QName, tc.Type, have)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package wsdlgo
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Naming is how the words of generated identifiers are cased.
+type Naming string
+
+const (
+	// GoNaming writes the initialisms of identifiers in upper case, as
+	// in CustomerID and URL, following the Go conventions. It's the
+	// default.
+	GoNaming Naming = "go"
+
+	// TitleNaming writes every word of identifiers in title case, as in
+	// CustomerId and Url, keeping the names of older versions.
+	TitleNaming Naming = "title"
+)
+
+// DefaultInitialisms are the initialisms of generated identifiers
+// written in upper case by GoNaming, those of golint.
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML",
+	"HTTP", "HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS",
+	"RPC", "SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI",
+	"UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF",
+	"XSS",
+}
+
+// goInitialisms returns the identifier s, in title case, with its
+// initialisms in upper case, unless the names of TitleNaming or of
+// compat v1 are kept.
+func (ge *goEncoder) goInitialisms(s string) string {
+	if ge.naming == TitleNaming || (ge.naming == "" && ge.compat == "v1") {
+		return s
+	}
+	if ge.initialisms == nil {
+		ge.SetInitialisms(DefaultInitialisms)
+	}
+	var name strings.Builder
+	for _, w := range identWords(s) {
+		if u := strings.ToUpper(w); ge.initialisms[u] {
+			w = u
+		} else if l := strings.TrimRight(w, "0123456789"); l != w && ge.initialisms[strings.ToUpper(l)] {
+			// with a number, as in Id2
+			w = strings.ToUpper(l) + w[len(l):]
+		}
+		name.WriteString(w)
+	}
+	return name.String()
+}
+
+// identWords splits the identifier s into its words: at changes from
+// lower case or digits to upper case, before the last letter of a run
+// of upper case letters followed by lower case ones, as in XMLHttp,
+// and around underscores.
+func identWords(s string) []string {
+	var words []string
+	r := []rune(s)
+	start := 0
+	for i := 1; i < len(r); i++ {
+		switch {
+		case r[i] == '_' || r[i-1] == '_':
+		case unicode.IsUpper(r[i]) && !unicode.IsUpper(r[i-1]):
+		case unicode.IsUpper(r[i]) && i+1 < len(r) && unicode.IsLower(r[i+1]):
+		default:
+			continue
+		}
+		words = append(words, string(r[start:i]))
+		start = i
+	}
+	return append(words, string(r[start:]))
+}
+
+// transliterations are the ASCII spellings of the lower case letters of
+// the Latin, Greek and Cyrillic scripts, for identifiers.
+var transliterations = func() map[rune]string {
+	m := map[rune]string{
+		'æ': "ae", 'œ': "oe", 'ß': "ss", 'þ': "th", 'ĳ': "ij",
+		'θ': "th", 'χ': "ch", 'ψ': "ps",
+		'ж': "zh", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+		'ъ': "", 'ь': "", 'ю': "yu", 'я': "ya", 'ё': "yo", 'є': "ye",
+		'ї': "yi",
+	}
+	for ascii, letters := range map[string]string{
+		"a": "àáâãäåāăąαάабә",
+		"b": "βб",
+		"c": "çćĉċč",
+		"d": "ďđðδд",
+		"e": "èéêëēĕėęěεέеэ",
+		"f": "φф",
+		"g": "ĝğġģγгґ",
+		"h": "ĥħ",
+		"i": "ìíîïĩīĭįıηήιίϊΐиі",
+		"j": "ĵ",
+		"k": "ķκк",
+		"l": "ĺļľŀłλл",
+		"m": "μм",
+		"n": "ñńņňŉνн",
+		"o": "òóôõöøōŏőοόωώо",
+		"p": "πп",
+		"r": "ŕŗřρр",
+		"s": "śŝşšσςс",
+		"t": "ţťŧτт",
+		"u": "ùúûüũūŭůűųу",
+		"v": "в",
+		"w": "ŵ",
+		"x": "ξ",
+		"y": "ýÿŷυύϋΰйы",
+		"z": "źżžζз",
+	} {
+		for _, r := range letters {
+			m[r] = ascii
+		}
+	}
+	return m
+}()
+
+// transliterate returns s with the letters of transliterations spelled
+// in ASCII, keeping their case, as in Ñandú to Nandu or Имя to Imya.
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		t, ok := transliterations[unicode.ToLower(r)]
+		switch {
+		case !ok:
+			b.WriteRune(r)
+		case unicode.IsUpper(r) && t != "":
+			b.WriteString(strings.ToUpper(t[:1]) + t[1:])
+		default:
+			b.WriteString(t)
+		}
+	}
+	return b.String()
+}
+
+// exportedName returns name prefixed with X if it starts with a letter
+// without upper case, as those of Chinese or Japanese, to be exported.
+func exportedName(name string) string {
+	r, _ := utf8.DecodeRuneInString(name)
+	if unicode.IsLetter(r) && !unicode.IsUpper(r) {
+		return "X" + name
+	}
+	return name
+}
@@ -0,0 +1,422 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// EncodeOpenAPI writes an OpenAPI 3 document in YAML to w, describing
+// the operations of d as POST endpoints that take and return their
+// messages, and the schema types of d as components.
+//
+// Imported schemas are only described after d has been processed by
+// an Encoder, which merges them into d.
+func EncodeOpenAPI(w io.Writer, d *wsdl.Definitions) error {
+	oa := &openAPIEncoder{
+		d:        d,
+		names:    make(map[string]bool),
+		elements: make(map[string]string),
+		messages: make(map[string]string),
+	}
+	var b bytes.Buffer
+	writeYAML(&b, oa.document(), 0)
+	_, err := io.Copy(w, &b)
+	return err
+}
+
+type openAPIEncoder struct {
+	d *wsdl.Definitions
+
+	// component names in use
+	names map[string]bool
+
+	// component names of elements and messages
+	elements map[string]string
+	messages map[string]string
+}
+
+// yamlMap is a YAML mapping that keeps its keys in order.
+type yamlMap []yamlItem
+
+type yamlItem struct {
+	Key   string
+	Value interface{} // string, bool, yamlMap or []interface{}
+}
+
+func (oa *openAPIEncoder) document() yamlMap {
+	d := oa.d
+	title := d.Name
+	if title == "" {
+		title = d.DefaultBinding().Name
+	}
+	info := yamlMap{{"title", title}, {"version", "1.0.0"}}
+	if doc := strings.TrimSpace(d.DefaultService().Doc.String()); doc != "" {
+		info = append(info, yamlItem{"description", doc})
+	}
+	doc := yamlMap{{"openapi", "3.0.0"}, {"info", info}}
+	servers := []interface{}{}
+	for _, p := range d.DefaultService().Ports {
+		if p.Address.Location != "" {
+			servers = append(servers, yamlMap{{"url", p.Address.Location}})
+		}
+	}
+	if len(servers) > 0 {
+		doc = append(doc, yamlItem{"servers", servers})
+	}
+	schemas := oa.schemas()
+	doc = append(doc, yamlItem{"paths", oa.paths()})
+	doc = append(doc, yamlItem{"components", yamlMap{{"schemas", schemas}}})
+	return doc
+}
+
+func (oa *openAPIEncoder) paths() yamlMap {
+	actions := make(map[string]string)
+	for _, bo := range oa.d.DefaultBinding().Operations {
+		actions[bo.Name] = bo.Operation11.Action
+		if bo.Operation.Action != "" {
+			actions[bo.Name] = bo.Operation.Action
+		}
+	}
+	paths := yamlMap{}
+	for _, op := range oa.d.DefaultPortType().Operations {
+		post := yamlMap{{"operationId", op.Name}}
+		if doc := strings.TrimSpace(op.Doc.String()); doc != "" {
+			post = append(post, yamlItem{"summary", doc})
+		}
+		if action := actions[op.Name]; action != "" {
+			post = append(post, yamlItem{"x-soap-action", action})
+		}
+		if op.Input != nil {
+			post = append(post, yamlItem{"requestBody", yamlMap{
+				{"required", true},
+				{"content", oa.content(op.Input.Message)},
+			}})
+		}
+		ok := yamlMap{{"description", "Successful response"}}
+		if op.Output != nil {
+			ok = append(ok, yamlItem{"content", oa.content(op.Output.Message)})
+		}
+		post = append(post, yamlItem{"responses", yamlMap{{"200", ok}}})
+		paths = append(paths, yamlItem{"/" + op.Name, yamlMap{{"post", post}}})
+	}
+	return paths
+}
+
+func (oa *openAPIEncoder) content(message string) yamlMap {
+	schema := yamlMap{}
+	if name, ok := oa.messages[trimns(message)]; ok {
+		schema = schemaRef(name)
+	}
+	return yamlMap{{"application/json", yamlMap{{"schema", schema}}}}
+}
+
+// schemas returns the component schemas of all types, elements and
+// messages. Elements and messages named after a type are renamed.
+func (oa *openAPIEncoder) schemas() yamlMap {
+	d := oa.d
+	for _, st := range d.Schema.SimpleTypes {
+		oa.names[st.Name] = true
+	}
+	for _, ct := range d.Schema.ComplexTypes {
+		oa.names[ct.Name] = true
+	}
+	for _, el := range d.Schema.Elements {
+		if el.Type != "" && trimns(el.Type) == el.Name && oa.names[el.Name] {
+			oa.elements[el.Name] = el.Name
+			continue
+		}
+		oa.elements[el.Name] = oa.newName(el.Name, "Element")
+	}
+	for _, m := range d.Messages {
+		oa.messages[m.Name] = oa.newName(m.Name, "Message")
+	}
+
+	schemas := yamlMap{}
+	for _, st := range d.Schema.SimpleTypes {
+		schemas = append(schemas, yamlItem{st.Name, oa.simpleSchema(st)})
+	}
+	for _, ct := range d.Schema.ComplexTypes {
+		schemas = append(schemas, yamlItem{ct.Name, oa.complexSchema(ct)})
+	}
+	for _, el := range d.Schema.Elements {
+		// Elements that keep the name of their type are described by it.
+		if name := oa.elements[el.Name]; name != el.Name || !oa.isType(el.Name) {
+			schemas = append(schemas, yamlItem{name, oa.elementSchema(el)})
+		}
+	}
+	for _, m := range d.Messages {
+		schemas = append(schemas, yamlItem{oa.messages[m.Name], oa.messageSchema(m)})
+	}
+	return schemas
+}
+
+func (oa *openAPIEncoder) newName(name, suffix string) string {
+	if oa.names[name] {
+		name += suffix
+	}
+	for oa.names[name] {
+		name += "_"
+	}
+	oa.names[name] = true
+	return name
+}
+
+func (oa *openAPIEncoder) isType(name string) bool {
+	for _, st := range oa.d.Schema.SimpleTypes {
+		if st.Name == name {
+			return true
+		}
+	}
+	for _, ct := range oa.d.Schema.ComplexTypes {
+		if ct.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (oa *openAPIEncoder) simpleSchema(st *wsdl.SimpleType) yamlMap {
+	switch {
+	case st.Restriction != nil:
+		schema := oa.typeSchema(st.Restriction.Base)
+		if len(st.Restriction.Enum) > 0 {
+			enum := make([]interface{}, len(st.Restriction.Enum))
+			for i, e := range st.Restriction.Enum {
+				enum[i] = e.Value
+			}
+			schema = append(schema, yamlItem{"enum", enum})
+		}
+		return schema
+	case st.Union != nil:
+		var anyOf []interface{}
+		for _, t := range strings.Fields(st.Union.MemberTypes) {
+			anyOf = append(anyOf, oa.typeSchema(t))
+		}
+		if len(anyOf) > 0 {
+			return yamlMap{{"anyOf", anyOf}}
+		}
+	}
+	return yamlMap{{"type", "string"}}
+}
+
+func (oa *openAPIEncoder) complexSchema(ct *wsdl.ComplexType) yamlMap {
+	props := yamlMap{}
+	addElements := func(elements []*wsdl.Element) {
+		for _, el := range elements {
+			name := el.Name
+			if name == "" {
+				name = trimns(el.Ref)
+			}
+			props = append(props, yamlItem{name, oa.elementSchema(el)})
+		}
+	}
+	addSequence := func(seq *wsdl.Sequence) {
+		if seq == nil {
+			return
+		}
+		addElements(seq.Elements)
+		for _, choice := range seq.Choices {
+			addElements(choice.Elements)
+		}
+	}
+	addAttributes := func(attrs []*wsdl.Attribute) {
+		for _, attr := range attrs {
+			if attr.Name != "" {
+				props = append(props, yamlItem{attr.Name, oa.typeSchema(attr.Type)})
+			}
+		}
+	}
+	addElements(ct.AllElements)
+	addSequence(ct.Sequence)
+	if ct.Choice != nil {
+		addElements(ct.Choice.Elements)
+	}
+	addAttributes(ct.Attributes)
+
+	var base yamlMap
+	if cc := ct.ComplexContent; cc != nil && cc.Extension != nil {
+		base = oa.typeSchema(cc.Extension.Base)
+		addSequence(cc.Extension.Sequence)
+		if cc.Extension.Choice != nil {
+			addElements(cc.Extension.Choice.Elements)
+		}
+		addAttributes(cc.Extension.Attributes)
+	}
+	if sc := ct.SimpleContent; sc != nil && sc.Extension != nil {
+		props = append(yamlMap{{"value", oa.typeSchema(sc.Extension.Base)}}, props...)
+		addAttributes(sc.Extension.Attributes)
+	}
+
+	schema := yamlMap{{"type", "object"}}
+	if doc := strings.TrimSpace(ct.Doc.String()); doc != "" {
+		schema = append(schema, yamlItem{"description", doc})
+	}
+	if len(props) > 0 {
+		schema = append(schema, yamlItem{"properties", props})
+	}
+	if base != nil {
+		return yamlMap{{"allOf", []interface{}{base, schema}}}
+	}
+	return schema
+}
+
+func (oa *openAPIEncoder) elementSchema(el *wsdl.Element) yamlMap {
+	var schema yamlMap
+	switch {
+	case el.Ref != "":
+		schema = oa.elementRef(el.Ref)
+	case el.Type != "":
+		schema = oa.typeSchema(el.Type)
+	case el.ComplexType != nil:
+		schema = oa.complexSchema(el.ComplexType)
+	default:
+		schema = yamlMap{}
+	}
+	if el.Nillable {
+		schema = append(schema, yamlItem{"nullable", true})
+	}
+	if n, _ := strconv.Atoi(el.Max); el.Max == "unbounded" || n > 1 {
+		schema = yamlMap{{"type", "array"}, {"items", schema}}
+	}
+	return schema
+}
+
+func (oa *openAPIEncoder) messageSchema(m *wsdl.Message) yamlMap {
+	props := yamlMap{}
+	for _, part := range m.Parts {
+		var schema yamlMap
+		switch {
+		case part.Element != "":
+			schema = oa.elementRef(part.Element)
+		default:
+			schema = oa.typeSchema(part.Type)
+		}
+		props = append(props, yamlItem{part.Name, schema})
+	}
+	schema := yamlMap{{"type", "object"}}
+	if len(props) > 0 {
+		schema = append(schema, yamlItem{"properties", props})
+	}
+	return schema
+}
+
+func (oa *openAPIEncoder) elementRef(name string) yamlMap {
+	if ref, ok := oa.elements[trimns(name)]; ok {
+		return schemaRef(ref)
+	}
+	return yamlMap{}
+}
+
+// typeSchema returns the schema of the named type, which is either a
+// reference to a schema component or an OpenAPI data type.
+func (oa *openAPIEncoder) typeSchema(t string) yamlMap {
+	prefix, local := "", t
+	if n := strings.SplitN(t, ":", 2); len(n) == 2 {
+		prefix, local = n[0], n[1]
+	}
+	if ns, declared := oa.d.Namespaces[prefix]; !declared || !xsdNamespaces[ns] {
+		if oa.isType(local) {
+			return schemaRef(local)
+		}
+		// Elements with anonymous types are also used as types.
+		if name, ok := oa.elements[local]; ok {
+			return schemaRef(name)
+		}
+	}
+	switch local {
+	case "boolean":
+		return yamlMap{{"type", "boolean"}}
+	case "int", "short", "byte", "unsignedShort", "unsignedByte":
+		return yamlMap{{"type", "integer"}, {"format", "int32"}}
+	case "long", "unsignedInt", "unsignedLong":
+		return yamlMap{{"type", "integer"}, {"format", "int64"}}
+	case "integer", "positiveInteger", "negativeInteger",
+		"nonNegativeInteger", "nonPositiveInteger":
+		return yamlMap{{"type", "integer"}}
+	case "float":
+		return yamlMap{{"type", "number"}, {"format", "float"}}
+	case "double":
+		return yamlMap{{"type", "number"}, {"format", "double"}}
+	case "decimal":
+		return yamlMap{{"type", "number"}}
+	case "date":
+		return yamlMap{{"type", "string"}, {"format", "date"}}
+	case "dateTime":
+		return yamlMap{{"type", "string"}, {"format", "date-time"}}
+	case "base64Binary", "base64":
+		return yamlMap{{"type", "string"}, {"format", "byte"}}
+	case "hexBinary":
+		return yamlMap{{"type", "string"}, {"format", "binary"}}
+	case "anyType", "":
+		return yamlMap{}
+	default:
+		return yamlMap{{"type", "string"}}
+	}
+}
+
+func schemaRef(name string) yamlMap {
+	return yamlMap{{"$ref", "#/components/schemas/" + name}}
+}
+
+var (
+	yamlPlainKey   = regexp.MustCompile(`^[A-Za-z_$/][A-Za-z0-9_$/.-]*$`)
+	yamlNumericKey = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// writeYAML writes v to b in block style, at the given indentation.
+// Keys are quoted when needed, and string values always are.
+func writeYAML(b *bytes.Buffer, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case yamlMap:
+		for _, item := range v {
+			key := item.Key
+			if !yamlPlainKey.MatchString(key) || yamlNumericKey.MatchString(key) {
+				key = strconv.Quote(key)
+			}
+			b.WriteString(pad + key + ":")
+			writeYAMLValue(b, item.Value, indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(yamlMap); ok && len(m) > 0 {
+				// Mappings start on the line of their list item.
+				var mb bytes.Buffer
+				writeYAML(&mb, m, indent+2)
+				b.WriteString(pad + "- ")
+				b.Write(mb.Bytes()[indent+2:])
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLValue(b, item, indent)
+		}
+	}
+}
+
+func writeYAMLValue(b *bytes.Buffer, v interface{}, indent int) {
+	switch v := v.(type) {
+	case yamlMap:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, v, indent+2)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAML(b, v, indent+2)
+	case bool:
+		b.WriteString(" " + strconv.FormatBool(v) + "\n")
+	case string:
+		b.WriteString(" " + strconv.Quote(v) + "\n")
+	}
+}
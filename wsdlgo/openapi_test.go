@@ -0,0 +1,37 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var OpenAPICases = []struct {
+	F string
+	G string
+}{
+	{F: "w3example2.wsdl", G: "w3example2.openapi.golden"},
+	{F: "conflicts.wsdl", G: "conflicts.openapi.golden"},
+	{F: "memcache.wsdl", G: "memcache.openapi.golden"},
+}
+
+func TestEncodeOpenAPI(t *testing.T) {
+	for i, tc := range OpenAPICases {
+		d := LoadDefinition(t, tc.F, nil)
+		var have bytes.Buffer
+		if err := EncodeOpenAPI(&have, d); err != nil {
+			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
+			continue
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", tc.G))
+		if err != nil {
+			t.Errorf("test %d: missing golden file %q: %v", i, tc.G, err)
+		}
+		if !bytes.Equal(have.Bytes(), want) {
+			err := Diff("_diff", "yaml", want, have.Bytes())
+			t.Errorf("test %d, %q != %q: %v\ngenerated:\n%s\n",
+				i, tc.F, tc.G, err, have.Bytes())
+		}
+	}
+}
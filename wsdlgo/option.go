@@ -0,0 +1,166 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+)
+
+// An Option configures an Encoder created with NewEncoder. Options
+// are the same as the Encoder setters; an error from an option, such
+// as an unsupported compat version, is returned by Encode.
+type Option func(Encoder) error
+
+// WithPackageName sets the name of the generated package.
+func WithPackageName(name fmt.Stringer) Option {
+	return func(e Encoder) error { e.SetPackageName(name); return nil }
+}
+
+// WithClient sets the http client used to download imported documents.
+func WithClient(c *http.Client) Option {
+	return func(e Encoder) error { e.SetClient(c); return nil }
+}
+
+// WithLocalNamespace sets the namespace used in XMLName.
+func WithLocalNamespace(namespace string) Option {
+	return func(e Encoder) error { e.SetLocalNamespace(namespace); return nil }
+}
+
+// WithASTHook sets the function that post-processes the generated code.
+func WithASTHook(hook ASTHook) Option {
+	return func(e Encoder) error { e.SetASTHook(hook); return nil }
+}
+
+// WithTestWriter sets where to write tests for the generated code.
+func WithTestWriter(w io.Writer) Option {
+	return func(e Encoder) error { e.SetTestWriter(w); return nil }
+}
+
+// WithEnumWriter sets where to write the tables of large enumerations.
+func WithEnumWriter(w io.Writer) Option {
+	return func(e Encoder) error { e.SetEnumWriter(w); return nil }
+}
+
+// WithDocLang sets the language of the documentation comments.
+func WithDocLang(lang string) Option {
+	return func(e Encoder) error { e.SetDocLang(lang); return nil }
+}
+
+// WithCompat sets the version of generated type names to keep.
+func WithCompat(version string) Option {
+	return func(e Encoder) error { return e.SetCompat(version) }
+}
+
+// WithNaming sets how the words of generated identifiers are cased.
+func WithNaming(n Naming) Option {
+	return func(e Encoder) error { return e.SetNaming(n) }
+}
+
+// WithInitialisms sets the initialisms written in upper case.
+func WithInitialisms(words []string) Option {
+	return func(e Encoder) error { e.SetInitialisms(words); return nil }
+}
+
+// WithBaseURL sets the location of the WSDL document.
+func WithBaseURL(loc string) Option {
+	return func(e Encoder) error { e.SetBaseURL(loc); return nil }
+}
+
+// WithCatalog sets the catalog of local files of imported documents.
+func WithCatalog(c Catalog) Option {
+	return func(e Encoder) error { e.SetCatalog(c); return nil }
+}
+
+// WithTemplate replaces the code template name.
+func WithTemplate(name string, t *template.Template) Option {
+	return func(e Encoder) error { return e.SetTemplate(name, t) }
+}
+
+// WithCache sets where to keep downloaded documents.
+func WithCache(c *Cache) Option {
+	return func(e Encoder) error { e.SetCache(c); return nil }
+}
+
+// WithOperationLabels sets whether operations call the Observe hook.
+func WithOperationLabels(enabled bool) Option {
+	return func(e Encoder) error { e.SetOperationLabels(enabled); return nil }
+}
+
+// WithMinimal sets whether to generate code without reflection.
+func WithMinimal(enabled bool) Option {
+	return func(e Encoder) error { e.SetMinimal(enabled); return nil }
+}
+
+// WithGetters sets whether to generate getters of pointer fields.
+func WithGetters(enabled bool) Option {
+	return func(e Encoder) error { e.SetGetters(enabled); return nil }
+}
+
+// WithEqualCopy sets whether to generate Equal and DeepCopy methods of
+// structs.
+func WithEqualCopy(enabled bool) Option {
+	return func(e Encoder) error { e.SetEqualCopy(enabled); return nil }
+}
+
+// WithConstructors sets whether to generate constructors of structs.
+func WithConstructors(enabled bool) Option {
+	return func(e Encoder) error { e.SetConstructors(enabled); return nil }
+}
+
+// WithFieldTags sets the struct tags added to the fields of structs.
+func WithFieldTags(t FieldTags) Option {
+	return func(e Encoder) error { e.SetFieldTags(t); return nil }
+}
+
+// WithOmitEmpty sets whether optional fields are tagged with omitempty.
+func WithOmitEmpty(enabled bool) Option {
+	return func(e Encoder) error { e.SetOmitEmpty(enabled); return nil }
+}
+
+// WithStructOmitEmpty overrides WithOmitEmpty for the fields of a struct.
+func WithStructOmitEmpty(name string, enabled bool) Option {
+	return func(e Encoder) error { e.SetStructOmitEmpty(name, enabled); return nil }
+}
+
+// WithValueScalars sets whether optional fields of simple types are values.
+func WithValueScalars(enabled bool) Option {
+	return func(e Encoder) error { e.SetValueScalars(enabled); return nil }
+}
+
+// WithChoiceStructs sets whether to generate choices as structs.
+func WithChoiceStructs(enabled bool) Option {
+	return func(e Encoder) error { e.SetChoiceStructs(enabled); return nil }
+}
+
+// WithResponseWrapper sets whether operations return their output wrapper.
+func WithResponseWrapper(enabled bool) Option {
+	return func(e Encoder) error { e.SetResponseWrapper(enabled); return nil }
+}
+
+// WithRawResponse sets whether operations return their raw response.
+func WithRawResponse(enabled bool) Option {
+	return func(e Encoder) error { e.SetRawResponse(enabled); return nil }
+}
+
+// WithUnwrap sets whether operations return the single element of their
+// response.
+func WithUnwrap(enabled bool) Option {
+	return func(e Encoder) error { e.SetUnwrap(enabled); return nil }
+}
+
+// WithStrict sets whether Encode fails on types that can't be found.
+func WithStrict(enabled bool) Option {
+	return func(e Encoder) error { e.SetStrict(enabled); return nil }
+}
+
+// WithLenient sets whether Encode skips imports that can't be fetched,
+// and generates placeholders for the types that can't be found.
+func WithLenient(enabled bool) Option {
+	return func(e Encoder) error { e.SetLenient(enabled); return nil }
+}
+
+// WithReporter sets the function called with the events of Encode.
+func WithReporter(r func(Event)) Option {
+	return func(e Encoder) error { e.SetReporter(r); return nil }
+}
@@ -0,0 +1,117 @@
+package wsdlgo
+
+import (
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// redefineTypes replaces the types of d overridden by xsd:redefine with
+// their redefinitions, once the schemas they redefine are merged into d.
+// Redefinitions of types that weren't found are added as they are.
+func (ge *goEncoder) redefineTypes(d *wsdl.Definitions) {
+	for _, r := range ge.redefines {
+		for _, re := range r.ComplexTypes {
+			found := false
+			for i, ct := range d.Schema.ComplexTypes {
+				if ct.Name == re.Name && ct.TargetNamespace == re.TargetNamespace {
+					d.Schema.ComplexTypes[i] = redefineComplexType(ct, re)
+					ge.declareAs(d.Schema.ComplexTypes[i], ct)
+					found = true
+				}
+			}
+			if !found {
+				d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, re)
+			}
+		}
+		for _, re := range r.SimpleTypes {
+			found := false
+			for i, st := range d.Schema.SimpleTypes {
+				if st.Name == re.Name && st.TargetNamespace == re.TargetNamespace {
+					d.Schema.SimpleTypes[i] = redefineSimpleType(st, re)
+					ge.declareAs(d.Schema.SimpleTypes[i], st)
+					found = true
+				}
+			}
+			if !found {
+				d.Schema.SimpleTypes = append(d.Schema.SimpleTypes, re)
+			}
+		}
+	}
+}
+
+// redefineComplexType returns the type orig redefined by re. Extensions
+// of orig add their elements and attributes to those of orig. Since the
+// content of complex restrictions isn't read, restrictions of orig keep
+// it as it is.
+func redefineComplexType(orig, re *wsdl.ComplexType) *wsdl.ComplexType {
+	cc := re.ComplexContent
+	switch {
+	case cc != nil && cc.Extension != nil && trimns(cc.Extension.Base) == re.Name:
+		ct := *orig
+		ext := cc.Extension
+		if ext.Sequence != nil || ext.Choice != nil {
+			var seq wsdl.Sequence
+			if ct.Sequence != nil {
+				seq = *ct.Sequence
+			}
+			seq.Choices = append([]*wsdl.Choice(nil), seq.Choices...)
+			if ext.Sequence != nil {
+				seq.Elements = append(append([]*wsdl.Element(nil), seq.Elements...), ext.Sequence.Elements...)
+				seq.ComplexTypes = append(append([]*wsdl.ComplexType(nil), seq.ComplexTypes...), ext.Sequence.ComplexTypes...)
+				seq.Any = append(append([]*wsdl.AnyElement(nil), seq.Any...), ext.Sequence.Any...)
+				seq.Choices = append(seq.Choices, ext.Sequence.Choices...)
+			}
+			if ext.Choice != nil {
+				seq.Choices = append(seq.Choices, ext.Choice)
+			}
+			ct.Sequence = &seq
+		}
+		ct.Attributes = append(append([]*wsdl.Attribute(nil), ct.Attributes...), ext.Attributes...)
+		if len(re.Doc) > 0 {
+			ct.Doc = re.Doc
+		}
+		return &ct
+	case cc != nil && cc.Restriction != nil && trimns(cc.Restriction.Base) == re.Name:
+		return orig
+	}
+	return re
+}
+
+// redefineSimpleType returns the type orig redefined by re. Restrictions
+// of orig are restrictions of its base, with the facets of re, or else
+// those of orig.
+func redefineSimpleType(orig, re *wsdl.SimpleType) *wsdl.SimpleType {
+	if re.Restriction == nil || trimns(re.Restriction.Base) != re.Name {
+		return re
+	}
+	if orig.Restriction == nil {
+		return orig
+	}
+	st := *re
+	r := *re.Restriction
+	r.Base = orig.Restriction.Base
+	o := orig.Restriction
+	if len(r.Enum) == 0 {
+		r.Enum = o.Enum
+	}
+	if len(r.Patterns) == 0 {
+		r.Patterns = o.Patterns
+	}
+	for _, f := range []struct{ re, orig **wsdl.Facet }{
+		{&r.Length, &o.Length},
+		{&r.MinLength, &o.MinLength},
+		{&r.MaxLength, &o.MaxLength},
+		{&r.WhiteSpace, &o.WhiteSpace},
+		{&r.TotalDigits, &o.TotalDigits},
+		{&r.FractionDigits, &o.FractionDigits},
+		{&r.MinInclusive, &o.MinInclusive},
+		{&r.MaxInclusive, &o.MaxInclusive},
+		{&r.MinExclusive, &o.MinExclusive},
+		{&r.MaxExclusive, &o.MaxExclusive},
+	} {
+		if *f.re == nil {
+			*f.re = *f.orig
+		}
+	}
+	st.Restriction = &r
+	return &st
+}
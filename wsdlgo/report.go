@@ -0,0 +1,85 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// An Event is something noteworthy that happens while generating code,
+// such as a document imported, or an operation or construct of the WSDL
+// that isn't generated. Events are reported to the function set with
+// SetReporter.
+type Event struct {
+	Kind    EventKind
+	Message string
+	Pos     wsdl.Pos // of the element in the WSDL or its schemas, if known
+}
+
+// String returns the event as pos: kind: message, e.g.
+// orders.xsd:12:3: skip: restriction of simpleContent of "Price" is not
+// supported.
+func (e Event) String() string {
+	s := e.Kind.String() + ": " + e.Message
+	if e.Pos.IsValid() {
+		s = e.Pos.String() + ": " + s
+	}
+	return s
+}
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	// ImportEvent is a document imported by the WSDL or its schemas,
+	// downloaded or read from a local file.
+	ImportEvent EventKind = iota
+
+	// SkipEvent is an operation or construct of the WSDL that isn't
+	// generated, or is generated partially.
+	SkipEvent
+
+	// WarningEvent is a problem that doesn't stop generation, such as
+	// an import skipped by SetLenient. Without a reporter, warnings are
+	// logged with the standard logger.
+	WarningEvent
+)
+
+// String returns the name of the kind, e.g. "warning".
+func (k EventKind) String() string {
+	switch k {
+	case ImportEvent:
+		return "import"
+	case SkipEvent:
+		return "skip"
+	case WarningEvent:
+		return "warning"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// operationPos returns the position of the input or output of op, if
+// known.
+func operationPos(op *wsdl.Operation) wsdl.Pos {
+	switch {
+	case op.Input != nil:
+		return op.Input.Pos
+	case op.Output != nil:
+		return op.Output.Pos
+	}
+	return wsdl.Pos{}
+}
+
+// report reports the event of kind at pos, with the message of format
+// and args, to the reporter, or logs it if it's a warning and there's
+// no reporter.
+func (ge *goEncoder) report(kind EventKind, pos wsdl.Pos, format string, args ...interface{}) {
+	e := Event{Kind: kind, Message: fmt.Sprintf(format, args...), Pos: pos}
+	switch {
+	case ge.reporter != nil:
+		ge.reporter(e)
+	case kind == WarningEvent:
+		log.Print(e)
+	}
+}
@@ -0,0 +1,317 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// Sample is a sample SOAP envelope of an operation's request or
+// response, with placeholder values derived from the schema.
+type Sample struct {
+	Name string // File name, such as EchoRequest.xml
+	Data []byte
+}
+
+const (
+	soap11Envelope = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Envelope = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// EncodeSamples generates the sample request and response envelopes of
+// each operation of d, in the order of the WSDL document.
+//
+// Imported schemas are only used after d has been processed by an
+// Encoder, which merges them into d.
+func EncodeSamples(d *wsdl.Definitions) []Sample {
+	se := &sampleEncoder{
+		d:        d,
+		stypes:   make(map[string]*wsdl.SimpleType),
+		ctypes:   make(map[string]*wsdl.ComplexType),
+		elements: make(map[string]*wsdl.Element),
+		messages: make(map[string]*wsdl.Message),
+		seen:     make(map[*wsdl.ComplexType]bool),
+	}
+	for _, st := range d.Schema.SimpleTypes {
+		se.stypes[st.Name] = st
+	}
+	for _, ct := range d.Schema.ComplexTypes {
+		se.ctypes[ct.Name] = ct
+	}
+	for _, el := range d.Schema.Elements {
+		se.elements[el.Name] = el
+	}
+	for _, m := range d.Messages {
+		se.messages[m.Name] = m
+	}
+	soap12 := make(map[string]bool)
+	rpc := make(map[string]bool)
+	inNS, outNS := make(map[string]string), make(map[string]string)
+	for _, bo := range d.DefaultBinding().Operations {
+		soap12[bo.Name] = bo.Operation.Action != ""
+		rpc[bo.Name] = isRPC(d, bo)
+		if bo.Input != nil {
+			inNS[bo.Name] = bo.Input.Namespace
+		}
+		if bo.Output != nil {
+			outNS[bo.Name] = bo.Output.Namespace
+		}
+	}
+	var samples []Sample
+	for _, op := range d.DefaultPortType().Operations {
+		if op.Input != nil {
+			samples = append(samples, Sample{
+				Name: op.Name + "Request.xml",
+				Data: se.envelope(op.Input.Message, op.Name, inNS[op.Name], rpc[op.Name], soap12[op.Name]),
+			})
+		}
+		if op.Output != nil {
+			samples = append(samples, Sample{
+				Name: op.Name + "Response.xml",
+				Data: se.envelope(op.Output.Message, op.Name+"Response", outNS[op.Name], rpc[op.Name], soap12[op.Name]),
+			})
+		}
+	}
+	return samples
+}
+
+type sampleEncoder struct {
+	d        *wsdl.Definitions
+	stypes   map[string]*wsdl.SimpleType
+	ctypes   map[string]*wsdl.ComplexType
+	elements map[string]*wsdl.Element
+	messages map[string]*wsdl.Message
+
+	// complex types being written, to stop at recursive types
+	seen map[*wsdl.ComplexType]bool
+
+	b bytes.Buffer
+}
+
+// envelope returns the sample envelope of the named message. The parts
+// of rpc messages are wrapped in an element named after the operation,
+// in the namespace space of their soap:body, if any.
+func (se *sampleEncoder) envelope(message, wrapper, space string, rpc, soap12 bool) []byte {
+	se.b.Reset()
+	ns := soap11Envelope
+	if soap12 {
+		ns = soap12Envelope
+	}
+	fmt.Fprintf(&se.b, "<soapenv:Envelope xmlns:soapenv=%q xmlns:tns=%q>\n", ns, se.d.TargetNamespace)
+	se.b.WriteString("  <soapenv:Header/>\n")
+	se.b.WriteString("  <soapenv:Body>\n")
+	indent := 4
+	name := "tns:" + wrapper
+	if rpc {
+		if space != "" && space != se.d.TargetNamespace {
+			name = "ns1:" + wrapper
+			se.b.WriteString(strings.Repeat(" ", indent) + "<" + name + ` xmlns:ns1="` + space + `">`)
+		} else {
+			se.start(name, nil, indent)
+		}
+		se.b.WriteString("\n")
+		indent += 2
+	}
+	if m, ok := se.messages[trimns(message)]; ok {
+		for _, part := range m.Parts {
+			switch {
+			case part.Element != "":
+				se.element(&wsdl.Element{Ref: part.Element}, indent)
+			default:
+				se.element(&wsdl.Element{Name: part.Name, Type: part.Type}, indent)
+			}
+		}
+	}
+	if rpc {
+		se.end(name, indent-2)
+	}
+	se.b.WriteString("  </soapenv:Body>\n")
+	se.b.WriteString("</soapenv:Envelope>\n")
+	return append([]byte(nil), se.b.Bytes()...)
+}
+
+// element writes a sample of el. Global elements, referenced by parts
+// or other elements, are qualified with the tns prefix.
+func (se *sampleEncoder) element(el *wsdl.Element, indent int) {
+	name := el.Name
+	if el.Ref != "" {
+		name = "tns:" + trimns(el.Ref)
+		if ref, ok := se.elements[trimns(el.Ref)]; ok {
+			el = ref
+		} else {
+			el = &wsdl.Element{}
+		}
+	}
+	ct := el.ComplexType
+	if ct == nil && el.Type != "" {
+		if t, ok := se.complexType(el.Type); ok {
+			ct = t
+		} else {
+			se.start(name, nil, indent)
+			se.value(el.Type)
+			se.end(name, 0)
+			return
+		}
+	}
+	if ct == nil {
+		se.start(name, nil, indent)
+		se.b.WriteString("?")
+		se.end(name, 0)
+		return
+	}
+	se.complexElement(name, ct, indent)
+}
+
+func (se *sampleEncoder) complexElement(name string, ct *wsdl.ComplexType, indent int) {
+	if se.seen[ct] {
+		se.start(name, nil, indent)
+		se.end(name, 0)
+		return
+	}
+	se.seen[ct] = true
+	defer delete(se.seen, ct)
+
+	var attrs []*wsdl.Attribute
+	var elements []*wsdl.Element
+	var text string
+	se.collect(ct, &attrs, &elements, &text)
+	se.start(name, attrs, indent)
+	switch {
+	case len(elements) > 0:
+		se.b.WriteString("\n")
+		for _, el := range elements {
+			se.element(el, indent+2)
+		}
+		se.end(name, indent)
+	case text != "":
+		se.value(text)
+		se.end(name, 0)
+	default:
+		se.end(name, 0)
+	}
+}
+
+// collect gathers the attributes, child elements and simple content
+// type of ct, including those of the types it extends.
+func (se *sampleEncoder) collect(ct *wsdl.ComplexType, attrs *[]*wsdl.Attribute, elements *[]*wsdl.Element, text *string) {
+	addSequence := func(seq *wsdl.Sequence) {
+		if seq == nil {
+			return
+		}
+		*elements = append(*elements, seq.Elements...)
+		for _, choice := range seq.Choices {
+			if len(choice.Elements) > 0 {
+				*elements = append(*elements, choice.Elements[0])
+			}
+		}
+	}
+	if cc := ct.ComplexContent; cc != nil && cc.Extension != nil {
+		if base, ok := se.complexType(cc.Extension.Base); ok && !se.seen[base] {
+			se.collect(base, attrs, elements, text)
+		}
+		addSequence(cc.Extension.Sequence)
+		if cc.Extension.Choice != nil && len(cc.Extension.Choice.Elements) > 0 {
+			*elements = append(*elements, cc.Extension.Choice.Elements[0])
+		}
+		*attrs = append(*attrs, cc.Extension.Attributes...)
+	}
+	if cc := ct.ComplexContent; cc != nil && cc.Restriction != nil {
+		// SOAP arrays, as generated by genGoStruct.
+		if r := cc.Restriction; len(r.Attributes) == 1 && r.Attributes[0].ArrayType != "" {
+			typ := strings.SplitN(r.Attributes[0].ArrayType, "[", 2)[0]
+			*elements = append(*elements, &wsdl.Element{Name: "item", Type: typ})
+		}
+	}
+	if sc := ct.SimpleContent; sc != nil && sc.Extension != nil {
+		*text = sc.Extension.Base
+		*attrs = append(*attrs, sc.Extension.Attributes...)
+	}
+	*elements = append(*elements, ct.AllElements...)
+	addSequence(ct.Sequence)
+	if ct.Choice != nil && len(ct.Choice.Elements) > 0 {
+		*elements = append(*elements, ct.Choice.Elements[0])
+	}
+	*attrs = append(*attrs, ct.Attributes...)
+}
+
+// complexType returns the complex type named t, if t is not an XML
+// Schema built-in type.
+func (se *sampleEncoder) complexType(t string) (*wsdl.ComplexType, bool) {
+	if se.isXSDType(t) {
+		return nil, false
+	}
+	if ct, ok := se.ctypes[trimns(t)]; ok {
+		return ct, true
+	}
+	// Elements with anonymous types are also used as types.
+	if el, ok := se.elements[trimns(t)]; ok && el.ComplexType != nil {
+		return el.ComplexType, true
+	}
+	return nil, false
+}
+
+func (se *sampleEncoder) isXSDType(t string) bool {
+	if n := strings.SplitN(t, ":", 2); len(n) == 2 {
+		if ns, ok := se.d.Namespaces[n[0]]; ok {
+			return xsdNamespaces[ns]
+		}
+	}
+	return false
+}
+
+func (se *sampleEncoder) start(name string, attrs []*wsdl.Attribute, indent int) {
+	se.b.WriteString(strings.Repeat(" ", indent) + "<" + name)
+	for _, attr := range attrs {
+		if attr.Name == "" {
+			continue
+		}
+		se.b.WriteString(" " + attr.Name + `="`)
+		se.value(attr.Type)
+		se.b.WriteString(`"`)
+	}
+	se.b.WriteString(">")
+}
+
+func (se *sampleEncoder) end(name string, indent int) {
+	se.b.WriteString(strings.Repeat(" ", indent) + "</" + name + ">\n")
+}
+
+// value writes a placeholder value of the simple type t.
+func (se *sampleEncoder) value(t string) {
+	if !se.isXSDType(t) {
+		if st, ok := se.stypes[trimns(t)]; ok && st.Restriction != nil {
+			if len(st.Restriction.Enum) > 0 {
+				xml.EscapeText(&se.b, []byte(st.Restriction.Enum[0].Value))
+				return
+			}
+			se.value(st.Restriction.Base)
+			return
+		}
+	}
+	var v string
+	switch trimns(t) {
+	case "boolean":
+		v = "false"
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger",
+		"unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+		v = "0"
+	case "positiveInteger":
+		v = "1"
+	case "float", "double", "decimal":
+		v = "0.0"
+	case "date":
+		v = "2006-01-02"
+	case "time":
+		v = "15:04:05"
+	case "dateTime":
+		v = "2006-01-02T15:04:05Z"
+	case "duration":
+		v = "P1D"
+	default:
+		v = "?"
+	}
+	se.b.WriteString(v)
+}
@@ -0,0 +1,38 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var SamplesCases = []struct {
+	F string
+	G string
+}{
+	{F: "memcache.wsdl", G: "memcache.samples.golden"},
+	{F: "w3example2.wsdl", G: "w3example2.samples.golden"},
+	{F: "arrayexample.wsdl", G: "arrayexample.samples.golden"},
+	{F: "mixedstyle.wsdl", G: "mixedstyle.samples.golden"},
+}
+
+func TestEncodeSamples(t *testing.T) {
+	for i, tc := range SamplesCases {
+		d := LoadDefinition(t, tc.F, nil)
+		var have bytes.Buffer
+		for _, s := range EncodeSamples(d) {
+			fmt.Fprintf(&have, "-- %s --\n%s", s.Name, s.Data)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", tc.G))
+		if err != nil {
+			t.Errorf("test %d: missing golden file %q: %v", i, tc.G, err)
+		}
+		if !bytes.Equal(have.Bytes(), want) {
+			err := Diff("_diff", "xml", want, have.Bytes())
+			t.Errorf("test %d, %q != %q: %v\ngenerated:\n%s\n",
+				i, tc.F, tc.G, err, have.Bytes())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package wsdlgo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// FieldTags are struct tags added to the fields of generated structs,
+// alongside their xml, json and yaml tags.
+type FieldTags []FieldTag
+
+// FieldTag is a struct tag added to the fields matching Selector.
+type FieldTag struct {
+	// Selector is a Struct.Field pattern of Go names, with * matching
+	// any name, such as Order.ID, Order.* or *.ID; a facet, @required,
+	// @optional or @attr; or type= and the schema type of the fields,
+	// without namespace, such as type=dateTime.
+	Selector string
+
+	// Key and Value of the tag. Occurrences of {name} in Value are
+	// replaced with the XML name of the field.
+	Key, Value string
+}
+
+// LoadFieldTags reads field tags from the file name.
+//
+// Each line of the file has a selector and the tags added to the
+// fields it matches, separated by white space. Each tag is added once
+// per field, by the first line that matches it. Empty lines and lines
+// starting with # are ignored:
+//
+//	# selector     tags
+//	Order.ID       db:"order_id"
+//	@required      validate:"required"
+//	type=dateTime  validate:"datetime=2006-01-02T15:04:05Z07:00"
+//	*              bson:"{name},omitempty" db:"{name}"
+func LoadFieldTags(name string) (FieldTags, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readFieldTags(f, name)
+}
+
+// readFieldTags reads field tags from r.
+func readFieldTags(r io.Reader, name string) (FieldTags, error) {
+	var ft FieldTags
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.IndexAny(text, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: want selector and tags, have %q", name, line, text)
+		}
+		sel := text[:i]
+		if err := checkSelector(sel); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, line, err)
+		}
+		tags, err := parseTags(text[i:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, line, err)
+		}
+		for _, t := range tags {
+			t.Selector = sel
+			ft = append(ft, t)
+		}
+	}
+	return ft, s.Err()
+}
+
+// checkSelector returns an error if sel isn't a valid selector.
+func checkSelector(sel string) error {
+	switch {
+	case sel == "@required", sel == "@optional", sel == "@attr":
+	case strings.HasPrefix(sel, "@"):
+		return fmt.Errorf("unknown facet %q, want @required, @optional or @attr", sel)
+	case strings.HasPrefix(sel, "type="):
+		if sel == "type=" {
+			return fmt.Errorf("missing type in %q", sel)
+		}
+	case sel == "*":
+	default:
+		if strings.Count(sel, ".") != 1 {
+			return fmt.Errorf("want Struct.Field, have %q", sel)
+		}
+		if _, err := path.Match(sel, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %v", sel, err)
+		}
+	}
+	return nil
+}
+
+// parseTags parses the struct tags in s, in the conventional format of
+// key:"value" pairs separated by spaces.
+func parseTags(s string) ([]FieldTag, error) {
+	var tags []FieldTag
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return tags, nil
+		}
+		i := strings.IndexByte(s, ':')
+		if i <= 0 || i+1 >= len(s) || s[i+1] != '"' || strings.ContainsAny(s[:i], " \t\"`") {
+			return nil, fmt.Errorf("want key:\"value\", have %q", s)
+		}
+		key := s[:i]
+		s = s[i+2:]
+		j := 0
+		for j < len(s) && s[j] != '"' {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(s) || strings.ContainsRune(s[:j], '`') {
+			return nil, fmt.Errorf("bad value of tag %s", key)
+		}
+		switch key {
+		case "xml", "json", "yaml":
+			return nil, fmt.Errorf("%s tags are already generated", key)
+		}
+		tags = append(tags, FieldTag{Key: key, Value: s[:j]})
+		s = s[j+1:]
+	}
+}
+
+// taggedField describes a field of a generated struct to select its
+// tags.
+type taggedField struct {
+	Struct   string // Go name of the struct
+	Field    string // Go name of the field
+	Name     string // XML name of the field
+	Type     string // schema type of the field, without namespace
+	Required bool
+	Attr     bool
+}
+
+// matches returns whether the selector of t matches f.
+func (t FieldTag) matches(f taggedField) bool {
+	switch {
+	case t.Selector == "*":
+		return true
+	case t.Selector == "@required":
+		return f.Required
+	case t.Selector == "@optional":
+		return !f.Required
+	case t.Selector == "@attr":
+		return f.Attr
+	case strings.HasPrefix(t.Selector, "type="):
+		return t.Selector[len("type="):] == f.Type
+	}
+	ok, _ := path.Match(t.Selector, f.Struct+"."+f.Field)
+	return ok
+}
+
+// lookup returns the tags of f, each preceded by a space, to be added
+// after its xml, json and yaml tags.
+func (ft FieldTags) lookup(f taggedField) string {
+	var b bytes.Buffer
+	seen := make(map[string]bool)
+	for _, t := range ft {
+		if seen[t.Key] || !t.matches(f) {
+			continue
+		}
+		seen[t.Key] = true
+		fmt.Fprintf(&b, " %s:\"%s\"", t.Key, strings.Replace(t.Value, "{name}", f.Name, -1))
+	}
+	return b.String()
+}
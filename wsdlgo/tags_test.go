@@ -0,0 +1,78 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadFieldTags(t *testing.T) {
+	ft, err := readFieldTags(strings.NewReader(`
+# selector  tags
+Order.ID    db:"order_id"
+@required   validate:"required" bson:"{name}"
+`), "tags.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FieldTags{
+		{Selector: "Order.ID", Key: "db", Value: "order_id"},
+		{Selector: "@required", Key: "validate", Value: "required"},
+		{Selector: "@required", Key: "bson", Value: "{name}"},
+	}
+	if len(ft) != len(want) {
+		t.Fatalf("want %v, have %v", want, ft)
+	}
+	for i := range want {
+		if ft[i] != want[i] {
+			t.Errorf("tag %d: want %v, have %v", i, want[i], ft[i])
+		}
+	}
+
+	cases := []struct {
+		Line, Err string
+	}{
+		{"Order.ID", "want selector and tags"},
+		{"Order db:\"id\"", "want Struct.Field"},
+		{"@unique db:\"id\"", "unknown facet"},
+		{"* db:id", "want key:\"value\""},
+		{"* db:\"id", "bad value of tag db"},
+		{"* json:\"id\"", "json tags are already generated"},
+	}
+	for _, tc := range cases {
+		_, err := readFieldTags(strings.NewReader("\n"+tc.Line), "bad.txt")
+		if err == nil || !strings.Contains(err.Error(), "bad.txt:2: "+tc.Err) {
+			t.Errorf("%s: want error %q at bad.txt:2, have %v", tc.Line, tc.Err, err)
+		}
+	}
+}
+
+func TestEncoderFieldTags(t *testing.T) {
+	ft, err := readFieldTags(strings.NewReader(`
+Order.ID        db:"order_id"
+@attr           db:"attr_{name}"
+@required       validate:"required"
+type=boolean    validate:"boolean"
+*               db:"{name}"
+`), "tags.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := LoadDefinition(t, "constructors.wsdl", nil)
+	var have bytes.Buffer
+	if err := NewEncoder(&have, WithFieldTags(ft)).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"`xml:\"ID\" json:\"ID\" yaml:\"ID\" db:\"order_id\" validate:\"required\"`",
+		"`xml:\"Items\" json:\"Items\" yaml:\"Items\" validate:\"required\" db:\"Items\"`",
+		"`xml:\"Gift,omitempty\" json:\"Gift,omitempty\" yaml:\"Gift,omitempty\" validate:\"boolean\" db:\"Gift\"`",
+		"`xml:\"Email\" json:\"Email\" yaml:\"Email\" db:\"Email\"`",
+		"`xml:\"version,attr,omitempty\" json:\"version,attr,omitempty\" yaml:\"version,attr,omitempty\" db:\"attr_version\" validate:\"required\"`",
+		"`xml:\"Text,omitempty\" json:\"Text,omitempty\" yaml:\"Text,omitempty\" db:\"Text\"`",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %s in:\n%s", want, have.Bytes())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package wsdlgo
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+)
+
+// templates are the code templates that can be replaced with
+// SetTemplate, by name.
+var templates = map[string]*template.Template{
+	"interfaceType":   interfaceTypeT,
+	"portType":        portTypeT,
+	"soapFunc":        soapFuncT,
+	"soapActionFunc":  soapActionFuncT,
+	"httpFunc":        httpFuncT,
+	"validator":       validatorT,
+	"enumValidator":   enumValidatorT,
+	"switchValidator": switchValidatorT,
+	"enumTable":       enumTableT,
+	"enumString":      enumStringT,
+	"typeRegistry":    typeRegistryT,
+	"arrayType":       arrayTypeT,
+	"choice":          choiceT,
+	"group":           groupT,
+	"constructor":     constructorT,
+	"roundTripTest":   roundTripTestT,
+}
+
+// TemplateNames returns the names of the code templates that can be
+// replaced with SetTemplate, in alphabetical order.
+func TemplateNames() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultTemplate returns a copy of the code template with the given
+// name, or nil if there's none. It shows the data the template is
+// executed with, and can be extended to replace it with SetTemplate:
+//
+//	t := template.Must(wsdlgo.DefaultTemplate("portType").New("custom").Parse(
+//		`{{template "portType" .}}
+//	func (p *{{.Name}}) Endpoint() string { return p.Client.URL }
+//	`))
+//	err := enc.SetTemplate("portType", t)
+func DefaultTemplate(name string) *template.Template {
+	t, ok := templates[name]
+	if !ok {
+		return nil
+	}
+	return template.Must(t.Clone())
+}
+
+// SetTemplate replaces the code template name
+func (ge *goEncoder) SetTemplate(name string, t *template.Template) error {
+	if _, ok := templates[name]; !ok {
+		return fmt.Errorf("unknown template %q", name)
+	}
+	if ge.templates == nil {
+		ge.templates = make(map[string]*template.Template)
+	}
+	ge.templates[name] = t
+	return nil
+}
+
+// template returns the template that replaces t, if any, or else t.
+func (ge *goEncoder) template(t *template.Template) *template.Template {
+	if r, ok := ge.templates[t.Name()]; ok {
+		return r
+	}
+	return t
+}
@@ -0,0 +1,57 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestSetTemplate(t *testing.T) {
+	portType := template.Must(DefaultTemplate("portType").New("endpoint").Parse(`{{template "portType" .}}
+// Endpoint returns the URL of the service.
+func (p *{{.Name}}) Endpoint() string {
+	return p.Client.URL
+}
+`))
+	d := LoadDefinition(t, "memcache.wsdl", nil)
+	var have bytes.Buffer
+	enc := NewEncoder(&have)
+	if err := enc.SetTemplate("portType", portType); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type MemoryServicePortTypeClient struct {\n\tsoap.Base\n}\n",
+		"func (p *MemoryServicePortTypeClient) Endpoint() string {\n",
+	} {
+		if !strings.Contains(have.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, have.Bytes())
+		}
+	}
+
+	if err := NewEncoder(ioutil.Discard).SetTemplate("struct", portType); err == nil {
+		t.Error("unexpected success with unknown template")
+	}
+
+	// errors executing templates are returned
+	enc = NewEncoder(ioutil.Discard)
+	enc.SetTemplate("soapActionFunc", template.Must(template.New("bad").Parse(`{{.Missing}}`)))
+	if err := enc.Encode(LoadDefinition(t, "memcache.wsdl", nil)); err == nil {
+		t.Error("unexpected success with bad template")
+	}
+}
+
+func TestTemplateNames(t *testing.T) {
+	for _, name := range TemplateNames() {
+		if DefaultTemplate(name) == nil {
+			t.Errorf("missing default template %q", name)
+		}
+	}
+	if DefaultTemplate("struct") != nil {
+		t.Error("unexpected template for unknown name")
+	}
+}
@@ -0,0 +1,116 @@
+package wsdlgo
+
+import (
+	"io"
+	"text/template"
+)
+
+var roundTripTestT = template.Must(template.New("roundTripTest").Parse(
+	`{{.Header}}
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestXMLRoundTrip was auto-generated from WSDL
+// and checks that generated types marshal to XML and back.
+func TestXMLRoundTrip(t *testing.T) {
+	cases := []interface{}{
+		{{range .Types}}&{{.}}{},
+		{{end}}
+	}
+	for _, v := range cases {
+		typ := reflect.TypeOf(v).Elem()
+		fillTestValue(reflect.ValueOf(v).Elem(), 0)
+		want, err := xml.Marshal(v)
+		if err != nil {
+			t.Errorf("%s: marshal: %v", typ.Name(), err)
+			continue
+		}
+		u := reflect.New(typ).Interface()
+		if err = xml.Unmarshal(want, u); err != nil {
+			t.Errorf("%s: unmarshal: %v", typ.Name(), err)
+			continue
+		}
+		have, err := xml.Marshal(u)
+		if err != nil {
+			t.Errorf("%s: marshal: %v", typ.Name(), err)
+			continue
+		}
+		if !bytes.Equal(want, have) {
+			t.Errorf("%s: round trip mismatch\nwant: %s\nhave: %s", typ.Name(), want, have)
+		}
+	}
+}
+
+// fillTestValue was auto-generated from WSDL
+// and sets representative values on v and its fields, down to a
+// limited depth for recursive types. Fields named with a namespace
+// prefix, such as xsi:type, are left alone as they can't be
+// unmarshaled.
+func fillTestValue(v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("string")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Ptr:
+		if depth < 3 {
+			v.Set(reflect.New(v.Type().Elem()))
+			fillTestValue(v.Elem(), depth+1)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte("bytes"))
+		} else if depth < 3 {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+			fillTestValue(v.Index(0), depth+1)
+		}
+	case reflect.Struct:
+{{- if .Choices}}
+		// choices of elements can only have one of them set
+		_, choice := v.Interface().(interface{ Which() string })
+{{- end}}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name := strings.Split(f.Tag.Get("xml"), ",")[0]
+			if f.PkgPath != "" || f.Name == "XMLName" || name == "-" || strings.Contains(name, ":") {
+				continue
+			}
+			fillTestValue(v.Field(i), depth)
+{{- if .Choices}}
+			if choice {
+				break
+			}
+{{- end}}
+		}
+	}
+}
+`))
+
+// writeTests writes tests for the generated struct types to w.
+func (ge *goEncoder) writeTests(w io.Writer) error {
+	return ge.template(roundTripTestT).Execute(w, &struct {
+		Header  string
+		Package string
+		Types   []string
+		Choices bool
+	}{
+		fileHeader,
+		ge.packageName.String(),
+		ge.structs,
+		len(ge.choiceTypes) > 0,
+	})
+}